@@ -0,0 +1,132 @@
+package clay
+
+import (
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/bidi"
+)
+
+type bidiRun struct {
+	start       int
+	end         int
+	rightToLeft bool
+}
+
+// Returns true if the text contains at least one rune from a right to left script.
+// Used as a fast path so that plain left to right text never goes through the bidi algorithm.
+func hasRightToLeftRunes(text string) bool {
+	for i := 0; i < len(text); i++ {
+		if text[i] < utf8.RuneSelf {
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(text[i:])
+		switch {
+		case r >= 0x0590 && r <= 0x08FF, // Hebrew, Arabic, Syriac, Thaana, NKo, Samaritan, Mandaic
+			r >= 0xFB1D && r <= 0xFDFF, // Hebrew and Arabic presentation forms A
+			r >= 0xFE70 && r <= 0xFEFF, // Arabic presentation forms B
+			r >= 0x10800 && r <= 0x10FFF,
+			r >= 0x1E800 && r <= 0x1EFFF:
+			return true
+		}
+		i += size - 1
+	}
+	return false
+}
+
+// Splits a single line of text into directional runs in visual (left to right) order.
+// Runs are stored as byte ranges into text in c.bidiRuns.
+func (c *Context) bidiRunsForLine(text string) []bidiRun {
+	c.bidiRuns = c.bidiRuns[:0]
+
+	// The paragraph is reused between lines and keeps the options applied by previous calls to Order, so the
+	// direction is always passed explicitly to not carry it over from a frame with a different direction
+	direction := bidi.LeftToRight
+	if c.rightToLeft {
+		direction = bidi.RightToLeft
+	}
+	if _, err := c.bidiParagraph.SetString(text, bidi.DefaultDirection(direction)); err != nil {
+		return append(c.bidiRuns, bidiRun{start: 0, end: len(text), rightToLeft: c.rightToLeft})
+	}
+	ordering, err := c.bidiParagraph.Order()
+	if err != nil || ordering.NumRuns() == 0 {
+		return append(c.bidiRuns, bidiRun{start: 0, end: len(text), rightToLeft: c.rightToLeft})
+	}
+
+	// Run positions are in runes, convert them to byte offsets while walking the string once
+	runeIndex := 0
+	byteIndex := 0
+	for i := range ordering.NumRuns() {
+		run := ordering.Run(i)
+		runStart, runEnd := run.Pos()
+		for runeIndex < runStart {
+			_, size := utf8.DecodeRuneInString(text[byteIndex:])
+			byteIndex += size
+			runeIndex++
+		}
+		start := byteIndex
+		for runeIndex <= runEnd {
+			_, size := utf8.DecodeRuneInString(text[byteIndex:])
+			byteIndex += size
+			runeIndex++
+		}
+		c.bidiRuns = append(c.bidiRuns, bidiRun{
+			start:       start,
+			end:         byteIndex,
+			rightToLeft: run.Direction() == bidi.RightToLeft,
+		})
+	}
+
+	// Runs are returned in logical order. With a left to right base direction the embedded right to left runs
+	// stay in place, with a right to left base direction the whole sequence of runs is reversed.
+	if c.rightToLeft {
+		for i, j := 0, len(c.bidiRuns)-1; i < j; i, j = i+1, j-1 {
+			c.bidiRuns[i], c.bidiRuns[j] = c.bidiRuns[j], c.bidiRuns[i]
+		}
+	}
+
+	return c.bidiRuns
+}
+
+// Adds a text render command, splitting it into one command per directional run when the text contains right to left script.
+// The first run keeps the id of the line, following runs derive their id from it.
+func (c *Context) addTextRenderCommand(renderCommand RenderCommand, textData TextRenderData, config *TextElementConfig) {
+	if !c.rightToLeft && !hasRightToLeftRunes(textData.StringContents) {
+		c.addTextRun(renderCommand, textData, config)
+		return
+	}
+
+	text := textData.StringContents
+	runs := c.bidiRunsForLine(text)
+	if len(runs) == 1 || c.measureText == nil {
+		textData.RightToLeft = runs[0].rightToLeft
+		c.addTextRun(renderCommand, textData, config)
+		return
+	}
+
+	lineId := renderCommand.Id
+	x := renderCommand.BoundingBox.X()
+	for runIndex, run := range runs {
+		runData := textData
+		runData.StringContents = text[run.start:run.end]
+		runData.RightToLeft = run.rightToLeft
+		runDimensions := c.measureText(runData.StringContents, config, c.measureTextUserData)
+
+		runCommand := renderCommand
+		runCommand.BoundingBox = MakeBoundingBox(
+			Vector2{X: x, Y: renderCommand.BoundingBox.Y()},
+			Dimensions{X: runDimensions.X, Y: renderCommand.BoundingBox.Height()},
+		)
+		if runIndex > 0 {
+			runCommand.Id = hashNumber(uint32(runIndex), lineId).id
+		}
+		c.addTextRun(runCommand, runData, config)
+		x += runDimensions.X
+	}
+}
+
+func (c *Context) addTextRun(renderCommand RenderCommand, textData TextRenderData, config *TextElementConfig) {
+	data := storeRenderData(&c.textRenderData, textData)
+	c.shapeTextRenderCommand(data, config)
+	renderCommand.RenderData = data
+	c.addRenderCommand(renderCommand)
+}
@@ -1,7 +1,13 @@
 package clay
 
 import (
+	"errors"
+	"fmt"
 	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/igadmg/gamemath/vector2"
@@ -12,7 +18,7 @@ func TestID(t *testing.T) {
 	id1 := ID("test")
 	id2 := ID("test")
 	assert.Equal(t, id1.id, id2.id)
-	assert.NotEqual(t, uint32(0), id1.id)
+	assert.NotEqual(t, uint64(0), id1.id)
 	id3 := ID("different")
 	assert.NotEqual(t, id1.id, id3.id)
 }
@@ -23,6 +29,86 @@ func TestIDI(t *testing.T) {
 	assert.Equal(t, id1.id, id2.id)
 	id3 := IDI("test", 2)
 	assert.NotEqual(t, id1.id, id3.id)
+	// The index is hashed into the id of the label, which is kept as the base id
+	assert.Equal(t, ID("test").id, id1.baseId)
+	assert.Equal(t, id1.baseId, IDI("test", math.MaxUint32).baseId)
+	assert.NotEqual(t, ID("test[1]").id, id1.id)
+	assert.NotEqual(t, ID("test").id, IDI("test", 0).id)
+	assert.Equal(t, "test", id1.stringId)
+	assert.Equal(t, uint32(1), id1.offset)
+	assert.Equal(t, "test[1]", id1.String())
+	assert.Equal(t, "test[0]", IDI("test", 0).String())
+	assert.Equal(t, "test", ID("test").String())
+}
+
+func TestID_LOCAL(t *testing.T) {
+	ctx := Initialize(MakeBoundingBox(MakeVector2(0, 0), MakeDimensions(800, 600)), ErrorHandler{})
+	var local, localInOther, indexed ElementId
+	ctx.BeginLayout()
+	ctx.CLAY_ID(ID("panel"), ElementDeclaration{}, func() {
+		local = ctx.ID_LOCAL("title")
+		indexed = ctx.IDI_LOCAL("title", 2)
+	})
+	ctx.CLAY_ID(ID("other"), ElementDeclaration{}, func() {
+		localInOther = ctx.ID_LOCAL("title")
+	})
+	ctx.EndLayout()
+
+	// Local ids are seeded with the parent id
+	assert.Equal(t, hashString("title", ID("panel").id), local)
+	assert.NotEqual(t, local.id, localInOther.id)
+	assert.NotEqual(t, ID("title").id, local.id)
+	assert.Equal(t, local.id, indexed.baseId)
+	assert.Equal(t, "title", local.String())
+	assert.Equal(t, "title[2]", indexed.String())
+}
+
+// Local ids are seeded with the element they are declared in, not with its parent.
+func TestID_LOCAL_SeededWithEnclosingElement(t *testing.T) {
+	var errors []ErrorData
+	ctx := Initialize(MakeBoundingBox(MakeVector2(0, 0), MakeDimensions(800, 600)), recordErrors(&errors))
+	rootId := hashString("Clay__RootContainer", 0).id
+	var titles []ElementId
+	var topLevel ElementId
+	ctx.BeginLayout()
+	// Directly in the root container there is no element below it on the stack
+	topLevel = ctx.ID_LOCAL("title")
+	ctx.CLAY_ID(ID("list"), ElementDeclaration{}, func() {
+		for i := range 2 {
+			ctx.CLAY_ID(IDI("card", uint32(i)), ElementDeclaration{}, func() {
+				titles = append(titles, ctx.ID_LOCAL("title"))
+				ctx.CLAY_ID(titles[i], ElementDeclaration{Layout: LayoutConfig{Sizing: Sizing{Width: FIXED(10), Height: FIXED(10)}}})
+			})
+		}
+	})
+	ctx.EndLayout()
+
+	assert.Empty(t, errors)
+	assert.Equal(t, hashString("title", rootId), topLevel)
+	for i, title := range titles {
+		assert.Equal(t, hashString("title", IDI("card", uint32(i)).id), title)
+		assert.NotEqual(t, hashString("title", ID("list").id).id, title.id)
+		assert.True(t, ctx.GetElementData(title).Found)
+	}
+	assert.NotEqual(t, titles[0].id, titles[1].id)
+}
+
+// Sequential ids as used by large list and tree views don't collide.
+func TestID_NoCollisions(t *testing.T) {
+	const count = 200_000
+	ids := make(map[uint64]ElementId, 4*count)
+	add := func(id ElementId) {
+		if other, ok := ids[id.id]; ok {
+			t.Fatalf("%s and %s have the same id", other, id)
+		}
+		ids[id.id] = id
+	}
+	for i := range uint32(count) {
+		add(IDI("row", i))
+		add(ID("row" + strconv.Itoa(int(i))))
+		add(hashNumber(i, ID("list").id))
+		add(hashStringWithOffset("cell", i, IDI("row", i).id))
+	}
 }
 
 func TestCornerRadius_IsEmpty(t *testing.T) {
@@ -39,10 +125,18 @@ func TestCORNER_RADIUS(t *testing.T) {
 }
 
 func TestSizingAxisTypeString(t *testing.T) {
-	assert.Equal(t, "FIXED", SizingAxisTypeString(SizingAxisFixed{}))
-	assert.Equal(t, "FIT", SizingAxisTypeString(SizingAxisFit{}))
-	assert.Equal(t, "GROW", SizingAxisTypeString(SizingAxisGrow{}))
-	assert.Equal(t, "PERCENT", SizingAxisTypeString(SizingAxisPercent{}))
+	assert.Equal(t, "FIXED", SizingAxisTypeString(FIXED(0)))
+	assert.Equal(t, "FIT", SizingAxisTypeString(SizingAxis{}))
+	assert.Equal(t, "GROW", SizingAxisTypeString(GROW[float32]()))
+	assert.Equal(t, "PERCENT", SizingAxisTypeString(PERCENT(0)))
+	assert.Equal(t, "MIN_CONTENT", SizingAxisTypeString(MIN_CONTENT[float32]()))
+	assert.Equal(t, "MAX_CONTENT", SizingAxisTypeString(MAX_CONTENT[float32]()))
+	assert.Equal(t, "CALC", SizingAxisTypeString(CALC()))
+}
+
+func TestSizingAxisCalc_String(t *testing.T) {
+	assert.Equal(t, "100% + -48", CALC(PERCENT(1), FIXED(-48)).String())
+	assert.Equal(t, "max(200, min(50vw, 500))", CALC_CLAMP(FIXED(200), VW(0.5), FIXED(500)).String())
 }
 
 func TestFIT(t *testing.T) {
@@ -72,14 +166,28 @@ func TestFIXED(t *testing.T) {
 func TestPERCENT(t *testing.T) {
 	s := PERCENT(0.5)
 	assert.Equal(t, float32(0.5), s.Percent)
+	assert.Equal(t, SizingMinMax{Min: 0, Max: math.MaxFloat32}, s.MinMax)
+	s = PERCENT(0.5, 10)
+	assert.Equal(t, SizingMinMax{Min: 10, Max: math.MaxFloat32}, s.MinMax)
+	s = PERCENT(0.5, 10, 20)
+	assert.Equal(t, SizingMinMax{Min: 10, Max: 20}, s.MinMax)
+	// Percent sizing doesn't follow the rules of sizings that fit their content
+	assert.False(t, s.fitsContent())
+}
+
+func TestSizingAxis_ZeroValue(t *testing.T) {
+	// The zero value is FIT without a minimum or maximum, a Max of 0 means there is no maximum except for FIXED
+	var s SizingAxis
+	assert.Equal(t, SIZING_TYPE_FIT, s.Type)
+	assert.Equal(t, FIT[float32]().GetMinMax(), s.GetMinMax())
+	assert.Equal(t, SizingMinMax{Min: 10, Max: math.MaxFloat32}, SizingAxis{Type: SIZING_TYPE_GROW, MinMax: SizingMinMax{Min: 10}}.GetMinMax())
+	assert.Equal(t, SizingMinMax{}, FIXED(0).GetMinMax())
 }
 
 func TestSizing_GetAxis(t *testing.T) {
-	s := Sizing{Width: SizingAxisFixed{}, Height: SizingAxisGrow{}}
-	_, ok := s.GetAxis(0).(SizingAxisFixed)
-	assert.True(t, ok)
-	_, ok = s.GetAxis(1).(SizingAxisGrow)
-	assert.True(t, ok)
+	s := Sizing{Width: FIXED(0), Height: GROW(0)}
+	assert.Equal(t, SIZING_TYPE_FIXED, s.GetAxis(0).Type)
+	assert.Equal(t, SIZING_TYPE_GROW, s.GetAxis(1).Type)
 }
 
 func TestPADDING_ALL(t *testing.T) {
@@ -159,16 +267,6 @@ func TestColor_IsZero(t *testing.T) {
 func TestInitialize(t *testing.T) {
 	ctx := Initialize(MakeBoundingBox(MakeVector2(0, 0), MakeDimensions(800, 600)), ErrorHandler{})
 	assert.NotNil(t, ctx)
-	assert.Equal(t, ctx, GetCurrentContext())
-}
-
-func TestSetCurrentContext(t *testing.T) {
-	ctx1 := Initialize(MakeBoundingBox(MakeVector2(0, 0), MakeDimensions(800, 600)), ErrorHandler{})
-	ctx2 := Initialize(MakeBoundingBox(MakeVector2(0, 0), MakeDimensions(800, 600)), ErrorHandler{})
-	SetCurrentContext(ctx1)
-	assert.Equal(t, ctx1, GetCurrentContext())
-	SetCurrentContext(ctx2)
-	assert.Equal(t, ctx2, GetCurrentContext())
 }
 
 // Mock MeasureText function for testing
@@ -261,6 +359,436 @@ func TestErrorHandling_MeasureTextNotSet(t *testing.T) {
 	assert.Greater(t, errorCount, 0)
 }
 
+// Returns an error handler that appends every reported error to errors.
+func recordErrors(errors *[]ErrorData) ErrorHandler {
+	return ErrorHandler{
+		ErrorHandlerFunction: func(err ErrorData) { *errors = append(*errors, err) },
+	}
+}
+
+func TestErrorHandling_TextMeasurementFunctionNotProvided(t *testing.T) {
+	var errors []ErrorData
+	ctx := Initialize(MakeBoundingBox(MakeVector2(0, 0), MakeDimensions(800, 600)), recordErrors(&errors))
+
+	ctx.BeginLayout()
+	ctx.CLAY_ID(ID("sidebar"), ElementDeclaration{}, func() {
+		ctx.CLAY(ElementDeclaration{}, func() {
+			ctx.CLAY_TEXT("test", &TextElementConfig{})
+		})
+	})
+	ctx.EndLayout()
+
+	if assert.Len(t, errors, 1) {
+		assert.Equal(t, ERROR_TYPE_TEXT_MEASUREMENT_FUNCTION_NOT_PROVIDED, errors[0].ErrorType)
+		assert.Equal(t, "", errors[0].StringId)
+		assert.Equal(t, "Clay__RootContainer/sidebar/#0", errors[0].ElementPath)
+	}
+}
+
+func TestErrorHandling_ArenaCapacityExceeded(t *testing.T) {
+	var errors []ErrorData
+	ctx := Initialize(MakeBoundingBox(MakeVector2(0, 0), MakeDimensions(800, 600)), recordErrors(&errors))
+
+	ctx.SetMaxElementCount(0)
+	ctx.SetMaxMeasureTextCacheWordCount(-1)
+
+	if assert.Len(t, errors, 2) {
+		assert.Equal(t, ERROR_TYPE_ARENA_CAPACITY_EXCEEDED, errors[0].ErrorType)
+		assert.Equal(t, ERROR_TYPE_ARENA_CAPACITY_EXCEEDED, errors[1].ErrorType)
+	}
+	assert.Equal(t, int32(2), ctx.GetMaxElementCount())
+	assert.Equal(t, int32(1), ctx.GetMaxMeasureTextCacheWordCount())
+}
+
+func TestErrorHandling_TextMeasurementCapacityExceeded(t *testing.T) {
+	var errors []ErrorData
+	ctx := Initialize(MakeBoundingBox(MakeVector2(0, 0), MakeDimensions(800, 600)), recordErrors(&errors))
+	ctx.SetMeasureTextFunction(mockMeasureText, nil)
+	ctx.SetMaxMeasureTextCacheWordCount(3)
+
+	ctx.BeginLayout()
+	ctx.CLAY_ID(ID("label"), ElementDeclaration{}, func() {
+		ctx.CLAY_TEXT("more words than the cache holds", &TextElementConfig{})
+	})
+	ctx.EndLayout()
+
+	if assert.Len(t, errors, 1) {
+		assert.Equal(t, ERROR_TYPE_TEXT_MEASUREMENT_CAPACITY_EXCEEDED, errors[0].ErrorType)
+		assert.Equal(t, "label", errors[0].StringId)
+		assert.Equal(t, "Clay__RootContainer/label", errors[0].ElementPath)
+	}
+}
+
+func TestErrorHandling_DuplicateId(t *testing.T) {
+	var errors []ErrorData
+	ctx := Initialize(MakeBoundingBox(MakeVector2(0, 0), MakeDimensions(800, 600)), recordErrors(&errors))
+	ctx.SetMeasureTextFunction(mockMeasureText, nil)
+
+	for range 2 {
+		errors = errors[:0]
+		ctx.BeginLayout()
+		ctx.CLAY_ID(ID("list"), ElementDeclaration{}, func() {
+			ctx.CLAY_ID(ID("item"), ElementDeclaration{Layout: LayoutConfig{Sizing: Sizing{Width: FIXED(10)}}})
+			ctx.CLAY_ID(ID("item"), ElementDeclaration{Layout: LayoutConfig{Sizing: Sizing{Width: FIXED(20)}}}, func() {
+				// Children of the duplicate have duplicate ids as well, they are not reported again
+				ctx.CLAY(ElementDeclaration{})
+				ctx.CLAY_TEXT("text", &TextElementConfig{})
+			})
+		})
+		ctx.EndLayout()
+
+		// Only the second declaration in each layout is a duplicate
+		if assert.Len(t, errors, 1) {
+			assert.Equal(t, ERROR_TYPE_DUPLICATE_ID, errors[0].ErrorType)
+			assert.Equal(t, "item", errors[0].StringId)
+			assert.Equal(t, "item", errors[0].FirstStringId)
+			assert.Equal(t, "Clay__RootContainer/list/item", errors[0].ElementPath)
+			if captureDeclarationLocations {
+				assert.Regexp(t, `clay_test\.go:\d+$`, errors[0].SourceLocation)
+				assert.Regexp(t, `clay_test\.go:\d+$`, errors[0].FirstSourceLocation)
+				assert.NotEqual(t, errors[0].SourceLocation, errors[0].FirstSourceLocation)
+			} else {
+				assert.Empty(t, errors[0].SourceLocation)
+			}
+		}
+	}
+
+	// Ids repeated in a loop, e.g. IDI with the same index
+	errors = errors[:0]
+	ctx.BeginLayout()
+	for range 2 {
+		ctx.CLAY_ID(IDI("row", 7), ElementDeclaration{})
+	}
+	ctx.EndLayout()
+	if assert.Len(t, errors, 1) {
+		assert.Equal(t, ERROR_TYPE_DUPLICATE_ID, errors[0].ErrorType)
+		assert.Contains(t, errors[0].ErrorText, "row[7]")
+	}
+
+	// The first element keeps the id
+	data := ctx.GetElementData(ID("item"))
+	assert.True(t, data.Found)
+	assert.Equal(t, float32(10), data.BoundingBox.Size.X)
+}
+
+func TestErrorHandling_HashCollision(t *testing.T) {
+	var errors []ErrorData
+	ctx := Initialize(MakeBoundingBox(MakeVector2(0, 0), MakeDimensions(800, 600)), recordErrors(&errors))
+	// 64 bit collisions can't be found by brute force, give a different string the same hash
+	collidingId := IDI("item", 322382)
+	collidingId.id = IDI("item", 139599).id
+
+	ctx.BeginLayout()
+	ctx.CLAY_ID(IDI("item", 139599), ElementDeclaration{})
+	ctx.CLAY_ID(collidingId, ElementDeclaration{})
+	ctx.EndLayout()
+
+	if assert.Len(t, errors, 1) {
+		assert.Equal(t, ERROR_TYPE_HASH_COLLISION, errors[0].ErrorType)
+		assert.Equal(t, "item", errors[0].StringId)
+		assert.Equal(t, "item", errors[0].FirstStringId)
+		assert.Contains(t, errors[0].ErrorText, "item[139599] and item[322382]")
+	}
+}
+
+func TestErrorHandling_FloatingContainerParentNotFound(t *testing.T) {
+	var errors []ErrorData
+	ctx := Initialize(MakeBoundingBox(MakeVector2(0, 0), MakeDimensions(800, 600)), recordErrors(&errors))
+
+	ctx.BeginLayout()
+	ctx.CLAY_ID(ID("tooltip"), ElementDeclaration{
+		Floating: FloatingElementConfig{AttachTo: ATTACH_TO_ELEMENT_WITH_ID, ParentId: ID("missing").id},
+	})
+	ctx.EndLayout()
+
+	if assert.Len(t, errors, 1) {
+		assert.Equal(t, ERROR_TYPE_FLOATING_CONTAINER_PARENT_NOT_FOUND, errors[0].ErrorType)
+		assert.Equal(t, "tooltip", errors[0].StringId)
+		assert.Equal(t, "Clay__RootContainer/tooltip", errors[0].ElementPath)
+	}
+}
+
+func TestErrorHandling_PercentReferenceNotFound(t *testing.T) {
+	var errors []ErrorData
+	ctx := Initialize(MakeBoundingBox(MakeVector2(0, 0), MakeDimensions(800, 600)), recordErrors(&errors))
+
+	ctx.BeginLayout()
+	ctx.CLAY_ID(ID("sibling"), ElementDeclaration{})
+	ctx.CLAY_ID(ID("panel"), ElementDeclaration{Layout: LayoutConfig{Sizing: Sizing{Width: FIXED(200)}}}, func() {
+		ctx.CLAY_ID(ID("content"), ElementDeclaration{Layout: LayoutConfig{Sizing: Sizing{Width: PERCENT_OF(ID("sibling"), 0.5)}}})
+	})
+	ctx.EndLayout()
+
+	if assert.Len(t, errors, 1) {
+		assert.Equal(t, ERROR_TYPE_PERCENT_REFERENCE_NOT_FOUND, errors[0].ErrorType)
+		assert.Equal(t, "Clay__RootContainer/panel/content", errors[0].ElementPath)
+	}
+	// The percentage is taken of the parent instead
+	assert.Equal(t, float32(100), ctx.GetElementData(ID("content")).BoundingBox.Width())
+}
+
+func TestErrorHandling_PercentageOver1(t *testing.T) {
+	var errors []ErrorData
+	ctx := Initialize(MakeBoundingBox(MakeVector2(0, 0), MakeDimensions(800, 600)), recordErrors(&errors))
+
+	ctx.BeginLayout()
+	ctx.CLAY(ElementDeclaration{}, func() {
+		ctx.CLAY(ElementDeclaration{})
+		ctx.CLAY(ElementDeclaration{Layout: LayoutConfig{Sizing: Sizing{Width: PERCENT(50)}}})
+	})
+	ctx.EndLayout()
+
+	if assert.Len(t, errors, 1) {
+		assert.Equal(t, ERROR_TYPE_PERCENTAGE_OVER_1, errors[0].ErrorType)
+		assert.Equal(t, "", errors[0].StringId)
+		assert.Equal(t, "Clay__RootContainer/#0/#1", errors[0].ElementPath)
+	}
+}
+
+func TestErrorHandling_InternalError(t *testing.T) {
+	var errors []ErrorData
+	ctx := Initialize(MakeBoundingBox(MakeVector2(0, 0), MakeDimensions(800, 600)), recordErrors(&errors))
+
+	ctx.BeginLayout()
+	ctx.CLAY_ID(ID("panel"), ElementDeclaration{})
+	// Simulate a bug that lost the element's hash map item
+	delete(ctx.layoutElementsHashMap, ID("panel").id)
+	assert.NotPanics(t, func() { ctx.EndLayout() })
+
+	if assert.Len(t, errors, 1) {
+		assert.Equal(t, ERROR_TYPE_INTERNAL_ERROR, errors[0].ErrorType)
+	}
+}
+
+func TestErrorHandling_UnbalancedOpenClose(t *testing.T) {
+	var errors []ErrorData
+	ctx := Initialize(MakeBoundingBox(MakeVector2(0, 0), MakeDimensions(800, 600)), recordErrors(&errors))
+
+	ctx.BeginLayout()
+	func() {
+		defer func() { recover() }()
+		ctx.CLAY_ID(ID("panel"), ElementDeclaration{}, func() {
+			ctx.CLAY_ID(ID("broken"), ElementDeclaration{}, func() {
+				panic("declaration failed")
+			})
+		})
+	}()
+	commands := ctx.EndLayout()
+
+	if assert.Len(t, errors, 1) {
+		assert.Equal(t, ERROR_TYPE_UNBALANCED_OPEN_CLOSE, errors[0].ErrorType)
+		assert.Equal(t, "broken", errors[0].StringId)
+		assert.Equal(t, "Clay__RootContainer/panel/broken", errors[0].ElementPath)
+	}
+	assert.NotNil(t, commands)
+
+	// Ending the same layout twice closes more elements than were opened
+	errors = errors[:0]
+	assert.NotPanics(t, func() { ctx.EndLayout() })
+	if assert.Len(t, errors, 1) {
+		assert.Equal(t, ERROR_TYPE_UNBALANCED_OPEN_CLOSE, errors[0].ErrorType)
+	}
+
+	// The next layout is not affected
+	errors = errors[:0]
+	ctx.BeginLayout()
+	ctx.CLAY_ID(ID("panel"), ElementDeclaration{})
+	ctx.EndLayout()
+	assert.Empty(t, errors)
+}
+
+func TestErrorHandling_ElementsCapacityExceeded(t *testing.T) {
+	var errors []ErrorType
+	ctx := Initialize(MakeBoundingBox(MakeVector2(0, 0), MakeDimensions(800, 600)), ErrorHandler{
+		ErrorHandlerFunction: func(err ErrorData) { errors = append(errors, err.ErrorType) },
+	})
+	ctx.SetMeasureTextFunction(mockMeasureText, nil)
+	assert.Equal(t, defaultMaxElementCount, ctx.GetMaxElementCount())
+	ctx.SetMaxElementCount(8)
+	assert.Equal(t, int32(8), ctx.GetMaxElementCount())
+
+	for range 2 {
+		errors = errors[:0]
+		ctx.BeginLayout()
+		for i := range 10 {
+			ctx.CLAY_ID(IDI("item", uint32(i)), ElementDeclaration{}, func() {
+				ctx.CLAY_TEXT("text", &TextElementConfig{})
+			})
+		}
+		commands := ctx.EndLayout()
+
+		// Reported once per layout, the layout is replaced with a single error message
+		assert.Equal(t, []ErrorType{ERROR_TYPE_ELEMENTS_CAPACITY_EXCEEDED}, errors)
+		assert.LessOrEqual(t, len(ctx.layoutElements), 7)
+		if assert.Len(t, commands, 1) {
+			text, _ := commands[0].RenderData.(*TextRenderData)
+			assert.Contains(t, text.StringContents, "exceeded")
+		}
+	}
+}
+
+func TestErrorHandling_TextConfigCapacityExceeded(t *testing.T) {
+	var errors []ErrorType
+	ctx := Initialize(MakeBoundingBox(MakeVector2(0, 0), MakeDimensions(800, 600)), ErrorHandler{
+		ErrorHandlerFunction: func(err ErrorData) { errors = append(errors, err.ErrorType) },
+	})
+	ctx.SetMeasureTextFunction(mockMeasureText, nil)
+	ctx.SetMaxElementCount(8)
+
+	ctx.BeginLayout()
+	// Fill the text configs, the copy of the text element's config doesn't fit anymore
+	for len(ctx.textElementConfigs) < cap(ctx.textElementConfigs) {
+		ctx.TEXT_CONFIG(TextElementConfig{FontSize: 1})
+	}
+	ctx.CLAY_TEXT("text", &TextElementConfig{FontSize: 99})
+	textElement := &ctx.layoutElements[len(ctx.layoutElements)-1]
+	_, hasConfig := ctx.textConfig(textElement)
+	assert.False(t, hasConfig)
+	ctx.EndLayout()
+
+	assert.Equal(t, []ErrorType{ERROR_TYPE_ELEMENTS_CAPACITY_EXCEEDED}, errors)
+}
+
+func TestErrorHandling_RenderCommandsCapacityExceeded(t *testing.T) {
+	var errors []ErrorType
+	ctx := Initialize(MakeBoundingBox(MakeVector2(0, 0), MakeDimensions(800, 600)), ErrorHandler{
+		ErrorHandlerFunction: func(err ErrorData) { errors = append(errors, err.ErrorType) },
+	})
+	ctx.SetMeasureTextFunction(mockMeasureText, nil)
+	ctx.SetMaxElementCount(8)
+
+	// Every word wraps onto its own line, so one text element produces more render commands than there are elements
+	ctx.BeginLayout()
+	ctx.CLAY(ElementDeclaration{Layout: LayoutConfig{Sizing: Sizing{Width: FIXED(10)}}}, func() {
+		ctx.CLAY_TEXT("one two three four five six seven eight nine ten", &TextElementConfig{})
+	})
+	commands := ctx.EndLayout()
+
+	assert.Equal(t, []ErrorType{ERROR_TYPE_ELEMENTS_CAPACITY_EXCEEDED}, errors)
+	assert.True(t, ctx.booleanWarnings.maxRenderCommandsExceeded)
+	assert.Len(t, commands, 7)
+}
+
+func TestEndLayoutWithError(t *testing.T) {
+	ctx := Initialize(MakeBoundingBox(MakeVector2(0, 0), MakeDimensions(800, 600)), ErrorHandler{})
+
+	ctx.BeginLayout()
+	ctx.CLAY_ID(ID("item"), ElementDeclaration{})
+	commands, err := ctx.EndLayoutWithError()
+	assert.NoError(t, err)
+	assert.NotNil(t, commands)
+
+	ctx.BeginLayout()
+	ctx.CLAY_ID(ID("list"), ElementDeclaration{}, func() {
+		ctx.CLAY_ID(ID("item"), ElementDeclaration{})
+		ctx.CLAY_ID(ID("item"), ElementDeclaration{})
+		ctx.CLAY(ElementDeclaration{Layout: LayoutConfig{Sizing: Sizing{Width: PERCENT(2)}}})
+	})
+	_, err = ctx.FinalizeLayoutWithError()
+
+	var layoutError *LayoutError
+	if assert.ErrorAs(t, err, &layoutError) {
+		assert.Len(t, layoutError.Errors, 2)
+	}
+	assert.ErrorIs(t, err, ERROR_TYPE_DUPLICATE_ID)
+	assert.ErrorIs(t, err, ERROR_TYPE_PERCENTAGE_OVER_1)
+	assert.NotErrorIs(t, err, ERROR_TYPE_INTERNAL_ERROR)
+	var errorData ErrorData
+	if assert.True(t, errors.As(err, &errorData)) {
+		assert.Equal(t, "item", errorData.StringId)
+	}
+	assert.Equal(t, "clay: DUPLICATE_ID at Clay__RootContainer/list/item: An element with the id item was already previously declared during this layout.\n"+
+		"clay: PERCENTAGE_OVER_1 at Clay__RootContainer/list/#2: An element was configured with SIZING_PERCENT, but the provided percentage value was over 1.0. Clay expects a value between 0 and 1, i.e. 20% is 0.2.",
+		err.Error())
+
+	// Errors are collected per layout
+	ctx.BeginLayout()
+	_, err = ctx.FinalizeLayoutWithError()
+	assert.NoError(t, err)
+}
+
+func TestStrictValidation(t *testing.T) {
+	grow := Sizing{Width: GROW[float32]()}
+	tests := []struct {
+		name    string
+		declare func(ctx *Context)
+		want    ErrorType
+	}{
+		{"grow in fit parent", func(ctx *Context) {
+			ctx.CLAY_ID(ID("row"), ElementDeclaration{}, func() {
+				ctx.CLAY(ElementDeclaration{Layout: LayoutConfig{Sizing: grow}})
+			})
+		}, ERROR_TYPE_GROW_IN_FIT_PARENT},
+		{"percentage sum over 1", func(ctx *Context) {
+			ctx.CLAY_ID(ID("row"), ElementDeclaration{Layout: LayoutConfig{Sizing: Sizing{Width: FIXED(100)}}}, func() {
+				ctx.CLAY(ElementDeclaration{Layout: LayoutConfig{Sizing: Sizing{Width: PERCENT(0.6)}}})
+				ctx.CLAY(ElementDeclaration{Layout: LayoutConfig{Sizing: Sizing{Width: PERCENT(0.6)}}})
+			})
+		}, ERROR_TYPE_PERCENTAGE_SUM_OVER_1},
+		{"negative child gap", func(ctx *Context) {
+			ctx.CLAY_ID(ID("row"), Element(WithChildGap(-4)))
+		}, ERROR_TYPE_NEGATIVE_CHILD_GAP},
+		{"min over max", func(ctx *Context) {
+			ctx.CLAY_ID(ID("row"), ElementDeclaration{Layout: LayoutConfig{Sizing: Sizing{Width: FIT(200, 100)}}})
+		}, ERROR_TYPE_SIZING_MIN_OVER_MAX},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := Initialize(MakeBoundingBox(MakeVector2(0, 0), MakeDimensions(800, 600)), ErrorHandler{})
+
+			ctx.BeginLayout()
+			tt.declare(ctx)
+			_, err := ctx.EndLayoutWithError()
+			assert.NoError(t, err, "only reported in strict mode")
+
+			ctx.SetStrictValidationEnabled(true)
+			ctx.BeginLayout()
+			tt.declare(ctx)
+			_, err = ctx.EndLayoutWithError()
+			var layoutError *LayoutError
+			if assert.ErrorAs(t, err, &layoutError) && assert.Len(t, layoutError.Errors, 1) {
+				assert.Equal(t, tt.want, layoutError.Errors[0].ErrorType)
+				assert.Equal(t, "row", layoutError.Errors[0].StringId)
+			}
+		})
+	}
+
+	// Growing along the cross axis of a FIT parent stretches children to the widest one, that's fine
+	ctx := Initialize(MakeBoundingBox(MakeVector2(0, 0), MakeDimensions(800, 600)), ErrorHandler{})
+	ctx.SetStrictValidationEnabled(true)
+	ctx.BeginLayout()
+	ctx.CLAY(ElementDeclaration{Layout: LayoutConfig{LayoutDirection: TOP_TO_BOTTOM}}, func() {
+		ctx.CLAY(ElementDeclaration{Layout: LayoutConfig{Sizing: grow}})
+		ctx.CLAY(ElementDeclaration{Layout: LayoutConfig{Sizing: Sizing{Width: FIXED(50)}}})
+	})
+	_, err := ctx.EndLayoutWithError()
+	assert.NoError(t, err)
+}
+
+func TestSetMaxMeasureTextCacheWordCount(t *testing.T) {
+	ctx := Initialize(MakeBoundingBox(MakeVector2(0, 0), MakeDimensions(800, 600)), ErrorHandler{})
+	assert.Equal(t, defaultMaxMeasureTextWordCacheCount, ctx.GetMaxMeasureTextCacheWordCount())
+	ctx.SetMaxMeasureTextCacheWordCount(32)
+	assert.Equal(t, int32(32), ctx.GetMaxMeasureTextCacheWordCount())
+	assert.Equal(t, 32, cap(ctx.measuredWords))
+}
+
+func TestInitializeFrom_KeepsPreviousLimits(t *testing.T) {
+	ctx := Initialize(MakeBoundingBox(MakeVector2(0, 0), MakeDimensions(800, 600)), ErrorHandler{})
+	ctx.SetMaxElementCount(64)
+	ctx.SetMaxMeasureTextCacheWordCount(128)
+
+	next := InitializeFrom(ctx, MakeBoundingBox(MakeVector2(0, 0), MakeDimensions(800, 600)), ErrorHandler{})
+	assert.Equal(t, int32(64), next.GetMaxElementCount())
+	assert.Equal(t, int32(128), next.GetMaxMeasureTextCacheWordCount())
+	assert.Equal(t, 64, cap(next.layoutElements))
+
+	// Contexts initialized on their own don't share limits
+	other := Initialize(MakeBoundingBox(MakeVector2(0, 0), MakeDimensions(800, 600)), ErrorHandler{})
+	assert.Equal(t, defaultMaxElementCount, other.GetMaxElementCount())
+	assert.Equal(t, defaultMaxMeasureTextWordCacheCount, other.GetMaxMeasureTextCacheWordCount())
+}
+
 func TestSimpleLayout_RenderCommandsCount(t *testing.T) {
 	ctx := Initialize(MakeBoundingBox(MakeVector2(0, 0), MakeDimensions(800, 600)), ErrorHandler{})
 	ctx.SetMeasureTextFunction(mockMeasureText, nil)
@@ -290,9 +818,9 @@ func TestSimpleLayout_RenderCommandsCount(t *testing.T) {
 	hasText := false
 	for _, cmd := range commands {
 		switch cmd.RenderData.(type) {
-		case RectangleRenderData:
+		case *RectangleRenderData:
 			hasRectangle = true
-		case TextRenderData:
+		case *TextRenderData:
 			hasText = true
 		}
 	}
@@ -331,9 +859,9 @@ func TestSimpleLayout_TextMultilineOutput(t *testing.T) {
 	textLines := 0
 	for _, cmd := range commands {
 		switch cmd.RenderData.(type) {
-		case RectangleRenderData:
+		case *RectangleRenderData:
 			hasRectangle = true
-		case TextRenderData:
+		case *TextRenderData:
 			hasText = true
 			textLines++
 		}
@@ -444,14 +972,14 @@ func TestSimpleLayout_ClipContainer(t *testing.T) {
 	for i, cmd := range commands {
 		_ = i
 		switch cmd.RenderData.(type) {
-		case RectangleRenderData:
+		case *RectangleRenderData:
 			hasRectangle = true
-		case TextRenderData:
+		case *TextRenderData:
 			hasText = true
 			textLines++
-		case ScissorsStartData:
+		case *ScissorsStartData:
 			hasSStart = i
-		case ScissorsEndData:
+		case *ScissorsEndData:
 			hasSEnd = i
 		}
 	}
@@ -461,3 +989,1404 @@ func TestSimpleLayout_ClipContainer(t *testing.T) {
 	assert.Equal(t, 12, hasSEnd, "Should have text render command")
 	assert.Equal(t, 4, textLines)
 }
+
+func TestRichText_SpansWrapTogether(t *testing.T) {
+	ctx := Initialize(MakeBoundingBox(MakeVector2(0, 0), MakeDimensions(800, 600)), ErrorHandler{})
+	ctx.SetMeasureTextFunction(mockMeasureText, nil)
+
+	ctx.BeginLayout()
+	ctx.CLAY(ElementDeclaration{
+		Layout: LayoutConfig{
+			Sizing: Sizing{
+				Width:  FIXED(100),
+				Height: FIT(0),
+			},
+		},
+	}, func() {
+		regular := ctx.TEXT_CONFIG(TextElementConfig{FontSize: 16})
+		bold := ctx.TEXT_CONFIG(TextElementConfig{FontId: 1, FontSize: 16, UserData: "link"})
+		ctx.CLAY_RICH_TEXT(regular,
+			TextSpan{Text: "Hello "},
+			TextSpan{Text: "bold", Config: bold},
+			TextSpan{Text: ". Next line"},
+		)
+	})
+	commands := ctx.EndLayout()
+
+	var texts []string
+	var boxes []BoundingBox
+	for _, cmd := range commands {
+		if text, ok := cmd.RenderData.(*TextRenderData); ok {
+			texts = append(texts, text.StringContents)
+			boxes = append(boxes, cmd.BoundingBox)
+		}
+	}
+	// "bold" and "." are glued together and move to the next line as one word
+	assert.Equal(t, []string{"Hello", "bold", ". Next", "line"}, texts)
+	assert.Equal(t, float32(0), boxes[1].X())
+	assert.Equal(t, float32(20), boxes[1].Y())
+	assert.Equal(t, float32(40), boxes[2].X())
+	assert.Equal(t, float32(20), boxes[2].Y())
+	assert.Equal(t, float32(40), boxes[3].Y())
+
+	for _, cmd := range commands {
+		if text, ok := cmd.RenderData.(*TextRenderData); ok && text.StringContents == "bold" {
+			assert.Equal(t, uint16(1), text.FontId)
+			assert.Equal(t, "link", cmd.UserData)
+		}
+	}
+}
+
+func TestRightToLeft_DirectionAndMirroring(t *testing.T) {
+	ctx := Initialize(MakeBoundingBox(MakeVector2(0, 0), MakeDimensions(800, 600)), ErrorHandler{})
+	ctx.SetMeasureTextFunction(mockMeasureText, nil)
+
+	ctx.BeginLayout()
+	ctx.CLAY_ID(ID("row"), ElementDeclaration{
+		Layout: LayoutConfig{
+			Sizing:          Sizing{Width: FIXED(300), Height: FIXED(50)},
+			LayoutDirection: RIGHT_TO_LEFT,
+			Padding:         Padding{Left: 10, Right: 20},
+			ChildGap:        5,
+		},
+	}, func() {
+		ctx.CLAY_ID(ID("first"), ElementDeclaration{Layout: LayoutConfig{Sizing: Sizing{Width: FIXED(50), Height: FIXED(10)}}})
+		ctx.CLAY_ID(ID("second"), ElementDeclaration{Layout: LayoutConfig{Sizing: Sizing{Width: FIXED(40), Height: FIXED(10)}}})
+	})
+	ctx.EndLayout()
+
+	// Children are packed to the left in reverse order, the first child is the rightmost one
+	assert.Equal(t, float32(55), ctx.GetElementData(ID("first")).BoundingBox.X())
+	assert.Equal(t, float32(10), ctx.GetElementData(ID("second")).BoundingBox.X())
+
+	// In right to left mode the whole layout is mirrored, the row is pushed to the right edge of the root
+	ctx.SetRightToLeftEnabled(true)
+	ctx.BeginLayout()
+	ctx.CLAY_ID(ID("row"), ElementDeclaration{
+		Layout: LayoutConfig{
+			Sizing:          Sizing{Width: FIXED(300), Height: FIXED(50)},
+			LayoutDirection: RIGHT_TO_LEFT,
+			Padding:         Padding{Left: 10, Right: 20},
+			ChildGap:        5,
+		},
+	}, func() {
+		ctx.CLAY_ID(ID("first"), ElementDeclaration{Layout: LayoutConfig{Sizing: Sizing{Width: FIXED(50), Height: FIXED(10)}}})
+		ctx.CLAY_ID(ID("second"), ElementDeclaration{Layout: LayoutConfig{Sizing: Sizing{Width: FIXED(40), Height: FIXED(10)}}})
+	})
+	ctx.EndLayout()
+
+	assert.Equal(t, float32(500), ctx.GetElementData(ID("row")).BoundingBox.X())
+	assert.Equal(t, float32(800-55-50), ctx.GetElementData(ID("first")).BoundingBox.X())
+	assert.Equal(t, float32(800-10-40), ctx.GetElementData(ID("second")).BoundingBox.X())
+}
+
+func TestRightToLeft_GridColumns(t *testing.T) {
+	ctx := Initialize(MakeBoundingBox(MakeVector2(0, 0), MakeDimensions(800, 600)), ErrorHandler{})
+	ctx.SetMeasureTextFunction(mockMeasureText, nil)
+
+	layout := func() {
+		ctx.BeginLayout()
+		ctx.CLAY_ID(ID("grid"), ElementDeclaration{
+			Layout: LayoutConfig{
+				Sizing:          Sizing{Width: FIXED(300)},
+				Padding:         Padding{Left: 10, Right: 20},
+				LayoutDirection: GRID,
+				Grid: GridConfig{
+					Columns:   []GridTrack{TRACK_FIXED(50), TRACK_FIXED(100)},
+					ColumnGap: 10,
+				},
+			},
+		}, func() {
+			cell := Sizing{Width: GROW[float32](), Height: FIXED(20)}
+			ctx.CLAY_ID(ID("first"), ElementDeclaration{Layout: LayoutConfig{Sizing: cell}})
+			ctx.CLAY_ID(ID("second"), ElementDeclaration{Layout: LayoutConfig{Sizing: cell}})
+			ctx.CLAY_ID(ID("placed"), ElementDeclaration{Layout: LayoutConfig{Sizing: cell, GridCell: GridCell{Column: 1, Row: 2}}})
+		})
+		ctx.EndLayout()
+	}
+
+	layout()
+	assert.Equal(t, MakeBoundingBox(MakeVector2(10, 0), MakeDimensions(50, 20)), ctx.GetElementData(ID("first")).BoundingBox)
+	assert.Equal(t, MakeBoundingBox(MakeVector2(70, 0), MakeDimensions(100, 20)), ctx.GetElementData(ID("second")).BoundingBox)
+	assert.Equal(t, MakeBoundingBox(MakeVector2(10, 20), MakeDimensions(50, 20)), ctx.GetElementData(ID("placed")).BoundingBox)
+
+	// The first column, and the elements placed into it, are on the right
+	ctx.SetRightToLeftEnabled(true)
+	layout()
+	assert.Equal(t, MakeBoundingBox(MakeVector2(800-10-50, 0), MakeDimensions(50, 20)), ctx.GetElementData(ID("first")).BoundingBox)
+	assert.Equal(t, MakeBoundingBox(MakeVector2(800-70-100, 0), MakeDimensions(100, 20)), ctx.GetElementData(ID("second")).BoundingBox)
+	assert.Equal(t, MakeBoundingBox(MakeVector2(800-10-50, 20), MakeDimensions(50, 20)), ctx.GetElementData(ID("placed")).BoundingBox)
+}
+
+func TestRightToLeft_Margin(t *testing.T) {
+	ctx := Initialize(MakeBoundingBox(MakeVector2(0, 0), MakeDimensions(800, 600)), ErrorHandler{})
+
+	layout := func() {
+		ctx.BeginLayout()
+		ctx.CLAY_ID(ID("a"), ElementDeclaration{Layout: LayoutConfig{Sizing: Sizing{Width: FIXED(30), Height: FIXED(30)}, Margin: Margin{Left: 27}}})
+		ctx.CLAY_ID(ID("b"), ElementDeclaration{Layout: LayoutConfig{Sizing: Sizing{Width: FIXED(30), Height: FIXED(30)}, Margin: Margin{Right: 5}}})
+		ctx.EndLayout()
+	}
+
+	layout()
+	assert.Equal(t, float32(27), ctx.GetElementData(ID("a")).BoundingBox.X())
+	assert.Equal(t, float32(27+30), ctx.GetElementData(ID("b")).BoundingBox.X())
+
+	// Left and right margins swap places like the padding, the layout is the mirror image of the left to right one
+	ctx.SetRightToLeftEnabled(true)
+	layout()
+	assert.Equal(t, float32(800-27-30), ctx.GetElementData(ID("a")).BoundingBox.X())
+	assert.Equal(t, float32(800-27-30-30), ctx.GetElementData(ID("b")).BoundingBox.X())
+}
+
+func TestRightToLeft_BidiTextRuns(t *testing.T) {
+	ctx := Initialize(MakeBoundingBox(MakeVector2(0, 0), MakeDimensions(800, 600)), ErrorHandler{})
+	ctx.SetMeasureTextFunction(mockMeasureText, nil)
+
+	layout := func() []RenderCommand {
+		ctx.BeginLayout()
+		ctx.CLAY(ElementDeclaration{
+			Layout: LayoutConfig{Sizing: Sizing{Width: FIXED(400), Height: FIT(0)}},
+		}, func() {
+			ctx.CLAY_TEXT("abc שלום def", ctx.TEXT_CONFIG(TextElementConfig{FontSize: 16}))
+		})
+		return ctx.EndLayout()
+	}
+
+	type run struct {
+		text        string
+		rightToLeft bool
+		x           float32
+	}
+	collect := func(commands []RenderCommand) (runs []run) {
+		for _, cmd := range commands {
+			if text, ok := cmd.RenderData.(*TextRenderData); ok {
+				runs = append(runs, run{text.StringContents, text.RightToLeft, cmd.BoundingBox.X()})
+			}
+		}
+		return
+	}
+
+	// Left to right paragraph keeps the runs in logical order
+	assert.Equal(t, []run{
+		{"abc ", false, 0},
+		{"שלום", true, 40},
+		{" def", false, 120},
+	}, collect(layout()))
+
+	// Right to left paragraph reverses the run order, neutral spaces join the right to left run.
+	// The container itself is mirrored to the right half of the root and text is aligned to the right.
+	ctx.SetRightToLeftEnabled(true)
+	assert.Equal(t, []run{
+		{"def", false, 640},
+		{" שלום ", true, 670},
+		{"abc", false, 770},
+	}, collect(layout()))
+
+	// Switching back restores the left to right paragraph direction
+	ctx.SetRightToLeftEnabled(false)
+	assert.Equal(t, []run{
+		{"abc ", false, 0},
+		{"שלום", true, 40},
+		{" def", false, 120},
+	}, collect(layout()))
+}
+
+func TestBaselineAlignment_MixedFontSizes(t *testing.T) {
+	ctx := Initialize(MakeBoundingBox(MakeVector2(0, 0), MakeDimensions(800, 600)), ErrorHandler{})
+	// Ascent is 80% of the font size, descent is the rest
+	ctx.SetMeasureTextMetricsFunction(func(text string, config *TextElementConfig, userData any) TextMetrics {
+		size := float32(config.FontSize)
+		return TextMetrics{
+			Dimensions: MakeDimensions(float32(len(text))*size/2, size),
+			Ascent:     size * 0.8,
+			Descent:    size * 0.2,
+		}
+	}, nil)
+
+	ctx.BeginLayout()
+	ctx.CLAY_ID(ID("row"), ElementDeclaration{
+		Layout: LayoutConfig{
+			Padding:        Padding{Top: 5, Bottom: 5},
+			ChildAlignment: ChildAlignment{Y: ALIGN_Y_BASELINE},
+		},
+	}, func() {
+		ctx.CLAY_TEXT("Heading", ctx.TEXT_CONFIG(TextElementConfig{FontSize: 40}))
+		ctx.CLAY_TEXT("label", ctx.TEXT_CONFIG(TextElementConfig{FontSize: 10, LineHeight: 20}))
+		ctx.CLAY_ID(ID("icon"), ElementDeclaration{Layout: LayoutConfig{Sizing: Sizing{Width: FIXED(16), Height: FIXED(16)}}})
+	})
+	commands := ctx.EndLayout()
+
+	var baselines []float32
+	for _, cmd := range commands {
+		if text, ok := cmd.RenderData.(*TextRenderData); ok {
+			baselines = append(baselines, cmd.BoundingBox.Y()+text.BaselineOffset)
+		}
+	}
+	// Both texts sit on the heading baseline: padding 5 + ascent 32
+	assert.Equal(t, []float32{37, 37}, baselines)
+	// The icon bottom edge sits on the baseline
+	icon := ctx.GetElementData(ID("icon")).BoundingBox
+	assert.Equal(t, float32(37), icon.Y()+icon.Height())
+	// The heading descent defines the space below the baseline
+	assert.Equal(t, float32(5+32+8+5), ctx.GetElementData(ID("row")).BoundingBox.Height())
+}
+
+// The height left by the ascent and descent, such as a line gap, is split above and below the text.
+func TestBaselineAlignment_DescentSplitsLineGap(t *testing.T) {
+	ctx := Initialize(MakeBoundingBox(MakeVector2(0, 0), MakeDimensions(800, 600)), ErrorHandler{})
+	// Ascent is 80% of the font size and descent the rest, in a line 1.5 times the font size. Font 1 has no descent.
+	ctx.SetMeasureTextMetricsFunction(func(text string, config *TextElementConfig, userData any) TextMetrics {
+		size := float32(config.FontSize)
+		descent := size * 0.2
+		if config.FontId == 1 {
+			descent = 0
+		}
+		return TextMetrics{
+			Dimensions: MakeDimensions(float32(len(text))*size/2, size*1.5),
+			Ascent:     size * 0.8,
+			Descent:    descent,
+		}
+	}, nil)
+
+	ctx.BeginLayout()
+	ctx.CLAY_ID(ID("row"), ElementDeclaration{
+		Layout: LayoutConfig{ChildAlignment: ChildAlignment{Y: ALIGN_Y_BASELINE}},
+	}, func() {
+		ctx.CLAY_TEXT("plain", ctx.TEXT_CONFIG(TextElementConfig{FontSize: 20}))
+		ctx.CLAY_RICH_TEXT(ctx.TEXT_CONFIG(TextElementConfig{FontSize: 20}), TextSpan{Text: "rich"})
+		ctx.CLAY_TEXT("nodescent", ctx.TEXT_CONFIG(TextElementConfig{FontSize: 20, FontId: 1}))
+	})
+	commands := ctx.EndLayout()
+
+	var offsets, baselines []float32
+	for _, cmd := range commands {
+		if text, ok := cmd.RenderData.(*TextRenderData); ok {
+			offsets = append(offsets, text.BaselineOffset)
+			baselines = append(baselines, cmd.BoundingBox.Y()+text.BaselineOffset)
+		}
+	}
+	// Half of the 10px gap above the 16px ascent, the text without a descent fills the height below its baseline
+	assert.Equal(t, []float32{21, 21, 16}, offsets)
+	assert.Equal(t, []float32{21, 21, 21}, baselines)
+}
+
+func TestLayoutWrap_Rows(t *testing.T) {
+	ctx := Initialize(MakeBoundingBox(MakeVector2(0, 0), MakeDimensions(800, 600)), ErrorHandler{})
+	ctx.SetMeasureTextFunction(mockMeasureText, nil)
+
+	tagSizes := []Dimensions{{X: 60, Y: 20}, {X: 60, Y: 30}, {X: 60, Y: 20}, {X: 100, Y: 20}, {X: 50, Y: 20}}
+	declare := func(tagsLayout LayoutConfig) {
+		ctx.BeginLayout()
+		ctx.CLAY_ID(ID("page"), ElementDeclaration{Layout: LayoutConfig{LayoutDirection: TOP_TO_BOTTOM}}, func() {
+			ctx.CLAY_ID(ID("tags"), ElementDeclaration{Layout: tagsLayout}, func() {
+				for i, size := range tagSizes {
+					ctx.CLAY_ID(IDI("tag", uint32(i)), ElementDeclaration{Layout: LayoutConfig{Sizing: Sizing{Width: FIXED(size.X), Height: FIXED(size.Y)}}})
+				}
+			})
+			ctx.CLAY_ID(ID("footer"), ElementDeclaration{Layout: LayoutConfig{Sizing: Sizing{Width: FIXED(10), Height: FIXED(10)}}})
+		})
+		ctx.EndLayout()
+	}
+	tagPosition := func(i int) Vector2 {
+		return ctx.GetElementData(IDI("tag", uint32(i))).BoundingBox.Position
+	}
+
+	// The content box is 180 wide, the rows are [0 1], [2 3] and [4]
+	declare(LayoutConfig{
+		Sizing:         Sizing{Width: FIXED(200)},
+		Padding:        PADDING(10),
+		ChildGap:       10,
+		ChildAlignment: ChildAlignment{Y: ALIGN_Y_CENTER},
+		Wrap:           LAYOUT_WRAP,
+		LineGap:        5,
+	})
+	// The FIT height covers every row and line gap, the elements after the container move down with it
+	assert.Equal(t, float32(10+30+5+20+5+20+10), ctx.GetElementData(ID("tags")).BoundingBox.Height())
+	assert.Equal(t, float32(100), ctx.GetElementData(ID("footer")).BoundingBox.Y())
+	assert.Equal(t, MakeVector2(10, 15), tagPosition(0)) // Centered in the 30px high first row
+	assert.Equal(t, MakeVector2(80, 10), tagPosition(1))
+	assert.Equal(t, MakeVector2(10, 45), tagPosition(2))
+	assert.Equal(t, MakeVector2(80, 45), tagPosition(3))
+	assert.Equal(t, MakeVector2(10, 70), tagPosition(4))
+
+	// Rows are aligned by ChildAlignment.X one by one, the block of rows by LineAlignment
+	declare(LayoutConfig{
+		Sizing:         Sizing{Width: FIXED(200), Height: FIXED(150)},
+		Padding:        PADDING(10),
+		ChildGap:       10,
+		ChildAlignment: ChildAlignment{X: ALIGN_X_CENTER},
+		Wrap:           LAYOUT_WRAP,
+		LineGap:        5,
+		LineAlignment:  ALIGN_LINES_END,
+	})
+	assert.Equal(t, MakeVector2(10+25, 10+50), tagPosition(0))
+	assert.Equal(t, MakeVector2(10+5, 10+50+35), tagPosition(2))
+	assert.Equal(t, MakeVector2(10+65, 10+50+60), tagPosition(4))
+}
+
+func TestLayoutWrap_GrowAndCompressPerLine(t *testing.T) {
+	ctx := Initialize(MakeBoundingBox(MakeVector2(0, 0), MakeDimensions(800, 600)), ErrorHandler{})
+	ctx.SetMeasureTextFunction(mockMeasureText, nil)
+
+	ctx.BeginLayout()
+	ctx.CLAY_ID(ID("toolbar"), ElementDeclaration{
+		Layout: LayoutConfig{Sizing: Sizing{Width: FIXED(200)}, Wrap: LAYOUT_WRAP},
+	}, func() {
+		ctx.CLAY_ID(ID("button"), ElementDeclaration{Layout: LayoutConfig{Sizing: Sizing{Width: FIXED(150), Height: FIXED(20)}}})
+		ctx.CLAY_ID(ID("search"), ElementDeclaration{Layout: LayoutConfig{Sizing: Sizing{Width: GROW[float32](100), Height: GROW[float32]()}}})
+		ctx.CLAY_ID(ID("icon"), ElementDeclaration{Layout: LayoutConfig{Sizing: Sizing{Width: FIXED(50), Height: FIXED(30)}}})
+		ctx.CLAY_ID(ID("label"), ElementDeclaration{}, func() {
+			ctx.CLAY_TEXT("a label longer than the toolbar", ctx.TEXT_CONFIG(TextElementConfig{}))
+		})
+	})
+	ctx.EndLayout()
+
+	// GROW children grow into the rest of their own line, along both axes
+	search := ctx.GetElementData(ID("search")).BoundingBox
+	assert.Equal(t, MakeBoundingBox(MakeVector2(0, 20), MakeDimensions(150, 30)), search)
+	assert.Equal(t, MakeVector2(150, 20), ctx.GetElementData(ID("icon")).BoundingBox.Position)
+	// A child longer than a line is alone on its line and compressed like in a container that doesn't wrap
+	label := ctx.GetElementData(ID("label")).BoundingBox
+	assert.Equal(t, MakeVector2(0, 50), label.Position)
+	assert.Equal(t, float32(200), label.Width())
+	assert.Equal(t, float32(20+30)+label.Height(), ctx.GetElementData(ID("toolbar")).BoundingBox.Height())
+}
+
+func TestLayoutWrap_Columns(t *testing.T) {
+	ctx := Initialize(MakeBoundingBox(MakeVector2(0, 0), MakeDimensions(800, 600)), ErrorHandler{})
+	ctx.SetMeasureTextFunction(mockMeasureText, nil)
+
+	ctx.BeginLayout()
+	ctx.CLAY_ID(ID("gallery"), ElementDeclaration{
+		Layout: LayoutConfig{
+			Sizing:          Sizing{Height: FIXED(100)},
+			LayoutDirection: TOP_TO_BOTTOM,
+			Wrap:            LAYOUT_WRAP,
+			LineGap:         10,
+		},
+	}, func() {
+		for i := range 5 {
+			width := FIXED(40)
+			if i == 1 {
+				width = GROW[float32]()
+			}
+			ctx.CLAY_ID(IDI("thumbnail", uint32(i)), ElementDeclaration{Layout: LayoutConfig{Sizing: Sizing{Width: width, Height: FIXED(40)}}})
+		}
+	})
+	ctx.EndLayout()
+
+	// The columns are [0 1], [2 3] and [4], the FIT width covers all of them
+	assert.Equal(t, float32(40*3+10*2), ctx.GetElementData(ID("gallery")).BoundingBox.Width())
+	assert.Equal(t, MakeBoundingBox(MakeVector2(0, 40), MakeDimensions(40, 40)), ctx.GetElementData(IDI("thumbnail", 1)).BoundingBox)
+	assert.Equal(t, MakeVector2(50, 0), ctx.GetElementData(IDI("thumbnail", 2)).BoundingBox.Position)
+	assert.Equal(t, MakeVector2(100, 0), ctx.GetElementData(IDI("thumbnail", 4)).BoundingBox.Position)
+}
+
+func TestLayoutWrap_ColumnsInFitParent(t *testing.T) {
+	ctx := Initialize(MakeBoundingBox(MakeVector2(0, 0), MakeDimensions(800, 600)), ErrorHandler{})
+	ctx.SetMeasureTextFunction(mockMeasureText, nil)
+
+	gallery := func(id string, height SizingAxis) {
+		ctx.CLAY_ID(ID(id), ElementDeclaration{
+			Layout: LayoutConfig{
+				Sizing:          Sizing{Height: height},
+				LayoutDirection: TOP_TO_BOTTOM,
+				Wrap:            LAYOUT_WRAP,
+				LineGap:         10,
+			},
+		}, func() {
+			for i := range 5 {
+				ctx.CLAY_ID(IDI(id+"Thumbnail", uint32(i)), ElementDeclaration{Layout: LayoutConfig{Sizing: Sizing{Width: FIXED(50), Height: FIXED(40)}}})
+			}
+		})
+	}
+
+	ctx.BeginLayout()
+	ctx.CLAY_ID(ID("fixedRow"), ElementDeclaration{}, func() {
+		gallery("fixedGallery", FIXED(100))
+		ctx.CLAY_ID(ID("fixedSibling"), ElementDeclaration{Layout: LayoutConfig{Sizing: Sizing{Width: FIXED(30), Height: FIXED(30)}}})
+	})
+	ctx.CLAY_ID(ID("growFrame"), ElementDeclaration{Layout: LayoutConfig{Sizing: Sizing{Height: FIXED(100)}}}, func() {
+		ctx.CLAY_ID(ID("growRow"), ElementDeclaration{Layout: LayoutConfig{Sizing: Sizing{Height: GROW[float32]()}}}, func() {
+			gallery("growGallery", GROW[float32]())
+			ctx.CLAY_ID(ID("growSibling"), ElementDeclaration{Layout: LayoutConfig{Sizing: Sizing{Width: FIXED(30), Height: FIXED(30)}}})
+		})
+	})
+	ctx.EndLayout()
+
+	// The columns are [0 1], [2 3] and [4], the FIT row is sized around all of them and the sibling follows the last one
+	columnsWidth := float32(50*3 + 10*2)
+	for _, prefix := range []string{"fixed", "grow"} {
+		assert.Equal(t, columnsWidth, ctx.GetElementData(ID(prefix+"Gallery")).BoundingBox.Width(), prefix)
+		assert.Equal(t, columnsWidth+30, ctx.GetElementData(ID(prefix+"Row")).BoundingBox.Width(), prefix)
+		rowX := ctx.GetElementData(ID(prefix + "Row")).BoundingBox.Position.X
+		assert.Equal(t, rowX+columnsWidth, ctx.GetElementData(ID(prefix+"Sibling")).BoundingBox.Position.X, prefix)
+	}
+	assert.Equal(t, columnsWidth+30, ctx.GetElementData(ID("growFrame")).BoundingBox.Width())
+}
+
+func TestGridLayout_Form(t *testing.T) {
+	ctx := Initialize(MakeBoundingBox(MakeVector2(0, 0), MakeDimensions(800, 600)), ErrorHandler{})
+	ctx.SetMeasureTextFunction(mockMeasureText, nil)
+
+	labels := []string{"Name", "Email address", "Notes"}
+	inputHeights := []float32{30, 30, 60}
+	ctx.BeginLayout()
+	ctx.CLAY_ID(ID("form"), ElementDeclaration{
+		Layout: LayoutConfig{
+			Sizing:          Sizing{Width: FIXED(400)},
+			Padding:         PADDING(10),
+			LayoutDirection: GRID,
+			Grid: GridConfig{
+				Columns:   []GridTrack{TRACK_FIT(), TRACK_FR(1)},
+				ColumnGap: 10,
+				RowGap:    5,
+			},
+		},
+	}, func() {
+		for i, label := range labels {
+			ctx.CLAY_TEXT(label, ctx.TEXT_CONFIG(TextElementConfig{}))
+			ctx.CLAY_ID(IDI("input", uint32(i)), ElementDeclaration{Layout: LayoutConfig{Sizing: Sizing{Width: GROW[float32](), Height: FIXED(inputHeights[i])}}})
+		}
+	})
+	ctx.EndLayout()
+
+	// The label column fits the longest label, the input column takes the rest of the width
+	assert.Equal(t, MakeBoundingBox(MakeVector2(10+130+10, 10), MakeDimensions(400-20-10-130, 30)), ctx.GetElementData(IDI("input", 0)).BoundingBox)
+	assert.Equal(t, MakeVector2(150, 10+30+5), ctx.GetElementData(IDI("input", 1)).BoundingBox.Position)
+	assert.Equal(t, MakeVector2(150, 10+30+5+30+5), ctx.GetElementData(IDI("input", 2)).BoundingBox.Position)
+	// The FIT height covers every row
+	assert.Equal(t, float32(10+30+5+30+5+60+10), ctx.GetElementData(ID("form")).BoundingBox.Height())
+}
+
+func TestGridLayout_PlacementAndSpans(t *testing.T) {
+	ctx := Initialize(MakeBoundingBox(MakeVector2(0, 0), MakeDimensions(800, 600)), ErrorHandler{})
+	ctx.SetMeasureTextFunction(mockMeasureText, nil)
+
+	tile := LayoutConfig{Sizing: Sizing{Width: GROW[float32](), Height: FIXED(40)}}
+	ctx.BeginLayout()
+	ctx.CLAY_ID(ID("dashboard"), ElementDeclaration{
+		Layout: LayoutConfig{
+			Sizing:          Sizing{Width: FIXED(500)},
+			LayoutDirection: GRID,
+			Grid:            GridConfig{Columns: []GridTrack{TRACK_FIXED(100), TRACK_FR(1), TRACK_FR(3)}},
+			ChildAlignment:  ChildAlignment{X: ALIGN_X_CENTER},
+		},
+	}, func() {
+		ctx.CLAY_ID(ID("header"), ElementDeclaration{Layout: LayoutConfig{
+			Sizing:   Sizing{Width: GROW[float32](), Height: FIXED(20)},
+			GridCell: GridCell{ColumnSpan: 3},
+		}})
+		ctx.CLAY_ID(ID("a"), ElementDeclaration{Layout: tile})
+		ctx.CLAY_ID(ID("b"), ElementDeclaration{Layout: LayoutConfig{Sizing: Sizing{Width: FIXED(50), Height: FIXED(40)}}})
+		ctx.CLAY_ID(ID("c"), ElementDeclaration{Layout: tile})
+		// Placed before the other children, they flow around it
+		ctx.CLAY_ID(ID("sidebar"), ElementDeclaration{Layout: LayoutConfig{
+			Sizing:   Sizing{Width: GROW[float32](), Height: GROW[float32]()},
+			GridCell: GridCell{Column: 1, Row: 2, RowSpan: 2},
+		}})
+	})
+	ctx.EndLayout()
+
+	boundingBox := func(id string) BoundingBox {
+		return ctx.GetElementData(ID(id)).BoundingBox
+	}
+	assert.Equal(t, MakeBoundingBox(MakeVector2(0, 0), MakeDimensions(500, 20)), boundingBox("header"))
+	assert.Equal(t, MakeBoundingBox(MakeVector2(100, 20), MakeDimensions(100, 40)), boundingBox("a"))
+	// Children that don't fill their cell are aligned by ChildAlignment
+	assert.Equal(t, MakeBoundingBox(MakeVector2(200+125, 20), MakeDimensions(50, 40)), boundingBox("b"))
+	assert.Equal(t, MakeBoundingBox(MakeVector2(100, 60), MakeDimensions(100, 40)), boundingBox("c"))
+	assert.Equal(t, MakeBoundingBox(MakeVector2(0, 20), MakeDimensions(100, 80)), boundingBox("sidebar"))
+	assert.Equal(t, float32(100), boundingBox("dashboard").Height())
+}
+
+func TestGridLayout_FitAndClampedFractions(t *testing.T) {
+	ctx := Initialize(MakeBoundingBox(MakeVector2(0, 0), MakeDimensions(800, 600)), ErrorHandler{})
+	ctx.SetMeasureTextFunction(mockMeasureText, nil)
+
+	ctx.BeginLayout()
+	ctx.CLAY(ElementDeclaration{Layout: LayoutConfig{LayoutDirection: TOP_TO_BOTTOM}}, func() {
+		// A FIT grid gives every fraction the size the largest FR track needs
+		ctx.CLAY_ID(ID("fit"), ElementDeclaration{Layout: LayoutConfig{
+			LayoutDirection: GRID,
+			Grid:            GridConfig{Columns: []GridTrack{TRACK_FR(1), TRACK_FR(1)}, ColumnGap: 10},
+		}}, func() {
+			ctx.CLAY_ID(ID("narrow"), ElementDeclaration{Layout: LayoutConfig{Sizing: Sizing{Width: FIXED(50), Height: FIXED(10)}}})
+			ctx.CLAY_ID(ID("wide"), ElementDeclaration{Layout: LayoutConfig{Sizing: Sizing{Width: FIXED(100), Height: FIXED(10)}}})
+		})
+		// A FR track clamped to its maximum leaves the rest to the other FR tracks
+		ctx.CLAY_ID(ID("clamped"), ElementDeclaration{Layout: LayoutConfig{
+			Sizing:          Sizing{Width: FIXED(300)},
+			LayoutDirection: GRID,
+			Grid:            GridConfig{Columns: []GridTrack{TRACK_FR(1, 0, 50), TRACK_FR(1)}},
+		}}, func() {
+			ctx.CLAY_ID(ID("first"), ElementDeclaration{Layout: LayoutConfig{Sizing: Sizing{Width: GROW[float32](), Height: FIXED(10)}}})
+			ctx.CLAY_ID(ID("second"), ElementDeclaration{Layout: LayoutConfig{Sizing: Sizing{Width: GROW[float32](), Height: FIXED(10)}}})
+		})
+	})
+	ctx.EndLayout()
+
+	assert.Equal(t, float32(100+10+100), ctx.GetElementData(ID("fit")).BoundingBox.Width())
+	assert.Equal(t, float32(110), ctx.GetElementData(ID("wide")).BoundingBox.X())
+	assert.Equal(t, float32(50), ctx.GetElementData(ID("first")).BoundingBox.Width())
+	assert.Equal(t, MakeVector2(50, 10), ctx.GetElementData(ID("second")).BoundingBox.Position)
+	assert.Equal(t, float32(250), ctx.GetElementData(ID("second")).BoundingBox.Width())
+}
+
+func TestStackLayout_Overlap(t *testing.T) {
+	ctx := Initialize(MakeBoundingBox(MakeVector2(0, 0), MakeDimensions(800, 600)), ErrorHandler{})
+	ctx.SetMeasureTextFunction(mockMeasureText, nil)
+
+	ctx.BeginLayout()
+	ctx.CLAY(ElementDeclaration{Layout: LayoutConfig{ChildGap: 10}}, func() {
+		ctx.CLAY_ID(ID("icon"), ElementDeclaration{Layout: LayoutConfig{
+			Padding:         Padding{Left: 2, Top: 2},
+			LayoutDirection: STACK,
+			ChildAlignment:  ChildAlignment{X: ALIGN_X_RIGHT, Y: ALIGN_Y_TOP},
+		}}, func() {
+			ctx.CLAY_ID(ID("image"), ElementDeclaration{
+				Layout:          LayoutConfig{Sizing: Sizing{Width: FIXED(40), Height: FIXED(40)}},
+				BackgroundColor: Color{R: 255, A: 255},
+			})
+			ctx.CLAY_ID(ID("badge"), ElementDeclaration{
+				Layout:          LayoutConfig{Sizing: Sizing{Width: FIXED(12), Height: FIXED(12)}},
+				BackgroundColor: Color{G: 255, A: 255},
+			})
+		})
+		ctx.CLAY_ID(ID("card"), ElementDeclaration{Layout: LayoutConfig{
+			LayoutDirection: STACK,
+			ChildAlignment:  ChildAlignment{Y: ALIGN_Y_BOTTOM},
+		}}, func() {
+			ctx.CLAY_ID(ID("photo"), ElementDeclaration{Layout: LayoutConfig{Sizing: Sizing{Width: FIXED(200), Height: FIXED(100)}}})
+			ctx.CLAY_ID(ID("caption"), ElementDeclaration{Layout: LayoutConfig{Sizing: Sizing{Width: GROW[float32]()}}}, func() {
+				ctx.CLAY_TEXT("caption", ctx.TEXT_CONFIG(TextElementConfig{}))
+			})
+		})
+	})
+	commands := ctx.EndLayout()
+
+	// The FIT size of a stack is the size of its largest child on each axis
+	assert.Equal(t, MakeDimensions(42, 42), ctx.GetElementData(ID("icon")).BoundingBox.Size)
+	assert.Equal(t, MakeVector2(2, 2), ctx.GetElementData(ID("image")).BoundingBox.Position)
+	assert.Equal(t, MakeVector2(2+28, 2), ctx.GetElementData(ID("badge")).BoundingBox.Position)
+	// Later children are drawn over earlier ones
+	var order []uint64
+	for _, cmd := range commands {
+		order = append(order, cmd.Id)
+	}
+	assert.Equal(t, []uint64{ID("image").id, ID("badge").id}, order[:2])
+
+	// GROW children fill the stack on both axes
+	card := ctx.GetElementData(ID("card")).BoundingBox
+	assert.Equal(t, MakeDimensions(200, 100), card.Size)
+	assert.Equal(t, MakeBoundingBox(MakeVector2(card.X(), 80), MakeDimensions(200, 20)), ctx.GetElementData(ID("caption")).BoundingBox)
+}
+
+func TestSizingGrow_Weights(t *testing.T) {
+	ctx := Initialize(MakeBoundingBox(MakeVector2(0, 0), MakeDimensions(920, 600)), ErrorHandler{})
+
+	// A split view updates the weights of its panes every frame
+	for _, mainWeight := range []float32{2, 3} {
+		ctx.BeginLayout()
+		ctx.CLAY(ElementDeclaration{Layout: LayoutConfig{Sizing: Sizing{Width: GROW[float32](), Height: GROW[float32]()}, ChildGap: 20}}, func() {
+			ctx.CLAY_ID(ID("main"), ElementDeclaration{Layout: LayoutConfig{Sizing: Sizing{Width: GROW[float32]().WithWeight(mainWeight)}}})
+			ctx.CLAY_ID(ID("side"), ElementDeclaration{Layout: LayoutConfig{Sizing: Sizing{Width: GROW[float32]()}}})
+		})
+		ctx.EndLayout()
+
+		side := 900 / (mainWeight + 1)
+		assert.Equal(t, 900-side, ctx.GetElementData(ID("main")).BoundingBox.Width())
+		assert.Equal(t, side, ctx.GetElementData(ID("side")).BoundingBox.Width())
+	}
+}
+
+func TestSizingGrow_SmallerChildAfterLarger(t *testing.T) {
+	ctx := Initialize(MakeBoundingBox(MakeVector2(0, 0), MakeDimensions(800, 600)), ErrorHandler{})
+	ctx.SetMeasureTextFunction(mockMeasureText, nil)
+	textConfig := ctx.TEXT_CONFIG(TextElementConfig{})
+
+	ctx.BeginLayout()
+	ctx.CLAY(ElementDeclaration{Layout: LayoutConfig{LayoutDirection: TOP_TO_BOTTOM}}, func() {
+		// Grown from the smallest up, wherever the smallest child is
+		ctx.CLAY(ElementDeclaration{Layout: LayoutConfig{Sizing: Sizing{Width: FIXED(300)}}}, func() {
+			ctx.CLAY_ID(ID("larger"), ElementDeclaration{Layout: LayoutConfig{Sizing: Sizing{Width: GROW[float32]()}}}, func() {
+				ctx.CLAY(ElementDeclaration{Layout: LayoutConfig{Sizing: Sizing{Width: FIXED(100), Height: FIXED(10)}}})
+			})
+			ctx.CLAY_ID(ID("smaller"), ElementDeclaration{Layout: LayoutConfig{Sizing: Sizing{Width: GROW[float32]()}}})
+		})
+		// Compressed from the largest down, wherever the largest child is
+		ctx.CLAY(ElementDeclaration{Layout: LayoutConfig{Sizing: Sizing{Width: FIXED(150)}}}, func() {
+			ctx.CLAY_ID(ID("shortText"), ElementDeclaration{}, func() {
+				ctx.CLAY_TEXT("aaaa aaaa", textConfig)
+			})
+			ctx.CLAY_ID(ID("longText"), ElementDeclaration{}, func() {
+				ctx.CLAY_TEXT("aaaa aaaa aaaa aaaa", textConfig)
+			})
+		})
+	})
+	ctx.EndLayout()
+
+	assert.Equal(t, float32(150), ctx.GetElementData(ID("larger")).BoundingBox.Width())
+	assert.Equal(t, float32(150), ctx.GetElementData(ID("smaller")).BoundingBox.Width())
+	assert.Equal(t, float32(75), ctx.GetElementData(ID("shortText")).BoundingBox.Width())
+	assert.Equal(t, float32(75), ctx.GetElementData(ID("longText")).BoundingBox.Width())
+}
+
+func TestSizingGrow_ShrinkAndBasis(t *testing.T) {
+	ctx := Initialize(MakeBoundingBox(MakeVector2(0, 0), MakeDimensions(800, 600)), ErrorHandler{})
+
+	ctx.BeginLayout()
+	ctx.CLAY(ElementDeclaration{Layout: LayoutConfig{LayoutDirection: TOP_TO_BOTTOM}}, func() {
+		// The overflow is given up in proportion to the shrink weights times the sizes
+		ctx.CLAY(ElementDeclaration{Layout: LayoutConfig{Sizing: Sizing{Width: FIXED(300)}}}, func() {
+			ctx.CLAY_ID(ID("kept"), ElementDeclaration{Layout: LayoutConfig{Sizing: Sizing{Width: GROW[float32]().WithBasis(200).WithShrink(1)}}})
+			ctx.CLAY_ID(ID("given"), ElementDeclaration{Layout: LayoutConfig{Sizing: Sizing{Width: GROW[float32]().WithBasis(200).WithShrink(3)}}})
+		})
+		ctx.CLAY(ElementDeclaration{Layout: LayoutConfig{Sizing: Sizing{Width: FIXED(300)}}}, func() {
+			ctx.CLAY_ID(ID("larger"), ElementDeclaration{Layout: LayoutConfig{Sizing: Sizing{Width: GROW[float32]().WithBasis(300)}}})
+			ctx.CLAY_ID(ID("smaller"), ElementDeclaration{Layout: LayoutConfig{Sizing: Sizing{Width: GROW[float32]().WithBasis(100).WithShrink(1)}}})
+		})
+		// A child that reaches its minimum size keeps it, its siblings give up the rest
+		ctx.CLAY(ElementDeclaration{Layout: LayoutConfig{Sizing: Sizing{Width: FIXED(300)}}}, func() {
+			ctx.CLAY_ID(ID("rest"), ElementDeclaration{Layout: LayoutConfig{Sizing: Sizing{Width: GROW[float32]().WithBasis(200)}}})
+			ctx.CLAY_ID(ID("min"), ElementDeclaration{Layout: LayoutConfig{Sizing: Sizing{Width: GROW[float32](150).WithBasis(200).WithShrink(3)}}})
+		})
+		// Grown from the basis instead of the size of the content
+		ctx.CLAY(ElementDeclaration{Layout: LayoutConfig{Sizing: Sizing{Width: FIXED(500)}}}, func() {
+			ctx.CLAY_ID(ID("basis"), ElementDeclaration{Layout: LayoutConfig{Sizing: Sizing{Width: GROW[float32]().WithBasis(300)}}})
+			ctx.CLAY_ID(ID("content"), ElementDeclaration{Layout: LayoutConfig{Sizing: Sizing{Width: GROW[float32]()}}})
+		})
+		// Without free space to grow into the basis is the size of the element
+		ctx.CLAY(ElementDeclaration{}, func() {
+			ctx.CLAY_ID(ID("fit"), ElementDeclaration{Layout: LayoutConfig{Sizing: Sizing{Width: GROW[float32]().WithBasis(120)}}})
+		})
+	})
+	ctx.EndLayout()
+
+	// 100 pixels of overflow are given up 1:3
+	assert.InDelta(t, 175, ctx.GetElementData(ID("kept")).BoundingBox.Width(), 0.01)
+	assert.InDelta(t, 125, ctx.GetElementData(ID("given")).BoundingBox.Width(), 0.01)
+	// Same weights, the larger child gives up 3 times as much
+	assert.InDelta(t, 225, ctx.GetElementData(ID("larger")).BoundingBox.Width(), 0.01)
+	assert.InDelta(t, 75, ctx.GetElementData(ID("smaller")).BoundingBox.Width(), 0.01)
+	assert.InDelta(t, 150, ctx.GetElementData(ID("rest")).BoundingBox.Width(), 0.01)
+	assert.InDelta(t, 150, ctx.GetElementData(ID("min")).BoundingBox.Width(), 0.01)
+	assert.Equal(t, float32(300), ctx.GetElementData(ID("basis")).BoundingBox.Width())
+	assert.Equal(t, float32(200), ctx.GetElementData(ID("content")).BoundingBox.Width())
+	assert.Equal(t, float32(120), ctx.GetElementData(ID("fit")).BoundingBox.Width())
+}
+
+func TestChildDistribution_AlongLayoutAxis(t *testing.T) {
+	ctx := Initialize(MakeBoundingBox(MakeVector2(0, 0), MakeDimensions(800, 600)), ErrorHandler{})
+
+	positions := func(direction LayoutDirection, distribution LayoutDistribution, childGap uint16, count int) []Vector2 {
+		ctx.BeginLayout()
+		ctx.CLAY(ElementDeclaration{Layout: LayoutConfig{
+			Sizing:            Sizing{Width: FIXED(600), Height: FIXED(400)},
+			ChildGap:          childGap,
+			LayoutDirection:   direction,
+			ChildDistribution: distribution,
+		}}, func() {
+			for i := range count {
+				ctx.CLAY_ID(ctx.IDI("child", uint32(i)), ElementDeclaration{Layout: LayoutConfig{Sizing: Sizing{Width: FIXED(100), Height: FIXED(100)}}})
+			}
+		})
+		ctx.EndLayout()
+
+		var result []Vector2
+		for i := range count {
+			result = append(result, ctx.GetElementData(ctx.IDI("child", uint32(i))).BoundingBox.Position)
+		}
+		return result
+	}
+
+	assert.Equal(t, []Vector2{MakeVector2(0, 0), MakeVector2(250, 0), MakeVector2(500, 0)}, positions(LEFT_TO_RIGHT, DISTRIBUTE_SPACE_BETWEEN, 0, 3))
+	assert.Equal(t, []Vector2{MakeVector2(500, 0), MakeVector2(250, 0), MakeVector2(0, 0)}, positions(RIGHT_TO_LEFT, DISTRIBUTE_SPACE_BETWEEN, 0, 3))
+	assert.Equal(t, []Vector2{MakeVector2(0, 0)}, positions(LEFT_TO_RIGHT, DISTRIBUTE_SPACE_BETWEEN, 0, 1))
+	// The child gap is kept and the rest of the space is spread on top of it
+	assert.Equal(t, []Vector2{MakeVector2(70, 0), MakeVector2(250, 0), MakeVector2(430, 0)}, positions(LEFT_TO_RIGHT, DISTRIBUTE_SPACE_EVENLY, 10, 3))
+	assert.Equal(t, []Vector2{MakeVector2(0, 50), MakeVector2(0, 250)}, positions(TOP_TO_BOTTOM, DISTRIBUTE_SPACE_AROUND, 0, 2))
+}
+
+func TestChildDistribution_WrapLinesAndBorders(t *testing.T) {
+	ctx := Initialize(MakeBoundingBox(MakeVector2(0, 0), MakeDimensions(800, 600)), ErrorHandler{})
+
+	ctx.BeginLayout()
+	ctx.CLAY(ElementDeclaration{Layout: LayoutConfig{LayoutDirection: TOP_TO_BOTTOM}}, func() {
+		ctx.CLAY(ElementDeclaration{Layout: LayoutConfig{
+			Sizing:            Sizing{Width: FIXED(250)},
+			Wrap:              LAYOUT_WRAP,
+			ChildDistribution: DISTRIBUTE_SPACE_BETWEEN,
+		}}, func() {
+			for i := range 3 {
+				ctx.CLAY_ID(ctx.IDI("item", uint32(i)), ElementDeclaration{Layout: LayoutConfig{Sizing: Sizing{Width: FIXED(100), Height: FIXED(20)}}})
+			}
+		})
+		ctx.CLAY_ID(ID("toolbar"), ElementDeclaration{
+			Layout: LayoutConfig{Sizing: Sizing{Width: FIXED(400)}, ChildDistribution: DISTRIBUTE_SPACE_BETWEEN},
+			Border: BorderElementConfig{Color: Color{A: 255}, Width: BorderWidth{BetweenChildren: 2}},
+		}, func() {
+			ctx.CLAY(ElementDeclaration{Layout: LayoutConfig{Sizing: Sizing{Width: FIXED(100), Height: FIXED(20)}}})
+			ctx.CLAY(ElementDeclaration{Layout: LayoutConfig{Sizing: Sizing{Width: FIXED(100), Height: FIXED(20)}}})
+		})
+	})
+	commands := ctx.EndLayout()
+
+	// Every line is distributed on its own, a child alone on its line stays at the start
+	assert.Equal(t, float32(0), ctx.GetElementData(ctx.IDI("item", 0)).BoundingBox.X())
+	assert.Equal(t, float32(150), ctx.GetElementData(ctx.IDI("item", 1)).BoundingBox.X())
+	assert.Equal(t, MakeVector2(0, 20), ctx.GetElementData(ctx.IDI("item", 2)).BoundingBox.Position)
+
+	// The border between children is drawn in the middle of the widened gap
+	toolbar := ctx.GetElementData(ID("toolbar")).BoundingBox
+	var borderX []float32
+	for _, cmd := range commands {
+		if cmd.BoundingBox.Width() == 2 && cmd.BoundingBox.Y() == toolbar.Y() {
+			borderX = append(borderX, cmd.BoundingBox.X())
+		}
+	}
+	assert.Equal(t, []float32{200}, borderX)
+}
+
+func TestMargin_FitAndPosition(t *testing.T) {
+	ctx := Initialize(MakeBoundingBox(MakeVector2(0, 0), MakeDimensions(800, 600)), ErrorHandler{})
+
+	ctx.BeginLayout()
+	ctx.CLAY(ElementDeclaration{Layout: LayoutConfig{LayoutDirection: TOP_TO_BOTTOM}}, func() {
+		ctx.CLAY_ID(ID("row"), ElementDeclaration{Layout: LayoutConfig{ChildGap: 10}}, func() {
+			ctx.CLAY_ID(ID("a"), ElementDeclaration{Layout: LayoutConfig{Sizing: Sizing{Width: FIXED(50), Height: FIXED(20)}, Margin: Margin{Right: 15}}})
+			ctx.CLAY_ID(ID("b"), ElementDeclaration{Layout: LayoutConfig{Sizing: Sizing{Width: FIXED(50), Height: FIXED(20)}, Margin: Margin{Left: 5, Top: 8, Bottom: 2}}})
+		})
+		ctx.CLAY_ID(ID("rtl"), ElementDeclaration{Layout: LayoutConfig{
+			Sizing:          Sizing{Width: FIXED(300)},
+			LayoutDirection: RIGHT_TO_LEFT,
+			ChildAlignment:  ChildAlignment{X: ALIGN_X_RIGHT},
+		}}, func() {
+			ctx.CLAY_ID(ID("c"), ElementDeclaration{Layout: LayoutConfig{Sizing: Sizing{Width: FIXED(50), Height: FIXED(20)}, Margin: Margin{Right: 10, Top: 10}}})
+			ctx.CLAY_ID(ID("d"), ElementDeclaration{Layout: LayoutConfig{Sizing: Sizing{Width: FIXED(50), Height: FIXED(20)}}})
+		})
+		ctx.CLAY_ID(ID("stack"), ElementDeclaration{Layout: LayoutConfig{
+			Sizing:          Sizing{Width: FIXED(100), Height: FIXED(100)},
+			LayoutDirection: STACK,
+			ChildAlignment:  ChildAlignment{X: ALIGN_X_RIGHT, Y: ALIGN_Y_BOTTOM},
+		}}, func() {
+			ctx.CLAY_ID(ID("badge"), ElementDeclaration{Layout: LayoutConfig{Sizing: Sizing{Width: FIXED(10), Height: FIXED(10)}, Margin: MARGIN(4)}})
+		})
+	})
+	ctx.EndLayout()
+
+	// Margins add to the child gap and take up space in the FIT size of the parent
+	assert.Equal(t, MakeDimensions(130, 30), ctx.GetElementData(ID("row")).BoundingBox.Size)
+	assert.Equal(t, MakeVector2(0, 0), ctx.GetElementData(ID("a")).BoundingBox.Position)
+	assert.Equal(t, MakeVector2(80, 8), ctx.GetElementData(ID("b")).BoundingBox.Position)
+
+	rtl := ctx.GetElementData(ID("rtl")).BoundingBox
+	assert.Equal(t, float32(30), rtl.Height())
+	assert.Equal(t, MakeVector2(240, rtl.Y()+10), ctx.GetElementData(ID("c")).BoundingBox.Position)
+	assert.Equal(t, float32(190), ctx.GetElementData(ID("d")).BoundingBox.X())
+
+	stack := ctx.GetElementData(ID("stack")).BoundingBox
+	assert.Equal(t, stack.Position.Add(MakeVector2(86, 86)), ctx.GetElementData(ID("badge")).BoundingBox.Position)
+}
+
+func TestMargin_GrowAndPercent(t *testing.T) {
+	ctx := Initialize(MakeBoundingBox(MakeVector2(0, 0), MakeDimensions(800, 600)), ErrorHandler{})
+
+	ctx.BeginLayout()
+	ctx.CLAY(ElementDeclaration{Layout: LayoutConfig{LayoutDirection: TOP_TO_BOTTOM}}, func() {
+		ctx.CLAY(ElementDeclaration{Layout: LayoutConfig{Sizing: Sizing{Width: FIXED(300)}}}, func() {
+			ctx.CLAY_ID(ID("grow"), ElementDeclaration{Layout: LayoutConfig{Sizing: Sizing{Width: GROW[float32](), Height: FIXED(20)}}})
+			ctx.CLAY_ID(ID("fixed"), ElementDeclaration{Layout: LayoutConfig{Sizing: Sizing{Width: FIXED(100), Height: FIXED(20)}, Margin: MARGIN(25, 0)}})
+		})
+		ctx.CLAY_ID(ID("column"), ElementDeclaration{Layout: LayoutConfig{Sizing: Sizing{Width: FIXED(200)}, LayoutDirection: TOP_TO_BOTTOM}}, func() {
+			ctx.CLAY_ID(ID("fill"), ElementDeclaration{Layout: LayoutConfig{Sizing: Sizing{Width: GROW[float32](), Height: FIXED(20)}, Margin: Margin{Left: 10, Right: 20}}})
+		})
+	})
+	ctx.EndLayout()
+
+	// GROW children share what is left after the margins of their siblings, and fill their parent up to their own margin
+	assert.Equal(t, float32(150), ctx.GetElementData(ID("grow")).BoundingBox.Width())
+	assert.Equal(t, float32(175), ctx.GetElementData(ID("fixed")).BoundingBox.X())
+	column := ctx.GetElementData(ID("column")).BoundingBox
+	assert.Equal(t, MakeBoundingBox(MakeVector2(10, column.Y()), MakeDimensions(170, 20)), ctx.GetElementData(ID("fill")).BoundingBox)
+}
+
+func TestSizingContent_MinAndMax(t *testing.T) {
+	ctx := Initialize(MakeBoundingBox(MakeVector2(0, 0), MakeDimensions(800, 600)), ErrorHandler{})
+	ctx.SetMeasureTextFunction(mockMeasureText, nil)
+	textConfig := ctx.TEXT_CONFIG(TextElementConfig{})
+
+	ctx.BeginLayout()
+	ctx.CLAY(ElementDeclaration{Layout: LayoutConfig{Sizing: Sizing{Width: FIXED(100)}, LayoutDirection: TOP_TO_BOTTOM}}, func() {
+		ctx.CLAY_ID(ID("min"), ElementDeclaration{Layout: LayoutConfig{Sizing: Sizing{Width: MIN_CONTENT[float32]()}, Padding: PADDING(5)}}, func() {
+			ctx.CLAY_TEXT("hello wonderful world", textConfig)
+		})
+		// Doesn't collapse when its parent is too small
+		ctx.CLAY_ID(ID("max"), ElementDeclaration{Layout: LayoutConfig{Sizing: Sizing{Width: MAX_CONTENT[float32]()}}}, func() {
+			ctx.CLAY_TEXT("hello world", textConfig)
+			ctx.CLAY_ID(ID("grow"), ElementDeclaration{Layout: LayoutConfig{Sizing: Sizing{Width: GROW[float32](), Height: FIXED(10)}}})
+		})
+		ctx.CLAY_ID(ID("fit"), ElementDeclaration{}, func() {
+			ctx.CLAY_TEXT("hello world", textConfig)
+		})
+		ctx.CLAY_ID(ID("clamped"), ElementDeclaration{Layout: LayoutConfig{Sizing: Sizing{Width: MAX_CONTENT(0, 60)}}}, func() {
+			ctx.CLAY_TEXT("hello world", textConfig)
+		})
+	})
+	ctx.EndLayout()
+
+	// The longest word sets the width, the text wraps at every word
+	assert.Equal(t, MakeDimensions(100, 70), ctx.GetElementData(ID("min")).BoundingBox.Size)
+	assert.Equal(t, MakeDimensions(110, 20), ctx.GetElementData(ID("max")).BoundingBox.Size)
+	assert.Equal(t, float32(0), ctx.GetElementData(ID("grow")).BoundingBox.Width())
+	assert.Equal(t, MakeDimensions(100, 40), ctx.GetElementData(ID("fit")).BoundingBox.Size)
+	assert.Equal(t, MakeDimensions(60, 40), ctx.GetElementData(ID("clamped")).BoundingBox.Size)
+}
+
+func TestSizingPercent_ViewportAncestorAndClamps(t *testing.T) {
+	ctx := Initialize(MakeBoundingBox(MakeVector2(0, 0), MakeDimensions(1200, 800)), ErrorHandler{})
+
+	modal := Sizing{Width: VW(0.8, 0, 900), Height: VH(0.5)}
+	declare := func() {
+		ctx.BeginLayout()
+		ctx.CLAY(ElementDeclaration{Layout: LayoutConfig{LayoutDirection: TOP_TO_BOTTOM}}, func() {
+			// FIT parents wrap around percentages of the viewport
+			ctx.CLAY_ID(ID("frame"), ElementDeclaration{Layout: LayoutConfig{Padding: PADDING(5)}}, func() {
+				ctx.CLAY_ID(ID("modal"), ElementDeclaration{Layout: LayoutConfig{Sizing: modal}})
+			})
+			ctx.CLAY_ID(ID("outer"), ElementDeclaration{Layout: LayoutConfig{Sizing: Sizing{Width: FIXED(600)}, Padding: PADDING(10)}}, func() {
+				ctx.CLAY(ElementDeclaration{Layout: LayoutConfig{Sizing: Sizing{Width: FIXED(200)}, LayoutDirection: TOP_TO_BOTTOM}}, func() {
+					ctx.CLAY_ID(ID("ofOuter"), ElementDeclaration{Layout: LayoutConfig{Sizing: Sizing{Width: PERCENT_OF(ID("outer"), 0.5)}}})
+					ctx.CLAY_ID(ID("clamped"), ElementDeclaration{Layout: LayoutConfig{Sizing: Sizing{Width: PERCENT(0.5, 150)}}})
+					// A struct literal without a maximum isn't limited
+					ctx.CLAY_ID(ID("literal"), ElementDeclaration{Layout: LayoutConfig{Sizing: Sizing{Width: SizingAxis{Type: SIZING_TYPE_PERCENT, Percent: 0.5}}}})
+				})
+			})
+			// And around the minimum of percentages of the parent
+			ctx.CLAY_ID(ID("minFrame"), ElementDeclaration{Layout: LayoutConfig{Padding: PADDING(5)}}, func() {
+				ctx.CLAY_ID(ID("minimum"), ElementDeclaration{Layout: LayoutConfig{Sizing: Sizing{Width: PERCENT(0.5, 120), Height: FIXED(10)}}})
+			})
+		})
+		ctx.EndLayout()
+	}
+
+	declare()
+	// 80% of the window but at most 900px
+	assert.Equal(t, MakeDimensions(900, 400), ctx.GetElementData(ID("modal")).BoundingBox.Size)
+	assert.Equal(t, MakeDimensions(910, 410), ctx.GetElementData(ID("frame")).BoundingBox.Size)
+	assert.Equal(t, float32(290), ctx.GetElementData(ID("ofOuter")).BoundingBox.Width())
+	assert.Equal(t, float32(150), ctx.GetElementData(ID("clamped")).BoundingBox.Width())
+	assert.Equal(t, float32(100), ctx.GetElementData(ID("literal")).BoundingBox.Width())
+	assert.Equal(t, float32(130), ctx.GetElementData(ID("minFrame")).BoundingBox.Width())
+	assert.Equal(t, float32(120), ctx.GetElementData(ID("minimum")).BoundingBox.Width())
+
+	ctx.SetLayoutDimensions(MakeBoundingBox(MakeVector2(0, 0), MakeDimensions(1000, 600)))
+	declare()
+	assert.Equal(t, MakeDimensions(800, 300), ctx.GetElementData(ID("modal")).BoundingBox.Size)
+}
+
+func TestSizingCalc_Expressions(t *testing.T) {
+	ctx := Initialize(MakeBoundingBox(MakeVector2(0, 0), MakeDimensions(1200, 800)), ErrorHandler{})
+
+	mainWidth := Sizing{Width: CALC(PERCENT(1), FIXED(-48))}
+	headline := Sizing{Width: CALC_CLAMP(FIXED(200), VW(0.5), FIXED(500)), Height: FIXED(10)}
+	capped := Sizing{Width: CALC_MIN(PERCENT(0.5), FIXED(100))}
+	declare := func() {
+		ctx.BeginLayout()
+		ctx.CLAY(ElementDeclaration{Layout: LayoutConfig{LayoutDirection: TOP_TO_BOTTOM}}, func() {
+			ctx.CLAY(ElementDeclaration{Layout: LayoutConfig{Sizing: Sizing{Width: FIXED(800)}, Padding: PADDING(10)}}, func() {
+				ctx.CLAY(ElementDeclaration{Layout: LayoutConfig{Sizing: Sizing{Width: FIXED(48)}}})
+				ctx.CLAY_ID(ID("main"), ElementDeclaration{Layout: LayoutConfig{Sizing: mainWidth}})
+			})
+			// Expressions that don't depend on the parent are known up front, so FIT parents wrap around them
+			ctx.CLAY_ID(ID("title"), ElementDeclaration{}, func() {
+				ctx.CLAY_ID(ID("headline"), ElementDeclaration{Layout: LayoutConfig{Sizing: headline}})
+			})
+			ctx.CLAY(ElementDeclaration{Layout: LayoutConfig{Sizing: Sizing{Width: FIXED(300)}}}, func() {
+				ctx.CLAY_ID(ID("capped"), ElementDeclaration{Layout: LayoutConfig{Sizing: capped}})
+			})
+		})
+		ctx.EndLayout()
+	}
+
+	declare()
+	main := ctx.GetElementData(ID("main")).BoundingBox
+	assert.Equal(t, float32(780-48), main.Width())
+	assert.Equal(t, float32(10+48), main.X())
+	assert.Equal(t, float32(500), ctx.GetElementData(ID("title")).BoundingBox.Width())
+	assert.Equal(t, float32(100), ctx.GetElementData(ID("capped")).BoundingBox.Width())
+
+	for viewportWidth, headlineWidth := range map[float32]float32{600: 300, 300: 200} {
+		ctx.SetLayoutDimensions(MakeBoundingBox(MakeVector2(0, 0), MakeDimensions(viewportWidth, 800)))
+		declare()
+		assert.Equal(t, headlineWidth, ctx.GetElementData(ID("headline")).BoundingBox.Width())
+	}
+}
+
+func TestAspectRatio_GrowWidth(t *testing.T) {
+	ctx := Initialize(MakeBoundingBox(MakeVector2(0, 0), MakeDimensions(800, 600)), ErrorHandler{})
+
+	tile := ElementDeclaration{Layout: LayoutConfig{Sizing: Sizing{Width: GROW[float32]()}}, AspectRatio: AspectRatioElementConfig{AspectRatio: 2}}
+	ctx.BeginLayout()
+	ctx.CLAY(ElementDeclaration{Layout: LayoutConfig{LayoutDirection: TOP_TO_BOTTOM}}, func() {
+		ctx.CLAY_ID(ID("row"), ElementDeclaration{Layout: LayoutConfig{Sizing: Sizing{Width: FIXED(800)}}}, func() {
+			ctx.CLAY_ID(ID("video"), tile, func() {
+				ctx.CLAY(ElementDeclaration{Layout: LayoutConfig{Sizing: Sizing{Width: FIXED(10), Height: FIXED(10)}}})
+			})
+			// Content taller than the ratio allows doesn't stretch the tile
+			ctx.CLAY_ID(ID("card"), tile, func() {
+				ctx.CLAY(ElementDeclaration{Layout: LayoutConfig{Sizing: Sizing{Width: FIXED(10), Height: FIXED(500)}}})
+			})
+		})
+		ctx.CLAY_ID(ID("column"), ElementDeclaration{Layout: LayoutConfig{Sizing: Sizing{Width: FIXED(300)}, LayoutDirection: TOP_TO_BOTTOM}}, func() {
+			ctx.CLAY_ID(ID("cover"), tile)
+			ctx.CLAY_ID(ID("caption"), ElementDeclaration{Layout: LayoutConfig{Sizing: Sizing{Width: GROW[float32](), Height: FIXED(20)}}})
+		})
+	})
+	ctx.EndLayout()
+
+	assert.Equal(t, MakeDimensions(400, 200), ctx.GetElementData(ID("video")).BoundingBox.Size)
+	assert.Equal(t, MakeDimensions(400, 200), ctx.GetElementData(ID("card")).BoundingBox.Size)
+	assert.Equal(t, MakeDimensions(800, 200), ctx.GetElementData(ID("row")).BoundingBox.Size)
+	assert.Equal(t, MakeDimensions(300, 150), ctx.GetElementData(ID("cover")).BoundingBox.Size)
+	assert.Equal(t, float32(350), ctx.GetElementData(ID("caption")).BoundingBox.Y())
+	assert.Equal(t, float32(170), ctx.GetElementData(ID("column")).BoundingBox.Height())
+}
+
+func TestAspectRatio_MinMax(t *testing.T) {
+	ctx := Initialize(MakeBoundingBox(MakeVector2(0, 0), MakeDimensions(800, 600)), ErrorHandler{})
+
+	aspect := func(ratio float32, sizing Sizing) ElementDeclaration {
+		return ElementDeclaration{Layout: LayoutConfig{Sizing: sizing}, AspectRatio: AspectRatioElementConfig{AspectRatio: ratio}}
+	}
+	ctx.BeginLayout()
+	ctx.CLAY(ElementDeclaration{Layout: LayoutConfig{LayoutDirection: TOP_TO_BOTTOM}}, func() {
+		ctx.CLAY(ElementDeclaration{Layout: LayoutConfig{Sizing: Sizing{Width: FIXED(800)}}}, func() {
+			// The max height caps the width, the remaining space goes to the sibling
+			ctx.CLAY_ID(ID("capped"), aspect(2, Sizing{Width: GROW[float32](), Height: FIT(0, 100)}))
+			ctx.CLAY_ID(ID("rest"), ElementDeclaration{Layout: LayoutConfig{Sizing: Sizing{Width: GROW[float32](), Height: FIXED(10)}}})
+		})
+		ctx.CLAY(ElementDeclaration{Layout: LayoutConfig{Sizing: Sizing{Width: FIXED(100)}, LayoutDirection: TOP_TO_BOTTOM}}, func() {
+			// The min height keeps the width from getting smaller than the parent
+			ctx.CLAY_ID(ID("atLeast"), aspect(2, Sizing{Width: GROW[float32](), Height: FIT(80)}))
+		})
+		ctx.CLAY(ElementDeclaration{Layout: LayoutConfig{Sizing: Sizing{Width: FIXED(800)}}}, func() {
+			ctx.CLAY_ID(ID("fixedHeight"), aspect(2, Sizing{Width: GROW[float32](), Height: FIXED(50)}))
+			ctx.CLAY_ID(ID("fitWidth"), aspect(3, Sizing{Height: FIXED(30)}))
+		})
+	})
+	ctx.EndLayout()
+
+	assert.Equal(t, MakeDimensions(200, 100), ctx.GetElementData(ID("capped")).BoundingBox.Size)
+	assert.Equal(t, float32(600), ctx.GetElementData(ID("rest")).BoundingBox.Width())
+	assert.Equal(t, MakeDimensions(160, 80), ctx.GetElementData(ID("atLeast")).BoundingBox.Size)
+	assert.Equal(t, MakeDimensions(100, 50), ctx.GetElementData(ID("fixedHeight")).BoundingBox.Size)
+	assert.Equal(t, MakeDimensions(90, 30), ctx.GetElementData(ID("fitWidth")).BoundingBox.Size)
+}
+
+func TestAspectRatio_ContainerChildren(t *testing.T) {
+	ctx := Initialize(MakeBoundingBox(MakeVector2(0, 0), MakeDimensions(800, 600)), ErrorHandler{})
+
+	fill := Sizing{Width: GROW[float32](), Height: GROW[float32]()}
+	ctx.BeginLayout()
+	ctx.CLAY(ElementDeclaration{Layout: LayoutConfig{LayoutDirection: TOP_TO_BOTTOM}}, func() {
+		ctx.CLAY_ID(ID("row"), ElementDeclaration{Layout: LayoutConfig{Sizing: Sizing{Width: FIXED(600)}}}, func() {
+			ctx.CLAY_ID(ID("side"), ElementDeclaration{Layout: LayoutConfig{Sizing: fill}})
+			ctx.CLAY_ID(ID("tile"), ElementDeclaration{Layout: LayoutConfig{Sizing: Sizing{Width: GROW[float32]()}, Padding: PADDING(10)}, AspectRatio: AspectRatioElementConfig{AspectRatio: 2}}, func() {
+				ctx.CLAY_ID(ID("fill"), ElementDeclaration{Layout: LayoutConfig{Sizing: fill}})
+			})
+		})
+		ctx.CLAY(ElementDeclaration{Layout: LayoutConfig{Sizing: Sizing{Width: FIXED(400), Height: FIXED(100)}}}, func() {
+			ctx.CLAY_ID(ID("percent"), ElementDeclaration{Layout: LayoutConfig{Sizing: Sizing{Height: PERCENT(0.5)}}, AspectRatio: AspectRatioElementConfig{AspectRatio: 2}}, func() {
+				ctx.CLAY_ID(ID("percentFill"), ElementDeclaration{Layout: LayoutConfig{Sizing: fill}})
+			})
+			ctx.CLAY_ID(ID("rest"), ElementDeclaration{Layout: LayoutConfig{Sizing: fill}})
+		})
+	})
+	ctx.EndLayout()
+
+	// The height follows the width while widths are sized, the FIT parent, the GROW sibling and the children of the tile follow it
+	assert.Equal(t, MakeDimensions(300, 150), ctx.GetElementData(ID("tile")).BoundingBox.Size)
+	assert.Equal(t, float32(150), ctx.GetElementData(ID("row")).BoundingBox.Height())
+	assert.Equal(t, MakeDimensions(300, 150), ctx.GetElementData(ID("side")).BoundingBox.Size)
+	assert.Equal(t, MakeDimensions(280, 130), ctx.GetElementData(ID("fill")).BoundingBox.Size)
+	// The width follows a percentage of a FIXED parent height, its children and the GROW sibling are sized around it
+	assert.Equal(t, MakeDimensions(100, 50), ctx.GetElementData(ID("percent")).BoundingBox.Size)
+	assert.Equal(t, MakeDimensions(100, 50), ctx.GetElementData(ID("percentFill")).BoundingBox.Size)
+	assert.Equal(t, float32(300), ctx.GetElementData(ID("rest")).BoundingBox.Width())
+}
+
+// Shapes every byte as one 10px glyph, "fi" is shaped as a single ligature glyph.
+type mockShaper struct{}
+
+func (mockShaper) Shape(text string, config *TextElementConfig, dst []ShapedGlyph) ShapedRun {
+	var width float32
+	for i := 0; i < len(text); i++ {
+		if strings.HasPrefix(text[i:], "fi") {
+			dst = append(dst, ShapedGlyph{GlyphId: 0xFB01, Advance: 15, Cluster: int32(i)})
+			width += 15
+			i++
+			continue
+		}
+		dst = append(dst, ShapedGlyph{GlyphId: uint32(text[i]), Advance: 10, Cluster: int32(i)})
+		width += 10
+	}
+	return ShapedRun{Glyphs: dst, Metrics: TextMetrics{Dimensions: MakeDimensions(width, 20), Ascent: 16, Descent: 4}}
+}
+
+func TestTextShaper_GlyphRunsAndCarets(t *testing.T) {
+	ctx := Initialize(MakeBoundingBox(MakeVector2(0, 0), MakeDimensions(800, 600)), ErrorHandler{})
+	ctx.SetTextShaper(mockShaper{})
+
+	ctx.BeginLayout()
+	ctx.CLAY_ID(ID("label"), ElementDeclaration{
+		Layout: LayoutConfig{Sizing: Sizing{Width: FIXED(60)}, Padding: Padding{Left: 5}},
+	}, func() {
+		ctx.CLAY_TEXT("find it", ctx.TEXT_CONFIG(TextElementConfig{FontSize: 16}))
+	})
+	commands := ctx.EndLayout()
+
+	var lines []string
+	var glyphCounts []int
+	for _, cmd := range commands {
+		if text, ok := cmd.RenderData.(*TextRenderData); ok {
+			lines = append(lines, text.StringContents)
+			glyphCounts = append(glyphCounts, len(text.Glyphs))
+			assert.Equal(t, float32(16), text.BaselineOffset)
+		}
+	}
+	// "find" is 35px wide thanks to the ligature and wraps away from "it"
+	assert.Equal(t, []string{"find", "it"}, lines)
+	assert.Equal(t, []int{3, 2}, glyphCounts)
+
+	caret, ok := ctx.GetTextCaretPosition(ID("label"), 2)
+	assert.True(t, ok)
+	assert.Equal(t, MakeVector2(20, 0), caret)
+	caret, _ = ctx.GetTextCaretPosition(ID("label"), 6)
+	assert.Equal(t, MakeVector2(15, 20), caret)
+
+	index, ok := ctx.GetTextIndexAtPosition(ID("label"), MakeVector2(32, 5))
+	assert.True(t, ok)
+	assert.Equal(t, 3, index)
+	index, _ = ctx.GetTextIndexAtPosition(ID("label"), MakeVector2(100, 25))
+	assert.Equal(t, 7, index)
+}
+
+func TestMeasureTextCache_HitsAcrossFrames(t *testing.T) {
+	ctx := Initialize(MakeBoundingBox(MakeVector2(0, 0), MakeDimensions(800, 600)), ErrorHandler{})
+	measureCalls := 0
+	ctx.SetMeasureTextFunction(func(text string, config *TextElementConfig, userData any) Dimensions {
+		measureCalls++
+		return mockMeasureText(text, config, userData)
+	}, nil)
+
+	layout := func() {
+		ctx.BeginLayout()
+		ctx.CLAY_TEXT("Hello cached world", ctx.TEXT_CONFIG(TextElementConfig{FontSize: 16}))
+		ctx.CLAY_TEXT("Hello cached world", ctx.TEXT_CONFIG(TextElementConfig{FontSize: 24}))
+		ctx.EndLayout()
+	}
+
+	layout()
+	callsAfterFirstFrame := measureCalls
+	stats := ctx.Stats()
+	assert.Equal(t, uint64(2), stats.MeasureTextCacheMisses)
+	assert.Equal(t, 2, stats.MeasureTextCacheEntries)
+	assert.Equal(t, 6, stats.MeasureTextCacheWords)
+
+	// Configs are stored at new addresses every frame, the cache is keyed by their values
+	layout()
+	layout()
+	stats = ctx.Stats()
+	assert.Equal(t, uint64(2), stats.MeasureTextCacheMisses)
+	assert.Less(t, uint64(4), stats.MeasureTextCacheHits)
+	assert.Equal(t, uint64(0), stats.MeasureTextCacheEvictions)
+	assert.Equal(t, callsAfterFirstFrame, measureCalls)
+}
+
+func TestMeasureTextCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	ctx := Initialize(MakeBoundingBox(MakeVector2(0, 0), MakeDimensions(800, 600)), ErrorHandler{})
+	ctx.SetMaxMeasureTextCacheWordCount(6)
+	ctx.SetMeasureTextFunction(mockMeasureText, nil)
+	config := &TextElementConfig{FontSize: 16}
+
+	ctx.measureTextCached("one two", config)
+	ctx.measureTextCached("three four", config)
+	// Touch the first text so that the second one is the least recently used
+	ctx.measureTextCached("one two", config)
+	ctx.measureTextCached("five six", config)
+
+	stats := ctx.Stats()
+	assert.Equal(t, uint64(1), stats.MeasureTextCacheEvictions)
+	assert.Equal(t, 2, stats.MeasureTextCacheEntries)
+	assert.Equal(t, 4, stats.MeasureTextCacheWords)
+
+	ctx.measureTextCached("one two", config)
+	assert.Equal(t, uint64(2), ctx.Stats().MeasureTextCacheHits)
+	ctx.measureTextCached("three four", config)
+	assert.Equal(t, uint64(4), ctx.Stats().MeasureTextCacheMisses)
+
+	// Words of the evicted texts are reused, the text still measures correctly
+	measured := ctx.measureTextCached("five six", config)
+	assert.Equal(t, MakeDimensions(80, 20), measured.unwrappedDimensions)
+}
+
+func TestMeasureTextCache_HashCollision(t *testing.T) {
+	ctx := Initialize(MakeBoundingBox(MakeVector2(0, 0), MakeDimensions(800, 600)), ErrorHandler{})
+	ctx.SetMeasureTextFunction(mockMeasureText, nil)
+	config := &TextElementConfig{FontSize: 16}
+
+	ctx.measureTextCached("short", config)
+	// Make the hash of another text point at the cached item, as a collision would
+	ctx.measureTextHashMap[hashTextWithConfig("a longer text", config)] = ctx.measureTextHashMap[hashTextWithConfig("short", config)]
+
+	measured := ctx.measureTextCached("a longer text", config)
+	assert.Equal(t, MakeDimensions(130, 20), measured.unwrappedDimensions)
+	assert.Equal(t, uint64(0), ctx.Stats().MeasureTextCacheHits)
+
+	// The same text with a different config is not a hit either
+	bigger := &TextElementConfig{FontSize: 24}
+	ctx.measureTextHashMap[hashTextWithConfig("a longer text", bigger)] = ctx.measureTextHashMap[hashTextWithConfig("a longer text", config)]
+	ctx.measureTextCached("a longer text", bigger)
+	assert.Equal(t, uint64(0), ctx.Stats().MeasureTextCacheHits)
+	assert.Equal(t, uint64(3), ctx.Stats().MeasureTextCacheMisses)
+}
+
+func TestMeasureText_PerContextCallbacks(t *testing.T) {
+	measureWithScale := func(text string, config *TextElementConfig, userData any) Dimensions {
+		scale := userData.(float32)
+		return MakeDimensions(float32(len(text))*10*scale, 20*scale)
+	}
+	window := Initialize(MakeBoundingBox(MakeVector2(0, 0), MakeDimensions(800, 600)), ErrorHandler{})
+	window.SetMeasureTextFunction(measureWithScale, float32(1))
+	thumbnail := Initialize(MakeBoundingBox(MakeVector2(0, 0), MakeDimensions(200, 150)), ErrorHandler{})
+	thumbnail.SetMeasureTextFunction(measureWithScale, float32(0.25))
+
+	layout := func(ctx *Context) Dimensions {
+		ctx.BeginLayout()
+		ctx.CLAY_ID(ID("label"), ElementDeclaration{}, func() {
+			ctx.CLAY_TEXT("Title", ctx.TEXT_CONFIG(TextElementConfig{FontSize: 16}))
+		})
+		ctx.EndLayout()
+		return ctx.GetElementData(ID("label")).BoundingBox.Size
+	}
+
+	assert.Equal(t, MakeDimensions(50, 20), layout(window))
+	assert.Equal(t, MakeDimensions(12.5, 5), layout(thumbnail))
+	assert.Equal(t, MakeDimensions(50, 20), layout(window))
+}
+
+// Reference values computed with Clay__HashData from upstream clay.h, using SSE2.
+func TestHashData_MatchesUpstream(t *testing.T) {
+	assert.Equal(t, uint64(0x0000000000000000), hashData(""))
+	assert.Equal(t, uint64(0x000000000ec20080), hashData("a"))
+	assert.Equal(t, uint64(0x744e095e76468c91), hashData("Clay__RootContainer"))
+	assert.Equal(t, uint64(0xbd962455b6df92ce), hashData("0123456789abcdef"))
+	assert.Equal(t, uint64(0xea35218df4cd00ab), hashData("The quick brown fox jumps over the lazy dog"))
+
+	assert.Equal(t, 0, int(testing.AllocsPerRun(100, func() {
+		ID("sidebar")
+		IDI("item", 3)
+		hashTextWithConfig("Hello", &default_TextElementConfig)
+	})))
+}
+
+func TestConcurrentContexts(t *testing.T) {
+	layout := func(ctx *Context, frame int) []RenderCommand {
+		ctx.BeginLayout()
+		ctx.CLAY_ID(ID("root"), ElementDeclaration{
+			Layout:          LayoutConfig{Sizing: Sizing{Width: GROW(0), Height: GROW(0)}, LayoutDirection: TOP_TO_BOTTOM},
+			BackgroundColor: Color{R: 10, A: 255},
+		}, func() {
+			for i := range 20 {
+				ctx.CLAY_ID(IDI("item", uint32(i)), ElementDeclaration{
+					Layout:          LayoutConfig{Sizing: Sizing{Width: FIXED(float32(10 * (i + frame%3)))}},
+					BackgroundColor: Color{G: 10, A: 255},
+				}, func() {
+					ctx.CLAY_TEXT("item text that wraps", ctx.TEXT_CONFIG(TextElementConfig{FontSize: uint16(10 + i)}))
+				})
+			}
+		})
+		return ctx.EndLayout()
+	}
+	newContext := func() *Context {
+		ctx := Initialize(MakeBoundingBox(MakeVector2(0, 0), MakeDimensions(800, 600)), ErrorHandler{})
+		ctx.SetMeasureTextFunction(mockMeasureText, nil)
+		return ctx
+	}
+
+	// Render data is only valid until the next BeginLayout, so every reference frame gets its own context
+	var expected [][]RenderCommand
+	for frame := range 3 {
+		expected = append(expected, layout(newContext(), frame))
+	}
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Go(func() {
+			ctx := newContext()
+			for frame := range 30 {
+				commands := layout(ctx, frame)
+				if !assert.Equal(t, expected[frame%3], commands) {
+					return
+				}
+			}
+		})
+	}
+	wg.Wait()
+}
+
+// Declares a layout that covers every kind of render command, with sizings declared inline.
+func declareSteadyFrame(ctx *Context, textConfig *TextElementConfig) []RenderCommand {
+	ctx.BeginLayout()
+	ctx.CLAY_ID(ID("root"), ElementDeclaration{
+		Layout:          LayoutConfig{Sizing: Sizing{Width: GROW(0), Height: GROW(0)}, LayoutDirection: TOP_TO_BOTTOM, Padding: Padding{Left: 4}},
+		BackgroundColor: Color{R: 10, A: 255},
+		CornerRadius:    CornerRadius{TopLeft: 2},
+		Border:          BorderElementConfig{Color: Color{A: 255}, Width: BorderWidth{Left: 1, BetweenChildren: 1}},
+		Clip:            ClipElementConfig{Vertical: true},
+	}, func() {
+		for i := range 20 {
+			ctx.CLAY_ID(ctx.IDI("item", uint32(i)), ElementDeclaration{
+				Layout:          LayoutConfig{Sizing: Sizing{Width: FIXED(100), Height: FIT(20, 60).WithShrink(2)}},
+				BackgroundColor: Color{G: 10, A: 255},
+			}, func() {
+				ctx.CLAY_ID(ctx.ID_LOCAL("label"), ElementDeclaration{}, func() {
+					ctx.CLAY_TEXT("item text that wraps", textConfig)
+				})
+			})
+		}
+		ctx.CLAY(ElementDeclaration{
+			Layout:   LayoutConfig{Sizing: Sizing{Width: PERCENT(0.5, 10), Height: VH(0.1)}},
+			Floating: FloatingElementConfig{AttachTo: ATTACH_TO_PARENT},
+			Image:    ImageElementConfig{ImageData: textConfig},
+		})
+	})
+	commands := ctx.EndLayout()
+	ctx.SetPointerState(MakeVector2(10, 10), false)
+	return commands
+}
+
+func TestFrameLoop_ZeroAllocations(t *testing.T) {
+	if captureDeclarationLocations {
+		t.Skip("capturing declaration locations allocates")
+	}
+	ctx := Initialize(MakeBoundingBox(MakeVector2(0, 0), MakeDimensions(800, 600)), ErrorHandler{})
+	ctx.SetMeasureTextFunction(mockMeasureText, nil)
+	textConfig := &TextElementConfig{FontSize: 16}
+
+	frame := func() { declareSteadyFrame(ctx, textConfig) }
+	frame() // Warm up the render data pools and the measure text cache
+
+	assert.Zero(t, testing.AllocsPerRun(100, frame))
+
+	kinds := map[string]int{}
+	for _, cmd := range declareSteadyFrame(ctx, textConfig) {
+		kinds[fmt.Sprintf("%T", cmd.RenderData)]++
+	}
+	for _, kind := range []string{"*clay.RectangleRenderData", "*clay.TextRenderData", "*clay.BorderRenderData", "*clay.ImageRenderData", "*clay.ScissorsStartData", "*clay.ScissorsEndData"} {
+		assert.NotZero(t, kinds[kind], kind)
+	}
+}
+
+func TestRenderCommand_Accessors(t *testing.T) {
+	ctx := Initialize(MakeBoundingBox(MakeVector2(0, 0), MakeDimensions(800, 600)), ErrorHandler{})
+	ctx.SetMeasureTextFunction(mockMeasureText, nil)
+
+	for _, cmd := range declareSteadyFrame(ctx, &TextElementConfig{FontSize: 16}) {
+		// Exactly one accessor returns the data of a command, the same pointer as RenderData
+		found := 0
+		for _, data := range []AnyRenderData{cmd.Rectangle(), cmd.Text(), cmd.Image(), cmd.Custom(), cmd.Border(), cmd.ScissorsStart(), cmd.ScissorsEnd()} {
+			if !reflect.ValueOf(data).IsNil() {
+				found++
+				assert.Equal(t, cmd.RenderData, data)
+			}
+		}
+		assert.Equal(t, 1, found, "%T", cmd.RenderData)
+	}
+}
+
+func BenchmarkFrameLoop(b *testing.B) {
+	ctx := Initialize(MakeBoundingBox(MakeVector2(0, 0), MakeDimensions(800, 600)), ErrorHandler{})
+	ctx.SetMeasureTextFunction(mockMeasureText, nil)
+	textConfig := &TextElementConfig{FontSize: 16}
+	declareSteadyFrame(ctx, textConfig)
+
+	b.ReportAllocs()
+	for b.Loop() {
+		declareSteadyFrame(ctx, textConfig)
+	}
+}
+
+// Declares 50 clipped rows of 100 elements with backgrounds, borders and text, about 5k elements in total.
+func declareLargeLayout(ctx *Context, textConfig *TextElementConfig) {
+	ctx.CLAY_ID(ID("root"), ElementDeclaration{
+		Layout: LayoutConfig{Sizing: Sizing{Width: GROW(0)}, LayoutDirection: TOP_TO_BOTTOM},
+	}, func() {
+		for row := range 50 {
+			ctx.CLAY_ID(ctx.IDI("row", uint32(row)), ElementDeclaration{
+				Layout: LayoutConfig{Sizing: Sizing{Width: GROW(0)}, ChildGap: 1},
+				Clip:   ClipElementConfig{Horizontal: true},
+			}, func() {
+				for column := range 98 {
+					ctx.CLAY(ElementDeclaration{
+						Layout:          LayoutConfig{Sizing: Sizing{Width: FIXED(20), Height: FIXED(20)}},
+						BackgroundColor: Color{G: uint8(column), A: 255},
+						CornerRadius:    CornerRadius{TopLeft: 2},
+						Border:          BorderElementConfig{Color: Color{A: uint8(column % 2)}, Width: BorderWidth{Left: 1}},
+					}, func() {
+						if column%10 == 0 {
+							ctx.CLAY_TEXT("cell", textConfig)
+						}
+					})
+				}
+			})
+		}
+	})
+}
+
+func BenchmarkLayout_5000Elements(b *testing.B) {
+	ctx := Initialize(MakeBoundingBox(MakeVector2(0, 0), MakeDimensions(1920, 1080)), ErrorHandler{})
+	ctx.SetMeasureTextFunction(mockMeasureText, nil)
+	textConfig := &TextElementConfig{FontSize: 12}
+	frame := func() {
+		ctx.BeginLayout()
+		declareLargeLayout(ctx, textConfig)
+		ctx.EndLayout()
+	}
+	frame()
+
+	b.ReportAllocs()
+	for b.Loop() {
+		frame()
+	}
+}
+
+func BenchmarkFindElementConfig_5000Elements(b *testing.B) {
+	ctx := Initialize(MakeBoundingBox(MakeVector2(0, 0), MakeDimensions(1920, 1080)), ErrorHandler{})
+	ctx.SetMeasureTextFunction(mockMeasureText, nil)
+	ctx.BeginLayout()
+	declareLargeLayout(ctx, &TextElementConfig{FontSize: 12})
+
+	b.ReportAllocs()
+	found := 0
+	for b.Loop() {
+		for i := range ctx.layoutElements {
+			element := &ctx.layoutElements[i]
+			if _, ok := ctx.sharedConfig(element); ok {
+				found++
+			}
+			if _, ok := ctx.clipConfig(element); ok {
+				found++
+			}
+			if _, ok := ctx.borderConfig(element); ok {
+				found++
+			}
+			if element.configMask.has(elementConfigText) {
+				found++
+			}
+		}
+	}
+	assert.NotZero(b, found)
+}
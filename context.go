@@ -1,17 +1,22 @@
 package clay
 
-import "maps"
+import (
+	"maps"
+	"runtime"
+	"strconv"
+	"strings"
 
-type measureTextKey struct {
-	config *TextElementConfig
-	text   string
-}
+	"golang.org/x/text/unicode/bidi"
+)
 
 type Context struct {
 	maxElementCount              int32
 	maxMeasureTextCacheWordCount int32
 	warningsEnabled              bool
 	errorHandler                 ErrorHandler
+	layoutErrors                 []ErrorData // Errors reported since BeginLayout, returned by EndLayoutWithError
+	strictValidation             bool
+	declarationLocation          string // Source location of the latest CLAY() call, only captured with the claydebug build tag
 	booleanWarnings              BooleanWarnings
 	warnings                     []Warning
 
@@ -22,11 +27,19 @@ type Context struct {
 	debugModeEnabled              bool
 	disableCulling                bool
 	externalScrollHandlingEnabled bool
-	debugSelectedElementId        uint32
+	debugSelectedElementId        uint64
 	generation                    uint32 // Increased on every layout
+	measureText                   MeasureTextFn
+	measureTextMetrics            MeasureTextMetricsFn
 	measureTextUserData           any
+	queryScrollOffset             QueryScrollOffsetFn
+	textShaper                    TextShaper
 	queryScrollOffsetUserData     any
 	renderTranslucent             bool
+	rightToLeft                   bool
+	stats                         Stats
+	discardedSharedElementConfig  SharedElementConfig // Written instead of the element configs once the element limit is exceeded
+	wrapColumnsWidened            bool                // Set when the columns of a wrapping element broken during height sizing made it wider
 
 	// Layout Elements / Render Commands
 	layoutElements              []LayoutElement
@@ -35,13 +48,11 @@ type Context struct {
 	layoutElementChildren       []int
 	layoutElementChildrenBuffer []int
 	textElementData             []TextElementData
-	aspectRatioElementIndexes   []int
 	reusableElementIndexBuffer  []int32
-	layoutElementClipElementIds []int
+	layoutElementClipElementIds []uint64
 
 	// Configs
 	layoutConfigs             []LayoutConfig
-	elementConfigs            []AnyElementConfig
 	textElementConfigs        []TextElementConfig
 	aspectRatioElementConfigs []AspectRatioElementConfig
 	imageElementConfigs       []ImageElementConfig
@@ -54,17 +65,42 @@ type Context struct {
 	// Misc Data Structures
 	layoutElementIdStrings      []string
 	wrappedTextLines            []WrappedTextLine
+	wrappedTextFragments        []WrappedTextFragment
+	wrapLines                   []wrapLine // Lines of the children of wrapping elements
+	gridLayouts                 []gridLayout
+	gridTrackSizes              []float32
+	gridCells                   []gridCell
+	gridOccupiedCells           []bool // Scratch buffer for placing the children of a grid
+	richTextPieces              []richTextPiece
+	shapedGlyphs                []ShapedGlyph // Glyph runs referenced by text render commands
+	measureGlyphs               []ShapedGlyph // Scratch buffer for shaping during text measurement
 	layoutElementTreeNodeArray1 []LayoutElementTreeNode
 	layoutElementTreeRoots      []LayoutElementTreeRoot
-	layoutElementsHashMap       map[uint32]LayoutElementHashMapItem
-	measureTextHashMap          map[measureTextKey]MeasureTextCacheItem
+	layoutElementsHashMap       map[uint64]LayoutElementHashMapItem
+	measureTextHashMap          map[uint64]int32 // Text and config hash to index in measureTextCacheItems
+	measureTextCacheItems       []MeasureTextCacheItem
+	measureTextCacheFreeList    []int32
+	measureTextCacheHead        int32 // Most recently used cache item
+	measureTextCacheTail        int32 // Least recently used cache item, evicted first
 	measuredWords               []MeasuredWord
 	measuredWordsFreeList       []int32
-	openClipElementStack        []int
+	openClipElementStack        []uint64
 	pointerOverIds              []ElementId
 	scrollContainerDatas        []ScrollContainerDataInternal
 	dynamicStringData           []byte
 	debugElementData            []DebugElementData
+	bidiParagraph               bidi.Paragraph
+	bidiRuns                    []bidiRun
+
+	// Render data pointed to by render commands. Only truncated at the start of a layout so that
+	// the render commands returned by EndLayout stay valid until the next call to BeginLayout.
+	rectangleRenderData []RectangleRenderData
+	textRenderData      []TextRenderData
+	imageRenderData     []ImageRenderData
+	customRenderData    []CustomRenderData
+	borderRenderData    []BorderRenderData
+	scissorsStartData   []ScissorsStartData
+	scissorsEndData     []ScissorsEndData
 
 	treeNodeVisited []bool
 }
@@ -74,20 +110,9 @@ func (c *Context) Finalize() []RenderCommand {
 	c.initializeEphemeralMemory()
 
 	// Do a GC run
-	maps.DeleteFunc(c.layoutElementsHashMap, func(_ uint32, v LayoutElementHashMapItem) bool {
+	maps.DeleteFunc(c.layoutElementsHashMap, func(_ uint64, v LayoutElementHashMapItem) bool {
 		return v.generation-c.generation > 3
 	})
-	maps.DeleteFunc(c.measureTextHashMap, func(_ measureTextKey, v MeasureTextCacheItem) bool {
-		if v.generation-c.generation > 3 {
-			for i := v.measuredWordsStartIndex; i != -1; {
-				c.measuredWordsFreeList = append(c.measuredWordsFreeList, i)
-				i = c.measuredWords[i].next
-			}
-
-			return true
-		}
-		return false
-	})
 
 	return rc
 }
@@ -103,8 +128,6 @@ func (c *Context) initializeEphemeralMemory() {
 
 	clear(c.layoutConfigs)
 	c.layoutConfigs = c.layoutConfigs[:0]
-	clear(c.elementConfigs)
-	c.elementConfigs = c.elementConfigs[:0]
 	clear(c.textElementConfigs)
 	c.textElementConfigs = c.textElementConfigs[:0]
 	clear(c.aspectRatioElementConfigs)
@@ -126,6 +149,14 @@ func (c *Context) initializeEphemeralMemory() {
 	c.layoutElementIdStrings = c.layoutElementIdStrings[:0]
 	clear(c.wrappedTextLines)
 	c.wrappedTextLines = c.wrappedTextLines[:0]
+	clear(c.wrappedTextFragments)
+	c.wrappedTextFragments = c.wrappedTextFragments[:0]
+	c.wrapLines = c.wrapLines[:0]
+	clear(c.gridLayouts)
+	c.gridLayouts = c.gridLayouts[:0]
+	c.gridTrackSizes = c.gridTrackSizes[:0]
+	c.gridCells = c.gridCells[:0]
+	c.shapedGlyphs = c.shapedGlyphs[:0]
 	clear(c.layoutElementTreeNodeArray1)
 	c.layoutElementTreeNodeArray1 = c.layoutElementTreeNodeArray1[:0]
 	clear(c.layoutElementTreeRoots)
@@ -136,8 +167,6 @@ func (c *Context) initializeEphemeralMemory() {
 	c.openLayoutElementStack = c.openLayoutElementStack[:0]
 	clear(c.textElementData)
 	c.textElementData = c.textElementData[:0]
-	clear(c.aspectRatioElementIndexes)
-	c.aspectRatioElementIndexes = c.aspectRatioElementIndexes[:0]
 	clear(c.openClipElementStack)
 	c.openClipElementStack = c.openClipElementStack[:0]
 	clear(c.reusableElementIndexBuffer)
@@ -150,15 +179,37 @@ func (c *Context) initializeEphemeralMemory() {
 	c.renderCommands = c.renderCommands[:0]
 }
 
+// Reset at the start of a layout instead of in initializeEphemeralMemory, which runs before the render commands are returned.
+func (c *Context) resetRenderData() {
+	c.rectangleRenderData = c.rectangleRenderData[:0]
+	c.textRenderData = c.textRenderData[:0]
+	c.imageRenderData = c.imageRenderData[:0]
+	c.customRenderData = c.customRenderData[:0]
+	c.borderRenderData = c.borderRenderData[:0]
+	c.scissorsStartData = c.scissorsStartData[:0]
+	c.scissorsEndData = c.scissorsEndData[:0]
+}
+
+// Copies render data into one of the render data pools and returns a pointer to the copy.
+// Storing a pointer in RenderCommand.RenderData doesn't allocate, once the pool has grown to the size of the layout.
+func storeRenderData[T RenderDataType](pool *[]T, data T) *T {
+	*pool = append(*pool, data)
+	return &(*pool)[len(*pool)-1]
+}
+
 // Persistent memory - initialized once and not reset
 func (c *Context) initializePersistentMemory() {
 	maxElementCount := c.maxElementCount
 	maxMeasureTextCacheWordCount := c.maxMeasureTextCacheWordCount
 
 	c.scrollContainerDatas = make([]ScrollContainerDataInternal, 0, 100)
-	c.layoutElementsHashMap = map[uint32]LayoutElementHashMapItem{}
+	c.layoutElementsHashMap = map[uint64]LayoutElementHashMapItem{}
 	c.measuredWordsFreeList = make([]int32, 0, maxMeasureTextCacheWordCount)
-	c.measureTextHashMap = map[measureTextKey]MeasureTextCacheItem{}
+	c.measureTextHashMap = map[uint64]int32{}
+	c.measureTextCacheItems = make([]MeasureTextCacheItem, 0, maxMeasureTextCacheWordCount)
+	c.measureTextCacheFreeList = make([]int32, 0, maxMeasureTextCacheWordCount)
+	c.measureTextCacheHead = -1
+	c.measureTextCacheTail = -1
 	c.measuredWords = make([]MeasuredWord, 0, maxMeasureTextCacheWordCount)
 	c.pointerOverIds = make([]ElementId, 0, maxElementCount)
 	c.debugElementData = make([]DebugElementData, 0, maxElementCount)
@@ -168,8 +219,8 @@ func (c *Context) initializePersistentMemory() {
 	c.warnings = make([]Warning, 0, 100)
 
 	c.layoutConfigs = make([]LayoutConfig, 0, maxElementCount)
-	c.elementConfigs = make([]AnyElementConfig, 0, maxElementCount)
-	c.textElementConfigs = make([]TextElementConfig, 0, maxElementCount)
+	c.textElementConfigs = make([]TextElementConfig, 0, 2*maxElementCount) // One per TEXT_CONFIG and a copy per text element
+	c.aspectRatioElementConfigs = make([]AspectRatioElementConfig, 0, maxElementCount)
 	c.imageElementConfigs = make([]ImageElementConfig, 0, maxElementCount)
 	c.floatingElementConfigs = make([]FloatingElementConfig, 0, maxElementCount)
 	c.clipElementConfigs = make([]ClipElementConfig, 0, maxElementCount)
@@ -179,25 +230,45 @@ func (c *Context) initializePersistentMemory() {
 
 	c.layoutElementIdStrings = make([]string, 0, maxElementCount)
 	c.wrappedTextLines = make([]WrappedTextLine, 0, maxElementCount)
+	c.wrappedTextFragments = make([]WrappedTextFragment, 0, maxElementCount)
+	c.wrapLines = make([]wrapLine, 0, maxElementCount) // Every line holds at least one child
+	c.gridLayouts = make([]gridLayout, 0, maxElementCount)
+	c.gridTrackSizes = make([]float32, 0, maxElementCount)
+	c.gridCells = make([]gridCell, 0, maxElementCount) // Every child has one cell
+	c.richTextPieces = make([]richTextPiece, 0, maxMeasureTextCacheWordCount)
+	c.shapedGlyphs = make([]ShapedGlyph, 0, maxMeasureTextCacheWordCount)
 	c.layoutElementTreeNodeArray1 = make([]LayoutElementTreeNode, 0, maxElementCount)
 	c.layoutElementTreeRoots = make([]LayoutElementTreeRoot, 0, maxElementCount)
 	c.layoutElementChildren = make([]int, 0, maxElementCount)
 	c.openLayoutElementStack = make([]int, 0, maxElementCount)
 	c.textElementData = make([]TextElementData, 0, maxElementCount)
-	c.aspectRatioElementIndexes = make([]int, 0, maxElementCount)
 	c.renderCommands = make([]RenderCommand, 0, maxElementCount)
-	c.openClipElementStack = make([]int, 0, maxElementCount)
+	c.openClipElementStack = make([]uint64, 0, maxElementCount)
 	c.reusableElementIndexBuffer = make([]int32, 0, maxElementCount)
-	c.layoutElementClipElementIds = make([]int, 0, maxElementCount)
+	c.layoutElementClipElementIds = make([]uint64, 0, maxElementCount)
 	c.dynamicStringData = make([]byte, 0, maxElementCount)
 }
 
+// Sizes the visited flags used by depth first traversals to the current element count, reusing the buffer between frames.
+func (c *Context) resetTreeNodeVisited() []bool {
+	if cap(c.treeNodeVisited) < len(c.layoutElements) {
+		c.treeNodeVisited = make([]bool, len(c.layoutElements), cap(c.layoutElements))
+	}
+	c.treeNodeVisited = c.treeNodeVisited[:len(c.layoutElements)]
+	clear(c.treeNodeVisited)
+	return c.treeNodeVisited
+}
+
 func (c *Context) getOpenLayoutElement() *LayoutElement {
 	return &c.layoutElements[c.openLayoutElementStack[len(c.openLayoutElementStack)-1]]
 }
 
-func (c *Context) getParentElementId() uint32 {
-	return c.layoutElements[c.openLayoutElementStack[len(c.openLayoutElementStack)-2]].id
+// Returns the id of the element that the next element will be a child of.
+// ID_LOCAL() is evaluated before CLAY() opens the element, so that's the innermost open element. Note: earlier versions
+// took the element below it on the open element stack, which made local ids in sibling elements collide and changes
+// the id of every ID_LOCAL() and IDI_LOCAL() element compared to them.
+func (c *Context) getParentElementId() uint64 {
+	return c.layoutElements[c.openLayoutElementStack[len(c.openLayoutElementStack)-1]].id
 }
 
 func (c *Context) storeLayoutConfig(config LayoutConfig) *LayoutConfig {
@@ -212,6 +283,10 @@ func (c *Context) storeTextElementConfig(config TextElementConfig) *TextElementC
 	if c.booleanWarnings.maxElementsExceeded {
 		return &default_TextElementConfig
 	}
+	if len(c.textElementConfigs) == cap(c.textElementConfigs) {
+		c.reportElementsCapacityExceeded()
+		return &default_TextElementConfig
+	}
 	c.textElementConfigs = append(c.textElementConfigs, config)
 	return &c.textElementConfigs[len(c.textElementConfigs)-1]
 }
@@ -266,20 +341,102 @@ func (c *Context) storeBorderElementConfig(config BorderElementConfig) *BorderEl
 
 func (c *Context) storeSharedElementConfig(config SharedElementConfig) *SharedElementConfig {
 	if c.booleanWarnings.maxElementsExceeded {
-		return &default_SharedElementConfig
+		// The shared config is modified after it is stored, so it can't be the package level default
+		c.discardedSharedElementConfig = config
+		return &c.discardedSharedElementConfig
 	}
 	c.sharedElementConfigs = append(c.sharedElementConfigs, config)
 	return &c.sharedElementConfigs[len(c.sharedElementConfigs)-1]
 }
 
-func (c *Context) attachElementConfig(config AnyElementConfig) AnyElementConfig {
+// Marks the config stored at index in the typed config slice for kind as attached to the open element.
+func (c *Context) attachElementConfig(kind elementConfigKind, index int) {
 	if c.booleanWarnings.maxElementsExceeded {
-		return config
+		return
 	}
 	openLayoutElement := c.getOpenLayoutElement()
-	c.elementConfigs = append(c.elementConfigs, config)
-	openLayoutElement.elementConfigs = append(openLayoutElement.elementConfigs, config)
-	return config
+	openLayoutElement.configMask |= 1 << kind
+	openLayoutElement.configIndexes[kind] = int32(index)
+}
+
+// Returns the file and line of the code that called the exported function calling callerLocation.
+func callerLocation() string {
+	_, file, line, ok := runtime.Caller(2)
+	if !ok {
+		return ""
+	}
+	return file + ":" + strconv.Itoa(line)
+}
+
+func (c *Context) handleError(errorData ErrorData) {
+	c.layoutErrors = append(c.layoutErrors, errorData)
+	c.errorHandler.ErrorHandlerFunction(errorData)
+}
+
+func (c *Context) reportError(errorType ErrorType, errorText string) {
+	c.handleError(ErrorData{
+		ErrorType: errorType,
+		ErrorText: errorText,
+		UserData:  c.errorHandler.UserData,
+	})
+}
+
+// Reports an error caused by the innermost open element, including its string id and path in the tree.
+func (c *Context) reportOpenElementError(errorType ErrorType, errorText string) {
+	c.handleError(c.openElementErrorData(errorType, errorText))
+}
+
+func (c *Context) openElementErrorData(errorType ErrorType, errorText string) ErrorData {
+	stringId := ""
+	if len(c.openLayoutElementStack) > 0 {
+		stringId = c.layoutElementIdStrings[c.openLayoutElementStack[len(c.openLayoutElementStack)-1]]
+	}
+	return ErrorData{
+		ErrorType:   errorType,
+		ErrorText:   errorText,
+		UserData:    c.errorHandler.UserData,
+		StringId:    stringId,
+		ElementPath: c.openElementPath(),
+	}
+}
+
+// Reports an element declared with the same ElementId as an element declared before it in this layout.
+// Elements whose ids were hashed from different strings, offsets or parents collided, the others are true duplicates.
+// Duplicated ids of anonymous and text elements come from a duplicated parent id, which was already reported.
+func (c *Context) reportDuplicateId(first LayoutElementHashMapItem, elementId ElementId) {
+	var errorData ErrorData
+	if first.elementId == elementId {
+		if elementId.stringId == "" {
+			return
+		}
+		errorData = c.openElementErrorData(ERROR_TYPE_DUPLICATE_ID, "An element with the id "+elementId.String()+" was already previously declared during this layout.")
+	} else {
+		errorData = c.openElementErrorData(ERROR_TYPE_HASH_COLLISION, "The ids "+first.elementId.String()+" and "+elementId.String()+" have the same hash, the second element can't be found by its id. Rename one of them.")
+	}
+	errorData.FirstStringId = first.elementId.stringId
+	errorData.SourceLocation = c.declarationLocation
+	errorData.FirstSourceLocation = first.declarationLocation
+	c.handleError(errorData)
+}
+
+// Returns the string ids of the open elements from the root down, separated by "/".
+// Anonymous elements are written as their index in the parent, which is the number of children the parent had before them.
+func (c *Context) openElementPath() string {
+	var path strings.Builder
+	// BeginLayout pushes the root container twice, skip the first entry
+	for i := 1; i < len(c.openLayoutElementStack); i++ {
+		elementIndex := c.openLayoutElementStack[i]
+		if i > 1 {
+			path.WriteByte('/')
+		}
+		if stringId := c.layoutElementIdStrings[elementIndex]; stringId != "" {
+			path.WriteString(stringId)
+		} else {
+			path.WriteByte('#')
+			path.WriteString(strconv.Itoa(c.layoutElements[c.openLayoutElementStack[i-1]].childrenCount))
+		}
+	}
+	return path.String()
 }
 
 func (c *Context) addMeasuredWord(word MeasuredWord, previousWord *MeasuredWord) *MeasuredWord {
@@ -297,36 +454,61 @@ func (c *Context) addMeasuredWord(word MeasuredWord, previousWord *MeasuredWord)
 }
 
 func (c *Context) addHashMapItem(elementId ElementId, layoutElement *LayoutElement) LayoutElementHashMapItem {
+	if existing, ok := c.layoutElementsHashMap[elementId.id]; ok && existing.generation == c.generation+1 {
+		// The first element declared with an id keeps it, like in the previous frames
+		c.reportDuplicateId(existing, elementId)
+		return existing
+	}
+
 	item := LayoutElementHashMapItem{
-		elementId:     elementId,
-		layoutElement: layoutElement,
-		generation:    c.generation + 1,
+		elementId:           elementId,
+		layoutElement:       layoutElement,
+		layoutElementIndex:  int32(len(c.layoutElements) - 1),
+		generation:          c.generation + 1,
+		declarationLocation: c.declarationLocation,
 	}
 
 	c.layoutElementsHashMap[elementId.id] = item
 	return item
 }
 
+// Returns false once the layout holds as many elements as the configured maximum.
+// The first element over the limit reports ERROR_TYPE_ELEMENTS_CAPACITY_EXCEEDED, further elements are dropped silently until the next BeginLayout.
+func (c *Context) hasElementCapacity() bool {
+	if c.booleanWarnings.maxElementsExceeded {
+		return false
+	}
+	if len(c.layoutElements) >= int(c.maxElementCount)-1 {
+		c.reportElementsCapacityExceeded()
+		return false
+	}
+	return true
+}
+
+func (c *Context) reportElementsCapacityExceeded() {
+	if c.booleanWarnings.maxElementsExceeded {
+		return
+	}
+	c.booleanWarnings.maxElementsExceeded = true
+	c.reportOpenElementError(ERROR_TYPE_ELEMENTS_CAPACITY_EXCEEDED, "Clay ran out of capacity while attempting to create more layout elements. Try using SetMaxElementCount() with a higher value.")
+}
+
 func (c *Context) addRenderCommand(renderCommand RenderCommand) {
-	if len(c.renderCommands) < cap(c.renderCommands)-1 {
+	if len(c.renderCommands) < int(c.maxElementCount)-1 {
 		c.renderCommands = append(c.renderCommands, renderCommand)
 	} else {
 		if !c.booleanWarnings.maxRenderCommandsExceeded {
 			c.booleanWarnings.maxRenderCommandsExceeded = true
-			c.errorHandler.ErrorHandlerFunction(ErrorData{
-				ErrorType: ERROR_TYPE_ELEMENTS_CAPACITY_EXCEEDED,
-				ErrorText: "Clay ran out of capacity while attempting to create render commands. This is usually caused by a large amount of wrapping text elements while close to the max element capacity. Try using Clay_SetMaxElementCount() with a higher value.",
-				UserData:  c.errorHandler.UserData,
-			})
+			c.reportError(ERROR_TYPE_ELEMENTS_CAPACITY_EXCEEDED, "Clay ran out of capacity while attempting to create render commands. This is usually caused by a large amount of wrapping text elements while close to the max element capacity. Try using SetMaxElementCount() with a higher value.")
 		}
 	}
 }
 
 func (c *Context) generateIdForAnonymousElement(openLayoutElement *LayoutElement) ElementId {
 	parentElement := c.layoutElements[c.openLayoutElementStack[len(c.openLayoutElementStack)-2]]
-	elementId := hashNumber(uint32(len(parentElement.children)), parentElement.id)
+	elementId := hashNumber(uint32(parentElement.childrenCount), parentElement.id)
 	openLayoutElement.id = elementId.id
-	c.addHashMapItem(elementId, openLayoutElement)
 	c.layoutElementIdStrings = append(c.layoutElementIdStrings, elementId.stringId)
+	c.addHashMapItem(elementId, openLayoutElement)
 	return elementId
 }
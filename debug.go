@@ -280,7 +280,7 @@ func (c *Context) renderDebugLayoutElementsList(initialRootsLength int32, highli
 		}
 
 		if (context.pointerInfo.state == CLAY_POINTER_DATA_PRESSED_THIS_FRAME) {
-			Clay_ElementId collapseButtonId = hashString("Clay__DebugView_CollapseElement");
+			Clay_ElementId collapseButtonId = hashString("Clay__DebugView_CollapseElement", 0);
 			for (int32 i = (int)context.pointerOverIds.length - 1; i >= 0; i--) {
 				Clay_ElementId *elementId = Clay__ElementIdArray_Get(&context.pointerOverIds, i);
 				if (elementId.baseId == collapseButtonId.baseId) {
@@ -313,23 +313,54 @@ func (c *Context) renderDebugLayoutElementsList(initialRootsLength int32, highli
 	return layoutData
 }
 
-func (c *Context) renderDebugLayoutSizing(sizing AnySizingAxis, infoTextConfig *TextElementConfig) {
+func (c *Context) renderDebugMinMax(minMax SizingMinMax, infoTextConfig *TextElementConfig) {
+	c.CLAY_TEXT("(", infoTextConfig)
+	if minMax.Min != 0 {
+		c.CLAY_TEXT("min: ", infoTextConfig)
+		c.CLAY_TEXT(strconv.Itoa(int(minMax.Min)), infoTextConfig)
+		if minMax.Max != math.MaxFloat32 {
+			c.CLAY_TEXT(", ", infoTextConfig)
+		}
+	}
+	if minMax.Max != math.MaxFloat32 {
+		c.CLAY_TEXT("max: ", infoTextConfig)
+		c.CLAY_TEXT(strconv.Itoa(int(minMax.Max)), infoTextConfig)
+	}
+	c.CLAY_TEXT(")", infoTextConfig)
+}
+
+func (c *Context) renderDebugLayoutSizing(sizing SizingAxis, infoTextConfig *TextElementConfig) {
 	c.CLAY_TEXT(SizingAxisTypeString(sizing), infoTextConfig)
-	switch mm := sizing.(type) {
-	case SizingAxisMinMax:
+	switch sizing.Type {
+	case SIZING_TYPE_PERCENT:
 		c.CLAY_TEXT("(", infoTextConfig)
-		if mm.GetMinMax().Min != 0 {
-			c.CLAY_TEXT("min: ", infoTextConfig)
-			c.CLAY_TEXT(strconv.Itoa(int(mm.GetMinMax().Min)), infoTextConfig)
-			if mm.GetMinMax().Max != math.MaxFloat32 {
-				c.CLAY_TEXT(", ", infoTextConfig)
-			}
+		c.CLAY_TEXT(strconv.Itoa(int(sizing.Percent*100)), infoTextConfig)
+		switch sizing.Of {
+		case PERCENT_OF_VIEWPORT_WIDTH:
+			c.CLAY_TEXT("vw", infoTextConfig)
+		case PERCENT_OF_VIEWPORT_HEIGHT:
+			c.CLAY_TEXT("vh", infoTextConfig)
+		case PERCENT_OF_ELEMENT:
+			c.CLAY_TEXT("% of ", infoTextConfig)
+			c.CLAY_TEXT(sizing.Element.String(), infoTextConfig)
+		default:
+			c.CLAY_TEXT("%", infoTextConfig)
 		}
-		if mm.GetMinMax().Max != math.MaxFloat32 {
-			c.CLAY_TEXT("max: ", infoTextConfig)
-			c.CLAY_TEXT(strconv.Itoa(int(mm.GetMinMax().Max)), infoTextConfig)
+		c.CLAY_TEXT(")", infoTextConfig)
+		// The percentage is only clamped when a minimum or maximum is set
+		if minMax := sizing.GetMinMax(); minMax.Min != 0 || minMax.Max != math.MaxFloat32 {
+			c.renderDebugMinMax(minMax, infoTextConfig)
 		}
+	case SIZING_TYPE_CALC:
+		c.CLAY_TEXT("(", infoTextConfig)
+		c.CLAY_TEXT(sizing.String(), infoTextConfig)
 		c.CLAY_TEXT(")", infoTextConfig)
+	default:
+		c.renderDebugMinMax(sizing.GetMinMax(), infoTextConfig)
+	}
+	if sizing.Type == SIZING_TYPE_GROW && sizing.Weight > 0 {
+		c.CLAY_TEXT("weight: ", infoTextConfig)
+		c.CLAY_TEXT(strconv.FormatFloat(float64(sizing.Weight), 'g', -1, 32), infoTextConfig)
 	}
 }
 
@@ -418,7 +449,7 @@ func (c *Context) renderDebugViewCornerRadius(cornerRadius CornerRadius, textCon
 	}
 */
 func (c *Context) Clay__RenderDebugView() {
-	closeButtonId := hashString("Clay__DebugViewTopHeaderCloseButtonOuter")
+	closeButtonId := hashString("Clay__DebugViewTopHeaderCloseButtonOuter", 0)
 	if c.pointerInfo.State == POINTER_DATA_PRESSED_THIS_FRAME {
 		for _, elementId := range c.pointerOverIds {
 			if elementId.id == closeButtonId.id {
@@ -440,7 +471,7 @@ func (c *Context) Clay__RenderDebugView() {
 		FontSize:  16,
 		WrapMode:  TEXT_WRAP_NONE,
 	})
-	scrollId := hashString("Clay__DebugViewOuterScrollPane")
+	scrollId := hashString("Clay__DebugViewOuterScrollPane", 0)
 	scrollYOffset := float32(0)
 	pointerInDebugView := c.pointerInfo.Position.Y < c.layoutBoundingBox.Size.Y-300
 	for _, scrollContainerData := range c.scrollContainerDatas {
@@ -554,7 +585,7 @@ func (c *Context) Clay__RenderDebugView() {
 				},
 				BackgroundColor: bgColor,
 			}, func() {
-				panelContentsId := hashString("Clay__DebugViewPaneOuter")
+				panelContentsId := hashString("Clay__DebugViewPaneOuter", 0)
 				// Element list
 				c.CLAY_ID(panelContentsId, ElementDeclaration{
 					Layout: LayoutConfig{
@@ -728,6 +759,23 @@ func (c *Context) Clay__RenderDebugView() {
 							c.CLAY_TEXT(strconv.Itoa(int(layoutConfig.Padding.Bottom)), infoTextConfig)
 							c.CLAY_TEXT(" }", infoTextConfig)
 						})
+					if layoutConfig.Margin != (Margin{}) {
+						// .margin
+						c.CLAY_TEXT("Margin", infoTitleConfig)
+						c.CLAY(ElementDeclaration{
+							Layout: LayoutConfig{LayoutDirection: LEFT_TO_RIGHT},
+						}, func() {
+							c.CLAY_TEXT("{ left: ", infoTextConfig)
+							c.CLAY_TEXT(strconv.Itoa(int(layoutConfig.Margin.Left)), infoTextConfig)
+							c.CLAY_TEXT(", right: ", infoTextConfig)
+							c.CLAY_TEXT(strconv.Itoa(int(layoutConfig.Margin.Right)), infoTextConfig)
+							c.CLAY_TEXT(", top: ", infoTextConfig)
+							c.CLAY_TEXT(strconv.Itoa(int(layoutConfig.Margin.Top)), infoTextConfig)
+							c.CLAY_TEXT(", bottom: ", infoTextConfig)
+							c.CLAY_TEXT(strconv.Itoa(int(layoutConfig.Margin.Bottom)), infoTextConfig)
+							c.CLAY_TEXT(" }", infoTextConfig)
+						})
+					}
 					// .childGap
 					c.CLAY_TEXT("Child Gap", infoTitleConfig)
 					c.CLAY_TEXT(strconv.Itoa(int(layoutConfig.ChildGap)), infoTextConfig)
@@ -752,12 +800,60 @@ func (c *Context) Clay__RenderDebugView() {
 							alignY = "CENTER"
 						case ALIGN_Y_BOTTOM:
 							alignY = "BOTTOM"
+						case ALIGN_Y_BASELINE:
+							alignY = "BASELINE"
 						}
 						c.CLAY_TEXT(alignY, infoTextConfig)
 						c.CLAY_TEXT(" }", infoTextConfig)
 					})
+					if layoutConfig.ChildDistribution != DISTRIBUTE_PACKED {
+						// .childDistribution
+						c.CLAY_TEXT("Child Distribution", infoTitleConfig)
+						c.CLAY_TEXT(layoutConfig.ChildDistribution.String(), infoTextConfig)
+					}
+					if layoutConfig.Wrap != LAYOUT_WRAP_NONE {
+						// .wrap
+						c.CLAY_TEXT("Wrap", infoTitleConfig)
+						c.CLAY(ElementDeclaration{
+							Layout: LayoutConfig{LayoutDirection: LEFT_TO_RIGHT},
+						}, func() {
+							c.CLAY_TEXT("{ lineGap: ", infoTextConfig)
+							c.CLAY_TEXT(strconv.Itoa(int(layoutConfig.LineGap)), infoTextConfig)
+							c.CLAY_TEXT(", lineAlignment: ", infoTextConfig)
+							alignLines := "START"
+							switch layoutConfig.LineAlignment {
+							case ALIGN_LINES_END:
+								alignLines = "END"
+							case ALIGN_LINES_CENTER:
+								alignLines = "CENTER"
+							}
+							c.CLAY_TEXT(alignLines, infoTextConfig)
+							c.CLAY_TEXT(" }", infoTextConfig)
+						})
+					}
+					if layoutConfig.LayoutDirection == GRID {
+						// .grid
+						c.CLAY_TEXT("Grid", infoTitleConfig)
+						c.CLAY(ElementDeclaration{
+							Layout: LayoutConfig{LayoutDirection: LEFT_TO_RIGHT},
+						}, func() {
+							c.CLAY_TEXT("{ columns: ", infoTextConfig)
+							c.CLAY_TEXT(strconv.Itoa(len(layoutConfig.Grid.Columns)), infoTextConfig)
+							c.CLAY_TEXT(", rows: ", infoTextConfig)
+							c.CLAY_TEXT(strconv.Itoa(len(layoutConfig.Grid.Rows)), infoTextConfig)
+							c.CLAY_TEXT(", columnGap: ", infoTextConfig)
+							c.CLAY_TEXT(strconv.Itoa(int(layoutConfig.Grid.ColumnGap)), infoTextConfig)
+							c.CLAY_TEXT(", rowGap: ", infoTextConfig)
+							c.CLAY_TEXT(strconv.Itoa(int(layoutConfig.Grid.RowGap)), infoTextConfig)
+							c.CLAY_TEXT(" }", infoTextConfig)
+						})
+					}
 				})
-				for _, elementConfig := range selectedItem.layoutElement.elementConfigs {
+				for kind := range elementConfigKindCount {
+					if !selectedItem.layoutElement.configMask.has(kind) {
+						continue
+					}
+					elementConfig := c.elementConfig(kind, selectedItem.layoutElement.configIndexes[kind])
 					c.Clay__RenderDebugViewElementConfigHeader(selectedItem.elementId.stringId, elementConfig)
 					switch cfg := elementConfig.(type) {
 					case *SharedElementConfig:
@@ -0,0 +1,87 @@
+package clay
+
+import "strings"
+
+func (t ErrorType) String() string {
+	switch t {
+	case ERROR_TYPE_TEXT_MEASUREMENT_FUNCTION_NOT_PROVIDED:
+		return "TEXT_MEASUREMENT_FUNCTION_NOT_PROVIDED"
+	case ERROR_TYPE_ARENA_CAPACITY_EXCEEDED:
+		return "ARENA_CAPACITY_EXCEEDED"
+	case ERROR_TYPE_ELEMENTS_CAPACITY_EXCEEDED:
+		return "ELEMENTS_CAPACITY_EXCEEDED"
+	case ERROR_TYPE_TEXT_MEASUREMENT_CAPACITY_EXCEEDED:
+		return "TEXT_MEASUREMENT_CAPACITY_EXCEEDED"
+	case ERROR_TYPE_DUPLICATE_ID:
+		return "DUPLICATE_ID"
+	case ERROR_TYPE_FLOATING_CONTAINER_PARENT_NOT_FOUND:
+		return "FLOATING_CONTAINER_PARENT_NOT_FOUND"
+	case ERROR_TYPE_PERCENTAGE_OVER_1:
+		return "PERCENTAGE_OVER_1"
+	case ERROR_TYPE_INTERNAL_ERROR:
+		return "INTERNAL_ERROR"
+	case ERROR_TYPE_UNBALANCED_OPEN_CLOSE:
+		return "UNBALANCED_OPEN_CLOSE"
+	case ERROR_TYPE_GROW_IN_FIT_PARENT:
+		return "GROW_IN_FIT_PARENT"
+	case ERROR_TYPE_PERCENTAGE_SUM_OVER_1:
+		return "PERCENTAGE_SUM_OVER_1"
+	case ERROR_TYPE_NEGATIVE_CHILD_GAP:
+		return "NEGATIVE_CHILD_GAP"
+	case ERROR_TYPE_SIZING_MIN_OVER_MAX:
+		return "SIZING_MIN_OVER_MAX"
+	case ERROR_TYPE_HASH_COLLISION:
+		return "HASH_COLLISION"
+	case ERROR_TYPE_PERCENT_REFERENCE_NOT_FOUND:
+		return "PERCENT_REFERENCE_NOT_FOUND"
+	}
+
+	return ""
+}
+
+// ErrorType values are sentinel errors, the errors returned by EndLayoutWithError can be matched against them with errors.Is.
+func (t ErrorType) Error() string {
+	return "clay: " + t.String()
+}
+
+func (e ErrorData) Error() string {
+	var b strings.Builder
+	b.WriteString(e.ErrorType.Error())
+	if e.ElementPath != "" {
+		b.WriteString(" at ")
+		b.WriteString(e.ElementPath)
+	}
+	b.WriteString(": ")
+	b.WriteString(e.ErrorText)
+	return b.String()
+}
+
+// Returns the ErrorType, so that errors.Is(err, ERROR_TYPE_DUPLICATE_ID) matches an ErrorData.
+func (e ErrorData) Unwrap() error {
+	return e.ErrorType
+}
+
+// All errors reported while computing one layout, returned by EndLayoutWithError.
+// Use errors.Is with an ErrorType to check for a kind of error, or errors.As with an ErrorData to get the first one.
+type LayoutError struct {
+	Errors []ErrorData
+}
+
+func (e *LayoutError) Error() string {
+	var b strings.Builder
+	for i, err := range e.Errors {
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+		b.WriteString(err.Error())
+	}
+	return b.String()
+}
+
+func (e *LayoutError) Unwrap() []error {
+	errs := make([]error, len(e.Errors))
+	for i, err := range e.Errors {
+		errs[i] = err
+	}
+	return errs
+}
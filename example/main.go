@@ -6,6 +6,7 @@ import (
 	"runtime/pprof"
 
 	clay "github.com/igadmg/goclay"
+	"github.com/igadmg/goclay/xfont"
 )
 
 var COLOR_LIGHT clay.Color = clay.Color{R: 224, G: 215, B: 210, A: 255}
@@ -69,13 +70,7 @@ func main() {
 
 	// Note: screenWidth and screenHeight will need to come from your environment, Clay doesn't handle window related tasks
 	ui := clay.Initialize(screenSize, clay.ErrorHandler{})
-	ui.SetMeasureTextFunction(func(text string, config *clay.TextElementConfig, userData any) clay.Dimensions {
-		width := config.FontSize * 3 / 4
-		return clay.MakeDimensions(
-			width*uint16(len(text)),
-			config.FontSize,
-		)
-	}, nil)
+	ui.SetMeasureTextFunction(xfont.NewGoFaces().MeasureText, nil)
 
 	for range 100000 {
 		// Optional: Update internal layout dimensions to support resizing
@@ -172,13 +167,7 @@ func main_callback() {
 
 	// Note: screenWidth and screenHeight will need to come from your environment, Clay doesn't handle window related tasks
 	ui := clay.Initialize(screenSize, clay.ErrorHandler{})
-	ui.SetMeasureTextFunction(func(text string, config *clay.TextElementConfig, userData any) clay.Dimensions {
-		width := config.FontSize * 3 / 4
-		return clay.MakeDimensions(
-			width*uint16(len(text)),
-			config.FontSize,
-		)
-	}, nil)
+	ui.SetMeasureTextFunction(xfont.NewGoFaces().MeasureText, nil)
 
 	for range 100000 {
 		// Optional: Update internal layout dimensions to support resizing
@@ -291,13 +280,7 @@ func main_callback_with_separate_layout() {
 
 	// Note: screenWidth and screenHeight will need to come from your environment, Clay doesn't handle window related tasks
 	ui := clay.Initialize(screenSize, clay.ErrorHandler{})
-	ui.SetMeasureTextFunction(func(text string, config *clay.TextElementConfig, userData any) clay.Dimensions {
-		width := config.FontSize * 3 / 4
-		return clay.MakeDimensions(
-			width*uint16(len(text)),
-			config.FontSize,
-		)
-	}, nil)
+	ui.SetMeasureTextFunction(xfont.NewGoFaces().MeasureText, nil)
 
 	doLayout := func(size clay.BoundingBox) []clay.RenderCommand {
 		// Optional: Update internal layout dimensions to support resizing
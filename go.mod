@@ -7,6 +7,8 @@ require (
 	github.com/igadmg/goex v0.0.0-20260201172011-1108af2793e7
 	github.com/stretchr/testify v1.11.1
 	golang.org/x/exp v0.0.0-20260112195511-716be5621a96
+	golang.org/x/image v0.25.0
+	golang.org/x/text v0.34.0
 )
 
 require (
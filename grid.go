@@ -0,0 +1,325 @@
+package clay
+
+// Track sizes and child placement of an element with the GRID layout direction.
+type gridLayout struct {
+	columns []float32  // Sizes of the columns
+	rows    []float32  // Sizes of the rows, including the FIT rows added after the declared ones
+	cells   []gridCell // Cell of every child, in child order
+
+	rightToLeft bool // Set for grids declared in right to left mode, their first column is on the right
+}
+
+// The cells covered by a child of a grid, numbered from 0.
+type gridCell struct {
+	column     int
+	row        int
+	columnSpan int
+	rowSpan    int
+}
+
+// Rows added after the declared rows of a grid, and the column of a grid without declared columns.
+var default_GridTrack = TRACK_FIT()
+
+// Returns the first track and the number of tracks covered by the cell along the axis.
+func (cell gridCell) axis(axis Axis) (int, int) {
+	if axis == AxisX {
+		return cell.column, cell.columnSpan
+	}
+	return cell.row, cell.rowSpan
+}
+
+// Returns the declared tracks, the track sizes and the gap between tracks of the grid along the axis.
+func (grid *gridLayout) axis(config *GridConfig, axis Axis) ([]GridTrack, []float32, float32) {
+	if axis == AxisX {
+		return config.Columns, grid.columns, float32(config.ColumnGap)
+	}
+	return config.Rows, grid.rows, float32(config.RowGap)
+}
+
+// Returns the declared track, or the FIT track used for tracks that were added to place the children.
+func gridTrack(tracks []GridTrack, index int) GridTrack {
+	if index < len(tracks) {
+		return tracks[index]
+	}
+	return default_GridTrack
+}
+
+// Returns the offset of the first track from the start of the content box and the size of the tracks, including the gaps between them.
+func gridTrackSpan(sizes []float32, gap float32, start int, span int) (float32, float32) {
+	var offset, size float32
+	for _, trackSize := range sizes[:start] {
+		offset += trackSize + gap
+	}
+	for _, trackSize := range sizes[start : start+span] {
+		size += trackSize
+	}
+	return offset, size + float32(span-1)*gap
+}
+
+// Returns the size of all tracks including the gaps between them.
+func gridTracksSize(sizes []float32, gap float32) float32 {
+	if len(sizes) == 0 {
+		return 0
+	}
+	_, size := gridTrackSpan(sizes, gap, 0, len(sizes))
+	return size
+}
+
+// Places the children of a closing GRID element into cells, adding rows after the declared ones as needed.
+// Children with both a column and a row are placed first, then the others in order into the next free cells.
+func (c *Context) placeGridChildren(element *LayoutElement) *gridLayout {
+	config := &element.layoutConfig.Grid
+	columnCount := max(len(config.Columns), 1)
+	rowCount := len(config.Rows)
+	cellsStart := len(c.gridCells)
+	c.gridOccupiedCells = c.gridOccupiedCells[:0]
+
+	occupied := func(column, row, columnSpan, rowSpan int) bool {
+		for r := row; r < row+rowSpan; r++ {
+			for col := column; col < column+columnSpan; col++ {
+				if i := r*columnCount + col; i < len(c.gridOccupiedCells) && c.gridOccupiedCells[i] {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	for range element.children {
+		c.gridCells = append(c.gridCells, gridCell{})
+	}
+	cells := c.gridCells[cellsStart:len(c.gridCells):len(c.gridCells)]
+	var cursorColumn, cursorRow int
+	for _, placeExplicit := range [2]bool{true, false} {
+		for i, childIndex := range element.children {
+			placement := c.layoutElements[childIndex].layoutConfig.GridCell
+			if (placement.Column > 0 && placement.Row > 0) != placeExplicit {
+				continue
+			}
+			cell := gridCell{
+				columnSpan: min(max(int(placement.ColumnSpan), 1), columnCount),
+				rowSpan:    max(int(placement.RowSpan), 1),
+			}
+			switch {
+			case placeExplicit:
+				cell.column = min(int(placement.Column)-1, columnCount-cell.columnSpan)
+				cell.row = int(placement.Row) - 1
+			case placement.Column > 0:
+				// Takes the first free row in the column
+				cell.column = min(int(placement.Column)-1, columnCount-cell.columnSpan)
+				for occupied(cell.column, cell.row, cell.columnSpan, cell.rowSpan) {
+					cell.row++
+				}
+			case placement.Row > 0:
+				// Takes the first free column in the row, or overlaps the first column when the row is full
+				cell.row = int(placement.Row) - 1
+				for cell.column+cell.columnSpan < columnCount && occupied(cell.column, cell.row, cell.columnSpan, cell.rowSpan) {
+					cell.column++
+				}
+			default:
+				cell.column, cell.row = cursorColumn, cursorRow
+				for cell.column+cell.columnSpan > columnCount || occupied(cell.column, cell.row, cell.columnSpan, cell.rowSpan) {
+					cell.column++
+					if cell.column+cell.columnSpan > columnCount {
+						cell.column = 0
+						cell.row++
+					}
+				}
+				cursorColumn, cursorRow = cell.column+cell.columnSpan, cell.row
+			}
+
+			for len(c.gridOccupiedCells) < (cell.row+cell.rowSpan)*columnCount {
+				c.gridOccupiedCells = append(c.gridOccupiedCells, false)
+			}
+			for r := cell.row; r < cell.row+cell.rowSpan; r++ {
+				for col := cell.column; col < cell.column+cell.columnSpan; col++ {
+					c.gridOccupiedCells[r*columnCount+col] = true
+				}
+			}
+			rowCount = max(rowCount, cell.row+cell.rowSpan)
+			cells[i] = cell
+		}
+	}
+
+	tracksStart := len(c.gridTrackSizes)
+	for range columnCount + rowCount {
+		c.gridTrackSizes = append(c.gridTrackSizes, 0)
+	}
+	tracks := c.gridTrackSizes[tracksStart:len(c.gridTrackSizes):len(c.gridTrackSizes)]
+	c.gridLayouts = append(c.gridLayouts, gridLayout{
+		columns: tracks[:columnCount:columnCount],
+		rows:    tracks[columnCount:],
+		cells:   cells,
+
+		rightToLeft: c.rightToLeft,
+	})
+	return &c.gridLayouts[len(c.gridLayouts)-1]
+}
+
+// Sets the tracks of the grid along the axis to the size of their children, or the minimum size of their children.
+// FIXED tracks have their size, and FR tracks the same size per fraction, large enough for the children of every FR track.
+// Children spanning several tracks grow the tracks that aren't FIXED evenly when they don't fit.
+// Returns the size of all tracks including the gaps between them.
+func (c *Context) fitGridTracks(element *LayoutElement, axis Axis, minimum bool) float32 {
+	grid := element.grid
+	tracks, sizes, gap := grid.axis(&element.layoutConfig.Grid, axis)
+	clear(sizes)
+
+	childSize := func(child *LayoutElement) float32 {
+		if minimum {
+			return child.outerMinSize(axis)
+		}
+		return child.outerSize(axis)
+	}
+	for i, childIndex := range element.children {
+		if start, span := grid.cells[i].axis(axis); span == 1 {
+			sizes[start] = max(sizes[start], childSize(&c.layoutElements[childIndex]))
+		}
+	}
+	for t := range sizes {
+		if track := gridTrack(tracks, t); track.Type == GRID_TRACK_FIXED {
+			sizes[t] = track.Size
+		}
+	}
+	for i, childIndex := range element.children {
+		start, span := grid.cells[i].axis(axis)
+		if span == 1 {
+			continue
+		}
+		_, spanSize := gridTrackSpan(sizes, gap, start, span)
+		excess := childSize(&c.layoutElements[childIndex]) - spanSize
+		flexibleTracks := 0
+		for t := start; t < start+span; t++ {
+			if gridTrack(tracks, t).Type != GRID_TRACK_FIXED {
+				flexibleTracks++
+			}
+		}
+		if excess <= 0 || flexibleTracks == 0 {
+			continue
+		}
+		for t := start; t < start+span; t++ {
+			if gridTrack(tracks, t).Type != GRID_TRACK_FIXED {
+				sizes[t] += excess / float32(flexibleTracks)
+			}
+		}
+	}
+
+	var sizePerFraction float32
+	for t := range sizes {
+		track := gridTrack(tracks, t)
+		switch track.Type {
+		case GRID_TRACK_FIT:
+			sizes[t] = min(max(sizes[t], track.MinMax.Min), track.MinMax.Max)
+		case GRID_TRACK_FR:
+			if track.Size > 0 {
+				sizePerFraction = max(sizePerFraction, max(sizes[t], track.MinMax.Min)/track.Size)
+			}
+		}
+	}
+	for t := range sizes {
+		if track := gridTrack(tracks, t); track.Type == GRID_TRACK_FR {
+			sizes[t] = min(max(sizePerFraction*track.Size, track.MinMax.Min), track.MinMax.Max)
+		}
+	}
+	return gridTracksSize(sizes, gap)
+}
+
+// Shares the space the other tracks of the grid leave free along the axis between its FR tracks,
+// then sizes the children of the grid to their cells.
+func (c *Context) sizeGridAlongAxis(parent *LayoutElement, axis Axis) {
+	layoutConfig := parent.layoutConfig
+	tracks, sizes, gap := parent.grid.axis(&layoutConfig.Grid, axis)
+
+	var padding float32
+	if axis == AxisX {
+		padding = float32(layoutConfig.Padding.Left + layoutConfig.Padding.Right)
+	} else {
+		padding = float32(layoutConfig.Padding.Top + layoutConfig.Padding.Bottom)
+	}
+	freeSize := parent.dimensions.Axis(axis) - padding - float32(max(len(sizes)-1, 0))*gap
+	var fractions float32
+	for t, size := range sizes {
+		if track := gridTrack(tracks, t); track.Type == GRID_TRACK_FR {
+			fractions += track.Size
+			sizes[t] = -1 // Not sized yet
+		} else {
+			freeSize -= size
+		}
+	}
+	// FR tracks clamped to their minimum or maximum size leave the rest of the free space to the others
+	for fractions > 0 {
+		sizePerFraction := max(freeSize, 0) / fractions
+		clamped := false
+		for t, size := range sizes {
+			track := gridTrack(tracks, t)
+			if track.Type != GRID_TRACK_FR || size >= 0 {
+				continue
+			}
+			if shared := sizePerFraction * track.Size; shared < track.MinMax.Min || shared > track.MinMax.Max {
+				sizes[t] = min(max(shared, track.MinMax.Min), track.MinMax.Max)
+				freeSize -= sizes[t]
+				fractions -= track.Size
+				clamped = true
+			}
+		}
+		if clamped {
+			continue
+		}
+		for t, size := range sizes {
+			if track := gridTrack(tracks, t); track.Type == GRID_TRACK_FR && size < 0 {
+				sizes[t] = sizePerFraction * track.Size
+			}
+		}
+		break
+	}
+	for t, size := range sizes {
+		// FR tracks without a fraction
+		sizes[t] = max(size, 0)
+	}
+
+	for i, childIndex := range parent.children {
+		child := &c.layoutElements[childIndex]
+		if axis == AxisY && child.configMask.has(elementConfigAspectRatio) {
+			continue
+		}
+		start, span := parent.grid.cells[i].axis(axis)
+		_, cellSize := gridTrackSpan(sizes, gap, start, span)
+		cellSize -= child.marginAxis(axis)
+		minSize := child.minDimensions.Axis(axis)
+		switch s := child.layoutConfig.Sizing.GetAxis(axis); s.Type {
+		case SIZING_TYPE_GROW:
+			child.dimensions = child.dimensions.SetAxis(axis, max(minSize, min(cellSize, c.sizingMinMax(child, axis).Max)))
+		case SIZING_TYPE_FIT:
+			if tc, ok := c.textConfig(child); ok && tc.WrapMode != TEXT_WRAP_WORDS {
+				continue
+			}
+			child.dimensions = child.dimensions.SetAxis(axis, max(minSize, min(child.dimensions.Axis(axis), cellSize)))
+		case SIZING_TYPE_PERCENT:
+			child.dimensions = child.dimensions.SetAxis(axis, c.percentSize(s, axis, cellSize))
+		case SIZING_TYPE_CALC:
+			child.dimensions = child.dimensions.SetAxis(axis, c.calcSize(s, axis, cellSize))
+		}
+		c.applyAspectRatio(child, axis)
+	}
+}
+
+// Returns the position of the cell of a child of the grid relative to the grid, and the size of the cell.
+func (c *Context) gridCellBox(element *LayoutElement, childOffset int) (Vector2, Dimensions) {
+	layoutConfig := element.layoutConfig
+	cell := element.grid.cells[childOffset]
+	x, width := gridTrackSpan(element.grid.columns, float32(layoutConfig.Grid.ColumnGap), cell.column, cell.columnSpan)
+	y, height := gridTrackSpan(element.grid.rows, float32(layoutConfig.Grid.RowGap), cell.row, cell.rowSpan)
+	if element.grid.rightToLeft {
+		// Columns are placed from the right edge of the content box, the padding was already swapped
+		return MakeVector2(element.dimensions.X-float32(layoutConfig.Padding.Right)-x-width, float32(layoutConfig.Padding.Top)+y), MakeDimensions(width, height)
+	}
+	return MakeVector2(float32(layoutConfig.Padding.Left)+x, float32(layoutConfig.Padding.Top)+y), MakeDimensions(width, height)
+}
+
+// Returns the size of the columns and rows of the grid, including gaps but not padding.
+func (c *Context) gridContentSize(element *LayoutElement) Dimensions {
+	config := &element.layoutConfig.Grid
+	return MakeDimensions(
+		gridTracksSize(element.grid.columns, float32(config.ColumnGap)),
+		gridTracksSize(element.grid.rows, float32(config.RowGap)))
+}
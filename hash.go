@@ -2,54 +2,129 @@ package clay
 
 import (
 	"encoding/binary"
-	"hash"
-	"hash/fnv"
+	"math/bits"
 )
 
-var hasher hash.Hash32 = fnv.New32a()
-var hashBuffer []byte = make([]byte, 4+2*4+1)
+// Element ids are 64 bit hashes, so that even trees with hundreds of thousands of elements are very unlikely
+// to contain two different ids with the same hash. The hashing model follows upstream Clay:
+// - hashString hashes a label, optionally seeded with the parent id for ID_LOCAL().
+// - hashStringWithOffset hashes the label into a base id, then the index into it numerically for IDI().
+// - hashNumber derives the ids of anonymous elements from their parent id and index.
+// All of them are pure functions of their arguments, so hashing doesn't share state between goroutines and doesn't allocate.
 
-func hashNumber(offset uint32, seed uint32) ElementId {
-	le := binary.LittleEndian
-	na := hashBuffer[0:0]
-	na = le.AppendUint32(na, offset)
-	na = le.AppendUint32(na, seed)
+// Port of Clay__HashData, an ARX mix of the data in 16 byte blocks with constants pinched from BLAKE.
+// Upstream computes it with SSE2 on x86_64, this computes the two 64 bit lanes of that version in plain Go,
+// so that the hashes are the same on every platform.
+func hashData(data string) uint64 {
+	v0 := [2]uint64{0x6a09e667f3bcc908, 0x6a09e667f3bcc908}
+	v1 := [2]uint64{0xbb67ae8584caa73b, 0xbb67ae8584caa73b}
+	v2 := [2]uint64{0x3c6ef372fe94f82b, 0x3c6ef372fe94f82b}
+	v3 := [2]uint64{0xa54ff53a5f1d36f1, 0xa54ff53a5f1d36f1}
 
-	hasher.Reset()
-	hasher.Write(hashBuffer[:2*4])
-	hash := hasher.Sum32()
+	for len(data) > 0 {
+		var msg [2]uint64
+		if len(data) >= 16 {
+			msg[0] = loadUint64(data[0:8])
+			msg[1] = loadUint64(data[8:16])
+			data = data[16:]
+		} else {
+			// Temporary buffer for small inputs
+			var overflowBuffer [16]byte
+			copy(overflowBuffer[:], data)
+			msg[0] = binary.LittleEndian.Uint64(overflowBuffer[0:8])
+			msg[1] = binary.LittleEndian.Uint64(overflowBuffer[8:16])
+			data = ""
+		}
+
+		for i := range v0 {
+			v0[i] ^= msg[i]
+		}
+		arxMix(&v0, &v1)
+		arxMix(&v2, &v3)
+		for i := range v0 {
+			v0[i] += v2[i]
+			v1[i] += v3[i]
+		}
+	}
+
+	arxMix(&v0, &v1)
+	arxMix(&v2, &v3)
+	for i := range v0 {
+		v0[i] += v2[i]
+		v1[i] += v3[i]
+		v0[i] += v1[i]
+	}
+
+	return v0[0] ^ v0[1]
+}
+
+// Reads the little endian uint64 in the first 8 bytes of s, like binary.LittleEndian.Uint64 without converting to []byte.
+func loadUint64(s string) uint64 {
+	_ = s[7] // Bounds check hint to the compiler
+	return uint64(s[0]) | uint64(s[1])<<8 | uint64(s[2])<<16 | uint64(s[3])<<24 |
+		uint64(s[4])<<32 | uint64(s[5])<<40 | uint64(s[6])<<48 | uint64(s[7])<<56
+}
+
+func arxMix(a, b *[2]uint64) {
+	for i := range a {
+		a[i] += b[i]
+		b[i] = bits.RotateLeft64(b[i], 17) ^ a[i]
+	}
+}
+
+// Finalizer of splitmix64, every bit of h affects every bit of the result.
+func mix64(h uint64) uint64 {
+	h ^= h >> 30
+	h *= 0xbf58476d1ce4e5b9
+	h ^= h >> 27
+	h *= 0x94d049bb133111eb
+	h ^= h >> 31
+	return h
+}
+
+// Combines a seed, e.g. a parent id, with a value hashed into it.
+func hashCombine(seed uint64, value uint64) uint64 {
+	return mix64(seed ^ mix64(value+0x9e3779b97f4a7c15))
+}
+
+func hashNumber(offset uint32, seed uint64) ElementId {
+	hash := hashCombine(seed, uint64(offset))
 
 	return ElementId{
 		id:       hash + 1, // Reserve the hash result of zero as "null id"
+		offset:   offset,
+		baseId:   seed,
 		stringId: STRING_DEFAULT,
 	}
 }
 
-func hashString(key string) ElementId {
-	hasher.Reset()
-	hasher.Write([]byte(key))
-	hash := hasher.Sum32()
+func hashString(key string, seed uint64) ElementId {
+	hash := hashCombine(seed, hashData(key))
 
 	return ElementId{
 		id:       hash + 1, // Reserve the hash result of zero as "null id"
+		baseId:   hash + 1,
 		stringId: key,
 	}
 }
 
-func hashTextWithConfig(text string, config *TextElementConfig) uint32 {
-	hasher.Reset()
-	hasher.Write([]byte(text))
+// The base id is the id hashString(key, seed) returns, the offset is hashed into it as a number.
+func hashStringWithOffset(key string, offset uint32, seed uint64) ElementId {
+	base := hashCombine(seed, hashData(key))
+	hash := hashCombine(base, uint64(offset))
+
+	return ElementId{
+		id:       hash + 1, // Reserve the hash result of zero as "null id"
+		offset:   offset,
+		baseId:   base + 1,
+		stringId: key,
+	}
+}
 
-	le := binary.LittleEndian
-	na := hashBuffer[0:0]
-	na = le.AppendUint32(na, uint32(len(text)))
-	na = le.AppendUint16(na, config.FontId)
-	na = le.AppendUint16(na, config.FontSize)
-	na = le.AppendUint16(na, config.LineHeight)
-	na = le.AppendUint16(na, config.LetterSpacing)
-	na[0] = byte(config.WrapMode)
-	hasher.Write(hashBuffer[:4+2*4+1])
+func hashTextWithConfig(text string, config *TextElementConfig) uint64 {
+	hash := hashData(text)
+	hash = hashCombine(hash, uint64(config.FontId)|uint64(config.FontSize)<<16|uint64(config.LineHeight)<<32|uint64(config.LetterSpacing)<<48)
+	hash = hashCombine(hash, uint64(config.WrapMode))
 
-	hash := hasher.Sum32()
 	return hash + 1 // Reserve the hash result of zero as "null id"
 }
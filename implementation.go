@@ -2,6 +2,7 @@ package clay
 
 import (
 	"math"
+	"unicode/utf8"
 
 	"github.com/igadmg/gamemath/vector2"
 )
@@ -12,7 +13,6 @@ var Color_WHITE Color = Color{0xff, 0xff, 0xff, 0xff}
 var CornerRadius_DEFAULT CornerRadius
 var BorderWidth_DEFAULT BorderWidth
 
-var currentContext *Context = nil
 var defaultMaxElementCount int32 = 8192
 var defaultMaxMeasureTextWordCacheCount int32 = 16384
 
@@ -79,14 +79,77 @@ type ElementConfigType interface {
 
 type AnyElementConfig any
 
+// Kinds of element configs, in the order they are attached to an element.
+type elementConfigKind uint8
+
+const (
+	elementConfigShared elementConfigKind = iota
+	elementConfigText
+	elementConfigImage
+	elementConfigAspectRatio
+	elementConfigFloating
+	elementConfigCustom
+	elementConfigClip
+	elementConfigBorder
+	elementConfigKindCount
+)
+
+// Bit set of the elementConfigKinds attached to an element.
+type elementConfigMask uint8
+
+func (m elementConfigMask) has(kind elementConfigKind) bool {
+	return m&(1<<kind) != 0
+}
+
+// Clip configs are rendered first so that the scissor starts before the element itself is drawn, borders are drawn last.
+var elementConfigRenderOrder = [elementConfigKindCount]elementConfigKind{
+	elementConfigClip,
+	elementConfigShared,
+	elementConfigText,
+	elementConfigImage,
+	elementConfigAspectRatio,
+	elementConfigFloating,
+	elementConfigCustom,
+	elementConfigBorder,
+}
+
 type WrappedTextLine struct {
 	dimensions Dimensions
 	line       string
+	start      int32                 // Byte offset of the line in the element text
+	fragments  []WrappedTextFragment // Only set for rich text lines
+}
+
+// Part of a wrapped rich text line which is rendered with a single span config.
+type WrappedTextFragment struct {
+	offset     float32 // Horizontal offset from the start of the line
+	dimensions Dimensions
+	baseline   float32 // Distance from the top of the fragment to its baseline
+	text       string
+	config     *TextElementConfig
+	span       int32
+	start      int32
+	end        int32
+}
+
+// A measured word of a rich text span, used when wrapping spans together.
+type richTextPiece struct {
+	span     int32
+	start    int32
+	length   int32
+	width    float32
+	height   float32
+	baseline float32 // Distance from the top of the piece to its baseline
+	spacing  float32 // Width of the whitespace after the piece, zero if the piece is not followed by a space
+	glued    bool    // There is no break opportunity between this piece and the next one
+	newline  bool
 }
 
 type TextElementData struct {
 	text                string
+	spans               []TextSpan // Only set for rich text elements
 	preferredDimensions Dimensions
+	baseline            float32 // Distance from the top of the element to the baseline of the first line
 	elementIndex        int
 	wrappedLines        []WrappedTextLine
 }
@@ -96,12 +159,41 @@ type LayoutElement struct {
 	children        []int
 	textElementData *TextElementData
 	//}
+	childrenCount         int // Number of children attached while the element is open, children is only set once it is closed
 	dimensions            Dimensions
 	minDimensions         Dimensions
 	layoutConfig          *LayoutConfig
-	elementConfigs        []AnyElementConfig
-	id                    uint32
+	configMask            elementConfigMask
+	configIndexes         [elementConfigKindCount]int32 // Index into the typed config slice of the Context for every kind set in configMask
+	id                    uint64
 	floatingChildrenCount uint16
+	wrapLines             []wrapLine  // Set for elements with LAYOUT_WRAP once their children are broken into lines
+	grid                  *gridLayout // Set for elements with the GRID layout direction once they are closed
+}
+
+// Returns the margin of the element on both sides of the axis together.
+func (e *LayoutElement) marginAxis(axis Axis) float32 {
+	if axis == AxisX {
+		return float32(e.layoutConfig.Margin.Left) + float32(e.layoutConfig.Margin.Right)
+	}
+	return float32(e.layoutConfig.Margin.Top) + float32(e.layoutConfig.Margin.Bottom)
+}
+
+// Returns the size of the element along the axis including its margin, which is the space it takes up inside its parent.
+func (e *LayoutElement) outerSize(axis Axis) float32 {
+	return e.dimensions.Axis(axis) + e.marginAxis(axis)
+}
+
+// Returns the minimum size of the element along the axis including its margin.
+func (e *LayoutElement) outerMinSize(axis Axis) float32 {
+	return e.minDimensions.Axis(axis) + e.marginAxis(axis)
+}
+
+// A line of children of an element with LAYOUT_WRAP, a row for LEFT_TO_RIGHT and RIGHT_TO_LEFT, a column for TOP_TO_BOTTOM.
+type wrapLine struct {
+	start     int     // Index of the first child of the line in the children of the element
+	end       int     // Index one past the last child of the line
+	crossSize float32 // Size of the line along the non layout axis, the size of its largest child
 }
 
 type ScrollContainerDataInternal struct {
@@ -114,7 +206,7 @@ type ScrollContainerDataInternal struct {
 	scrollPosition      Vector2
 	previousDelta       Vector2
 	momentumTime        float32
-	elementId           uint32
+	elementId           uint64
 	openThisFrame       bool
 	pointerScrollActive bool
 }
@@ -128,9 +220,11 @@ type LayoutElementHashMapItem struct { // TODO(clay): get this struct into a sin
 	boundingBox           BoundingBox
 	elementId             ElementId
 	layoutElement         *LayoutElement
+	layoutElementIndex    int32 // Index of layoutElement in the layout it was declared in
 	onHoverFunction       func(elementId ElementId, pointerInfo PointerData, userData any)
 	hoverFunctionUserData any
 	generation            uint32
+	declarationLocation   string
 	//debugData             DebugElementData
 }
 
@@ -151,11 +245,36 @@ type MeasureTextCacheItem struct {
 	unwrappedDimensions     Dimensions
 	measuredWordsStartIndex int32
 	minWidth                float32
+	ascent                  float32
+	descent                 float32
 	containsNewlines        bool
-	generation              uint32
+	id                      uint64
+	text                    string // The measured text and config, compared on a hit in case of a hash collision
+	config                  measureTextConfig
+	previous                int32 // Neighbours in the least recently used list
+	next                    int32
+}
+
+// The values of a text config that text measurement depends on.
+type measureTextConfig struct {
+	fontId        uint16
+	fontSize      uint16
+	letterSpacing uint16
+	lineHeight    uint16
+	wrapMode      TextElementConfigWrapMode
+}
+
+func makeMeasureTextConfig(config *TextElementConfig) measureTextConfig {
+	return measureTextConfig{
+		fontId:        config.FontId,
+		fontSize:      config.FontSize,
+		letterSpacing: config.LetterSpacing,
+		lineHeight:    config.LineHeight,
+		wrapMode:      config.WrapMode,
+	}
 }
 
-var default_MeasureTextCacheItem MeasureTextCacheItem
+var default_MeasureTextCacheItem = MeasureTextCacheItem{measuredWordsStartIndex: -1}
 
 type LayoutElementTreeNode struct {
 	layoutElement   *LayoutElement
@@ -165,46 +284,154 @@ type LayoutElementTreeNode struct {
 
 type LayoutElementTreeRoot struct {
 	layoutElementIndex int
-	parentId           uint32 // This can be zero in the case of the root layout tree
-	clipElementId      uint32 // This can be zero if there is no clip element
+	parentId           uint64 // This can be zero in the case of the root layout tree
+	clipElementId      uint64 // This can be zero if there is no clip element
 	zIndex             int16
 	pointerOffset      Vector2 // Only used when scroll containers are managed externally
 }
 
-var measureText MeasureTextFn
-var queryScrollOffset QueryScrollOffsetFn
+// Typed config lookups, one per elementConfigKind so that they stay small enough to be inlined.
+func (c *Context) sharedConfig(element *LayoutElement) (*SharedElementConfig, bool) {
+	if !element.configMask.has(elementConfigShared) {
+		return nil, false
+	}
+	return &c.sharedElementConfigs[element.configIndexes[elementConfigShared]], true
+}
+
+func (c *Context) textConfig(element *LayoutElement) (*TextElementConfig, bool) {
+	if !element.configMask.has(elementConfigText) {
+		return nil, false
+	}
+	return &c.textElementConfigs[element.configIndexes[elementConfigText]], true
+}
 
-func findElementConfigWithType[T ElementConfigType](element *LayoutElement) (T, bool) {
-	for _, config := range element.elementConfigs {
-		switch cfg := config.(type) {
-		case T:
-			return cfg, true
-		}
+func (c *Context) imageConfig(element *LayoutElement) (*ImageElementConfig, bool) {
+	if !element.configMask.has(elementConfigImage) {
+		return nil, false
 	}
-	return nil, false
+	return &c.imageElementConfigs[element.configIndexes[elementConfigImage]], true
+}
+
+func (c *Context) aspectRatioConfig(element *LayoutElement) (*AspectRatioElementConfig, bool) {
+	if !element.configMask.has(elementConfigAspectRatio) {
+		return nil, false
+	}
+	return &c.aspectRatioElementConfigs[element.configIndexes[elementConfigAspectRatio]], true
+}
+
+func (c *Context) floatingConfig(element *LayoutElement) (*FloatingElementConfig, bool) {
+	if !element.configMask.has(elementConfigFloating) {
+		return nil, false
+	}
+	return &c.floatingElementConfigs[element.configIndexes[elementConfigFloating]], true
+}
+
+func (c *Context) customConfig(element *LayoutElement) (*CustomElementConfig, bool) {
+	if !element.configMask.has(elementConfigCustom) {
+		return nil, false
+	}
+	return &c.customElementConfigs[element.configIndexes[elementConfigCustom]], true
+}
+
+func (c *Context) clipConfig(element *LayoutElement) (*ClipElementConfig, bool) {
+	if !element.configMask.has(elementConfigClip) {
+		return nil, false
+	}
+	return &c.clipElementConfigs[element.configIndexes[elementConfigClip]], true
+}
+
+func (c *Context) borderConfig(element *LayoutElement) (*BorderElementConfig, bool) {
+	if !element.configMask.has(elementConfigBorder) {
+		return nil, false
+	}
+	return &c.borderElementConfigs[element.configIndexes[elementConfigBorder]], true
+}
+
+// Returns a pointer to the config stored at index in the typed config slice for kind.
+func (c *Context) elementConfig(kind elementConfigKind, index int32) AnyElementConfig {
+	switch kind {
+	case elementConfigShared:
+		return &c.sharedElementConfigs[index]
+	case elementConfigText:
+		return &c.textElementConfigs[index]
+	case elementConfigImage:
+		return &c.imageElementConfigs[index]
+	case elementConfigAspectRatio:
+		return &c.aspectRatioElementConfigs[index]
+	case elementConfigFloating:
+		return &c.floatingElementConfigs[index]
+	case elementConfigCustom:
+		return &c.customElementConfigs[index]
+	case elementConfigClip:
+		return &c.clipElementConfigs[index]
+	default:
+		return &c.borderElementConfigs[index]
+	}
+}
+
+// Measures text with the metrics function when it is set, otherwise the ascent is assumed to be the full text height.
+func (c *Context) measureTextWithMetrics(text string, config *TextElementConfig) TextMetrics {
+	if c.measureTextMetrics != nil {
+		return c.measureTextMetrics(text, config, c.measureTextUserData)
+	}
+	dimensions := c.measureText(text, config, c.measureTextUserData)
+	return TextMetrics{Dimensions: dimensions, Ascent: dimensions.Y}
+}
+
+// Attaches the shaped glyph run to a text render command when a text shaper is set.
+func (c *Context) shapeTextRenderCommand(textData *TextRenderData, config *TextElementConfig) {
+	if c.textShaper == nil {
+		return
+	}
+	start := len(c.shapedGlyphs)
+	run := c.textShaper.Shape(textData.StringContents, config, c.shapedGlyphs)
+	c.shapedGlyphs = run.Glyphs
+	textData.Glyphs = run.Glyphs[start:len(run.Glyphs):len(run.Glyphs)]
+}
+
+// Returns the distance from the top of the measured text to its baseline. The space the ascent and descent leave in the
+// height, such as the line gap of the font, is split evenly above and below the text. Without a descent the text is
+// assumed to fill the height below the baseline.
+func (m *MeasureTextCacheItem) baselineOffset() float32 {
+	if m.descent == 0 {
+		return m.ascent
+	}
+	return (m.unwrappedDimensions.Y-m.ascent-m.descent)/2 + m.ascent
+}
+
+// Returns the distance from the top of a line to the baseline of its bottom aligned text.
+func lineBaseline(naturalHeight float32, lineHeight float32, belowBaseline float32) float32 {
+	return (lineHeight-naturalHeight)/2 + naturalHeight - belowBaseline
 }
 
 func (c *Context) measureTextCached(text string, config *TextElementConfig) MeasureTextCacheItem {
-	if measureText == nil {
+	if c.measureText == nil {
 		if !c.booleanWarnings.textMeasurementFunctionNotSet {
 			c.booleanWarnings.textMeasurementFunctionNotSet = true
-			c.errorHandler.ErrorHandlerFunction(ErrorData{
-				ErrorType: ERROR_TYPE_TEXT_MEASUREMENT_FUNCTION_NOT_PROVIDED,
-				ErrorText: "Clay's internal MeasureText function is null. You may have forgotten to call Clay_SetMeasureTextFunction(), or passed a NULL function pointer by mistake.",
-				UserData:  c.errorHandler.UserData,
-			})
+			c.reportOpenElementError(ERROR_TYPE_TEXT_MEASUREMENT_FUNCTION_NOT_PROVIDED, "Clay's internal MeasureText function is nil. You may have forgotten to call SetMeasureTextFunction(), or passed a nil function by mistake.")
 		}
 		return default_MeasureTextCacheItem
 	}
 
-	id := measureTextKey{config, text}
-	if hashEntry, ok := c.measureTextHashMap[id]; ok {
-		return hashEntry
+	id := hashTextWithConfig(text, config)
+	measureConfig := makeMeasureTextConfig(config)
+	if index, ok := c.measureTextHashMap[id]; ok {
+		item := &c.measureTextCacheItems[index]
+		if item.text == text && item.config == measureConfig {
+			c.stats.MeasureTextCacheHits++
+			c.touchMeasureTextCacheItem(index)
+			return *item
+		}
+		// A different text or config with the same hash, the new measurement replaces it
+		c.evictMeasureTextCacheItem(index)
 	}
+	c.stats.MeasureTextCacheMisses++
 
 	measured := MeasureTextCacheItem{
 		measuredWordsStartIndex: -1,
-		generation:              c.generation + 1,
+		id:                      id,
+		text:                    text,
+		config:                  measureConfig,
 	}
 
 	/*
@@ -213,7 +440,7 @@ func (c *Context) measureTextCached(text string, config *TextElementConfig) Meas
 			if !c.booleanWarnings.maxTextMeasureCacheExceeded {
 				c.errorHandler.ErrorHandlerFunction(ErrorData{
 					ErrorType: ERROR_TYPE_ELEMENTS_CAPACITY_EXCEEDED,
-					ErrorText: "Clay ran out of capacity while attempting to measure text elements. Try using Clay_SetMaxElementCount() with a higher value.",
+					ErrorText: "Clay ran out of capacity while attempting to measure text elements. Try using SetMaxElementCount() with a higher value.",
 					UserData:  c.errorHandler.UserData})
 				c.booleanWarnings.maxTextMeasureCacheExceeded = true
 			}
@@ -227,7 +454,7 @@ func (c *Context) measureTextCached(text string, config *TextElementConfig) Meas
 	lineWidth := float32(0)
 	measuredWidth := float32(0)
 	measuredHeight := float32(0)
-	spaceWidth := measureText(SPACECHAR, config, c.measureTextUserData).X
+	spaceWidth := c.measureText(SPACECHAR, config, c.measureTextUserData).X
 
 	preFirstWord := MeasuredWord{next: -1}
 	previousWord := &preFirstWord
@@ -238,22 +465,19 @@ func (c *Context) measureTextCached(text string, config *TextElementConfig) Meas
 			continue
 		}
 
-		if len(c.measuredWords) == cap(c.measuredWords)-1 {
-			if !c.booleanWarnings.maxTextMeasureCacheExceeded {
-				c.errorHandler.ErrorHandlerFunction(ErrorData{
-					ErrorType: ERROR_TYPE_TEXT_MEASUREMENT_CAPACITY_EXCEEDED,
-					ErrorText: "Clay has run out of space in it's internal text measurement cache. Try using Clay_SetMaxMeasureTextCacheWordCount() (default 16384, with 1 unit storing 1 measured word).",
-					UserData:  c.errorHandler.UserData,
-				})
-				c.booleanWarnings.maxTextMeasureCacheExceeded = true
-			}
+		// A newline can add two words
+		if !c.reserveMeasuredWords(2) {
+			c.freeMeasuredWords(preFirstWord.next)
 			return default_MeasureTextCacheItem
 		}
 
 		length := end - start
 		var dimensions Dimensions
 		if length > 0 {
-			dimensions = measureText(text[start:end], config, c.measureTextUserData)
+			metrics := c.measureTextWithMetrics(text[start:end], config)
+			dimensions = metrics.Dimensions
+			measured.ascent = max(measured.ascent, metrics.Ascent)
+			measured.descent = max(measured.descent, metrics.Descent)
 		}
 		measured.minWidth = max(dimensions.X, measured.minWidth)
 		measuredHeight = max(float32(measuredHeight), dimensions.Y)
@@ -293,7 +517,14 @@ func (c *Context) measureTextCached(text string, config *TextElementConfig) Meas
 	}
 
 	if end-start > 0 {
-		dimensions := measureText(text[start:end], config, c.measureTextUserData)
+		if !c.reserveMeasuredWords(1) {
+			c.freeMeasuredWords(preFirstWord.next)
+			return default_MeasureTextCacheItem
+		}
+		metrics := c.measureTextWithMetrics(text[start:end], config)
+		dimensions := metrics.Dimensions
+		measured.ascent = max(measured.ascent, metrics.Ascent)
+		measured.descent = max(measured.descent, metrics.Descent)
 		c.addMeasuredWord(MeasuredWord{
 			startOffset: int32(start),
 			length:      int32(end - start),
@@ -310,34 +541,133 @@ func (c *Context) measureTextCached(text string, config *TextElementConfig) Meas
 	measured.unwrappedDimensions.X = measuredWidth
 	measured.unwrappedDimensions.Y = measuredHeight
 
-	c.measureTextHashMap[id] = measured
+	c.addMeasureTextCacheItem(measured)
 	return measured
 }
 
-func elementHasConfig[T ElementConfigType](layoutElement *LayoutElement) bool {
-	for _, config := range layoutElement.elementConfigs {
-		switch config.(type) {
-		case T:
-			return true
+// Makes room for count more measured words, evicting least recently used texts from the cache when it is full.
+// Reports an error and returns false if the text being measured doesn't fit into the cache on its own.
+func (c *Context) reserveMeasuredWords(count int) bool {
+	for len(c.measuredWordsFreeList)+cap(c.measuredWords)-1-len(c.measuredWords) < count {
+		if c.measureTextCacheTail == -1 {
+			if !c.booleanWarnings.maxTextMeasureCacheExceeded {
+				c.reportOpenElementError(ERROR_TYPE_TEXT_MEASUREMENT_CAPACITY_EXCEEDED, "Clay has run out of space in it's internal text measurement cache. Try using SetMaxMeasureTextCacheWordCount() (default 16384, with 1 unit storing 1 measured word).")
+				c.booleanWarnings.maxTextMeasureCacheExceeded = true
+			}
+			return false
 		}
+		c.evictMeasureTextCacheItem(c.measureTextCacheTail)
 	}
-	return false
+	return true
 }
 
-func updateAspectRatioBox(layoutElement *LayoutElement) {
-	for _, config := range layoutElement.elementConfigs {
-		switch c := config.(type) {
-		case *AspectRatioElementConfig:
-			if c.AspectRatio == 0 {
-				break
-			}
-			if layoutElement.dimensions.X == 0 && layoutElement.dimensions.Y != 0 {
-				layoutElement.dimensions.X = layoutElement.dimensions.Y * c.AspectRatio
-			} else if layoutElement.dimensions.X != 0 && layoutElement.dimensions.Y == 0 {
-				layoutElement.dimensions.Y = layoutElement.dimensions.Y * (1 / c.AspectRatio)
-			}
+// Returns a chain of measured words starting at index to the free list.
+func (c *Context) freeMeasuredWords(index int32) {
+	for index != -1 {
+		c.measuredWordsFreeList = append(c.measuredWordsFreeList, index)
+		index = c.measuredWords[index].next
+	}
+}
+
+// Stores a measured text as the most recently used cache item.
+func (c *Context) addMeasureTextCacheItem(measured MeasureTextCacheItem) {
+	if len(c.measureTextCacheFreeList) == 0 && len(c.measureTextCacheItems) == cap(c.measureTextCacheItems) {
+		c.evictMeasureTextCacheItem(c.measureTextCacheTail)
+	}
+
+	var index int32
+	if len(c.measureTextCacheFreeList) > 0 {
+		index = c.measureTextCacheFreeList[len(c.measureTextCacheFreeList)-1]
+		c.measureTextCacheFreeList = c.measureTextCacheFreeList[:len(c.measureTextCacheFreeList)-1]
+	} else {
+		index = int32(len(c.measureTextCacheItems))
+		c.measureTextCacheItems = append(c.measureTextCacheItems, MeasureTextCacheItem{})
+	}
+
+	measured.previous = -1
+	measured.next = c.measureTextCacheHead
+	c.measureTextCacheItems[index] = measured
+	if c.measureTextCacheHead != -1 {
+		c.measureTextCacheItems[c.measureTextCacheHead].previous = index
+	} else {
+		c.measureTextCacheTail = index
+	}
+	c.measureTextCacheHead = index
+	c.measureTextHashMap[measured.id] = index
+}
+
+// Moves a cache item to the front of the least recently used list.
+func (c *Context) touchMeasureTextCacheItem(index int32) {
+	if index == c.measureTextCacheHead {
+		return
+	}
+	c.unlinkMeasureTextCacheItem(index)
+	item := &c.measureTextCacheItems[index]
+	item.previous = -1
+	item.next = c.measureTextCacheHead
+	c.measureTextCacheItems[c.measureTextCacheHead].previous = index
+	c.measureTextCacheHead = index
+}
+
+func (c *Context) unlinkMeasureTextCacheItem(index int32) {
+	item := &c.measureTextCacheItems[index]
+	if item.previous != -1 {
+		c.measureTextCacheItems[item.previous].next = item.next
+	} else {
+		c.measureTextCacheHead = item.next
+	}
+	if item.next != -1 {
+		c.measureTextCacheItems[item.next].previous = item.previous
+	} else {
+		c.measureTextCacheTail = item.previous
+	}
+}
+
+func (c *Context) evictMeasureTextCacheItem(index int32) {
+	c.unlinkMeasureTextCacheItem(index)
+	item := &c.measureTextCacheItems[index]
+	c.freeMeasuredWords(item.measuredWordsStartIndex)
+	delete(c.measureTextHashMap, item.id)
+	*item = MeasureTextCacheItem{}
+	c.measureTextCacheFreeList = append(c.measureTextCacheFreeList, index)
+	c.stats.MeasureTextCacheEvictions++
+}
+
+func (c *Context) updateAspectRatioBox(layoutElement *LayoutElement) {
+	config, ok := c.aspectRatioConfig(layoutElement)
+	if !ok || config.AspectRatio == 0 {
+		return
+	}
+	if layoutElement.dimensions.X == 0 && layoutElement.dimensions.Y != 0 {
+		layoutElement.dimensions.X = layoutElement.dimensions.Y * config.AspectRatio
+	} else if layoutElement.dimensions.X != 0 && layoutElement.dimensions.Y == 0 {
+		layoutElement.dimensions.Y = layoutElement.dimensions.X * (1 / config.AspectRatio)
+	}
+}
+
+// Returns the min and max size of an element along the axis. For an element with an aspect ratio they are narrowed by
+// the min and max of the other axis, so that the size derived from them through the ratio stays within its own limits.
+func (c *Context) sizingMinMax(element *LayoutElement, axis Axis) SizingMinMax {
+	minMax := SizingMinMax{Max: math.MaxFloat32}
+	if sizing := element.layoutConfig.Sizing.GetAxis(axis); sizing.fitsContent() {
+		minMax = sizing.GetMinMax()
+	}
+	config, ok := c.aspectRatioConfig(element)
+	if !ok || config.AspectRatio == 0 {
+		return minMax
+	}
+	otherAxis, scale := AxisY, config.AspectRatio
+	if axis == AxisY {
+		otherAxis, scale = AxisX, 1/config.AspectRatio
+	}
+	if sizing := element.layoutConfig.Sizing.GetAxis(otherAxis); sizing.fitsContent() {
+		other := sizing.GetMinMax()
+		minMax.Min = max(minMax.Min, other.Min*scale)
+		if other.Max < math.MaxFloat32 {
+			minMax.Max = min(minMax.Max, other.Max*scale)
 		}
 	}
+	return minMax
 }
 
 func (c *Context) closeElement() {
@@ -350,109 +680,216 @@ func (c *Context) closeElement() {
 		openLayoutElement.layoutConfig = &default_LayoutConfig
 	}
 
+	if c.strictValidation {
+		c.validateOpenElement(openLayoutElement)
+	}
+
 	layoutConfig := openLayoutElement.layoutConfig
 	elementHasClipHorizontal := false
 	elementHasClipVertical := false
 
-	for _, config := range openLayoutElement.elementConfigs {
-		switch cfg := config.(type) {
-		case *ClipElementConfig:
-			elementHasClipHorizontal = cfg.Horizontal
-			elementHasClipVertical = cfg.Vertical
-			c.openClipElementStack = c.openClipElementStack[:len(c.openClipElementStack)-1]
-		case *FloatingElementConfig:
-		}
+	if clipConfig, ok := c.clipConfig(openLayoutElement); ok {
+		elementHasClipHorizontal = clipConfig.Horizontal
+		elementHasClipVertical = clipConfig.Vertical
+		c.openClipElementStack = c.openClipElementStack[:len(c.openClipElementStack)-1]
 	}
 
 	leftRightPadding := float32(layoutConfig.Padding.Left + layoutConfig.Padding.Right)
 	topBottomPadding := float32(layoutConfig.Padding.Top + layoutConfig.Padding.Bottom)
+	// A wrapping element can shrink along its layout axis until its largest child is alone on a line
+	wraps := layoutConfig.Wrap == LAYOUT_WRAP
 
 	// Attach children to the current open element
 	lenlayoutElementChildren := len(c.layoutElementChildren)
-	c.layoutElementChildren = c.layoutElementChildren[0 : lenlayoutElementChildren+len(openLayoutElement.children)]
-	openLayoutElement.children = c.layoutElementChildren[lenlayoutElementChildren : lenlayoutElementChildren+len(openLayoutElement.children)]
+	c.layoutElementChildren = c.layoutElementChildren[0 : lenlayoutElementChildren+openLayoutElement.childrenCount]
+	openLayoutElement.children = c.layoutElementChildren[lenlayoutElementChildren : lenlayoutElementChildren+openLayoutElement.childrenCount]
 	switch layoutConfig.LayoutDirection {
-	case LEFT_TO_RIGHT:
+	case LEFT_TO_RIGHT, RIGHT_TO_LEFT:
 		openLayoutElement.dimensions.X = leftRightPadding
 		openLayoutElement.minDimensions.X = leftRightPadding
 		for i := range openLayoutElement.children {
 			childIndex := c.layoutElementChildrenBuffer[len(c.layoutElementChildrenBuffer)-len(openLayoutElement.children)+i]
 			child := c.layoutElements[childIndex]
-			openLayoutElement.dimensions.X += child.dimensions.X
+			openLayoutElement.dimensions.X += child.outerSize(AxisX)
 			openLayoutElement.dimensions.Y = max(
 				openLayoutElement.dimensions.Y,
-				child.dimensions.Y+topBottomPadding)
+				child.outerSize(AxisY)+topBottomPadding)
 
 			// Minimum size of child elements doesn't matter to clip containers as they can shrink and hide their contents
 			if !elementHasClipHorizontal {
-				openLayoutElement.minDimensions.X += child.minDimensions.X
+				if wraps {
+					openLayoutElement.minDimensions.X = max(openLayoutElement.minDimensions.X, child.outerMinSize(AxisX)+leftRightPadding)
+				} else {
+					openLayoutElement.minDimensions.X += child.outerMinSize(AxisX)
+				}
 			}
 			if !elementHasClipVertical {
 				openLayoutElement.minDimensions.Y = max(
 					openLayoutElement.minDimensions.Y,
-					child.minDimensions.Y+topBottomPadding)
+					child.outerMinSize(AxisY)+topBottomPadding)
 			}
 			openLayoutElement.children[i] = childIndex
 		}
 
 		childGap := float32(max(len(openLayoutElement.children)-1, 0) * int(layoutConfig.ChildGap))
 		openLayoutElement.dimensions.X += childGap
-		if !elementHasClipHorizontal {
+		if !elementHasClipHorizontal && !wraps {
 			openLayoutElement.minDimensions.X += childGap
 		}
+		if layoutConfig.ChildAlignment.Y == ALIGN_Y_BASELINE {
+			// Children shifted down to a common baseline need more height than the tallest child alone
+			baselineHeight := c.baselineAlignedHeight(openLayoutElement) + topBottomPadding
+			openLayoutElement.dimensions.Y = max(openLayoutElement.dimensions.Y, baselineHeight)
+			if !elementHasClipVertical {
+				openLayoutElement.minDimensions.Y = max(openLayoutElement.minDimensions.Y, baselineHeight)
+			}
+		}
 	case TOP_TO_BOTTOM:
 		openLayoutElement.dimensions.Y = topBottomPadding
 		openLayoutElement.minDimensions.Y = topBottomPadding
 		for i := range openLayoutElement.children {
 			childIndex := c.layoutElementChildrenBuffer[len(c.layoutElementChildrenBuffer)-len(openLayoutElement.children)+i]
 			child := c.layoutElements[childIndex]
-			openLayoutElement.dimensions.Y += child.dimensions.Y
+			openLayoutElement.dimensions.Y += child.outerSize(AxisY)
 			openLayoutElement.dimensions.X = max(
 				openLayoutElement.dimensions.X,
-				child.dimensions.X+leftRightPadding)
+				child.outerSize(AxisX)+leftRightPadding)
 			// Minimum size of child elements doesn't matter to clip containers as they can shrink and hide their contents
 			if !elementHasClipVertical {
-				openLayoutElement.minDimensions.Y += child.minDimensions.Y
+				if wraps {
+					openLayoutElement.minDimensions.Y = max(openLayoutElement.minDimensions.Y, child.outerMinSize(AxisY)+topBottomPadding)
+				} else {
+					openLayoutElement.minDimensions.Y += child.outerMinSize(AxisY)
+				}
 			}
 			if !elementHasClipHorizontal {
 				openLayoutElement.minDimensions.X = max(
 					openLayoutElement.minDimensions.X,
-					child.minDimensions.X+leftRightPadding)
+					child.outerMinSize(AxisX)+leftRightPadding)
 			}
 			openLayoutElement.children[i] = childIndex
 		}
 		childGap := float32(max(len(openLayoutElement.children)-1, 0) * int(layoutConfig.ChildGap))
 		openLayoutElement.dimensions.Y += childGap
-		if elementHasClipVertical {
+		if elementHasClipVertical && !wraps {
 			openLayoutElement.minDimensions.Y += childGap
 		}
+	case GRID:
+		for i := range openLayoutElement.children {
+			openLayoutElement.children[i] = c.layoutElementChildrenBuffer[len(c.layoutElementChildrenBuffer)-len(openLayoutElement.children)+i]
+		}
+		openLayoutElement.grid = c.placeGridChildren(openLayoutElement)
+		openLayoutElement.minDimensions = MakeDimensions(leftRightPadding, topBottomPadding)
+		if !elementHasClipHorizontal {
+			openLayoutElement.minDimensions.X += c.fitGridTracks(openLayoutElement, AxisX, true)
+		}
+		if !elementHasClipVertical {
+			openLayoutElement.minDimensions.Y += c.fitGridTracks(openLayoutElement, AxisY, true)
+		}
+		openLayoutElement.dimensions = MakeDimensions(
+			leftRightPadding+c.fitGridTracks(openLayoutElement, AxisX, false),
+			topBottomPadding+c.fitGridTracks(openLayoutElement, AxisY, false))
+	case STACK:
+		openLayoutElement.dimensions = MakeDimensions(leftRightPadding, topBottomPadding)
+		openLayoutElement.minDimensions = MakeDimensions(leftRightPadding, topBottomPadding)
+		for i := range openLayoutElement.children {
+			childIndex := c.layoutElementChildrenBuffer[len(c.layoutElementChildrenBuffer)-len(openLayoutElement.children)+i]
+			child := c.layoutElements[childIndex]
+			openLayoutElement.dimensions.X = max(openLayoutElement.dimensions.X, child.outerSize(AxisX)+leftRightPadding)
+			openLayoutElement.dimensions.Y = max(openLayoutElement.dimensions.Y, child.outerSize(AxisY)+topBottomPadding)
+			// Minimum size of child elements doesn't matter to clip containers as they can shrink and hide their contents
+			if !elementHasClipHorizontal {
+				openLayoutElement.minDimensions.X = max(openLayoutElement.minDimensions.X, child.outerMinSize(AxisX)+leftRightPadding)
+			}
+			if !elementHasClipVertical {
+				openLayoutElement.minDimensions.Y = max(openLayoutElement.minDimensions.Y, child.outerMinSize(AxisY)+topBottomPadding)
+			}
+			openLayoutElement.children[i] = childIndex
+		}
 	}
 
 	c.layoutElementChildrenBuffer = c.layoutElementChildrenBuffer[:len(c.layoutElementChildrenBuffer)-len(openLayoutElement.children)]
 
+	// GROW elements with a flex basis start growing or shrinking from it instead of from the size of their content
+	if w := layoutConfig.Sizing.Width; w.Type == SIZING_TYPE_GROW && w.Basis > 0 {
+		openLayoutElement.dimensions.X = w.Basis
+	}
+	if h := layoutConfig.Sizing.Height; h.Type == SIZING_TYPE_GROW && h.Basis > 0 {
+		openLayoutElement.dimensions.Y = h.Basis
+	}
+
+	// Intrinsic sizes are taken from the content here, the element is neither grown nor compressed by its parent afterwards
+	switch layoutConfig.Sizing.Width.Type {
+	case SIZING_TYPE_MIN_CONTENT:
+		openLayoutElement.dimensions.X = openLayoutElement.minDimensions.X
+	case SIZING_TYPE_MAX_CONTENT:
+		openLayoutElement.minDimensions.X = openLayoutElement.dimensions.X
+	}
+	switch layoutConfig.Sizing.Height.Type {
+	case SIZING_TYPE_MIN_CONTENT:
+		openLayoutElement.dimensions.Y = openLayoutElement.minDimensions.Y
+	case SIZING_TYPE_MAX_CONTENT:
+		openLayoutElement.minDimensions.Y = openLayoutElement.dimensions.Y
+	}
+
 	// Clamp element min and max width to the values configured in the layout
-	switch w := layoutConfig.Sizing.Width.(type) {
-	case SizingAxisMinMax:
+	switch w := layoutConfig.Sizing.Width; w.Type {
+	case SIZING_TYPE_PERCENT:
+		// Percentages of the viewport are known up front, so FIT parents can wrap around them, and around the minimum of the others
+		openLayoutElement.dimensions.X = max(c.viewportPercentSize(w, AxisX), w.clamp(0))
+		openLayoutElement.minDimensions.X = openLayoutElement.dimensions.X
+	case SIZING_TYPE_CALC:
+		openLayoutElement.dimensions.X = c.viewportCalcSize(w, AxisX)
+		openLayoutElement.minDimensions.X = openLayoutElement.dimensions.X
+	default:
 		mm := w.GetMinMax()
 		openLayoutElement.dimensions.X = min(max(openLayoutElement.dimensions.X, mm.Min), mm.Max)
 		openLayoutElement.minDimensions.X = min(max(openLayoutElement.minDimensions.X, mm.Min), mm.Max)
-	default:
-		openLayoutElement.dimensions.X = 0
 	}
 
 	// Clamp element min and max height to the values configured in the layout
-	switch h := layoutConfig.Sizing.Height.(type) {
-	case SizingAxisMinMax:
+	switch h := layoutConfig.Sizing.Height; h.Type {
+	case SIZING_TYPE_PERCENT:
+		openLayoutElement.dimensions.Y = max(c.viewportPercentSize(h, AxisY), h.clamp(0))
+		openLayoutElement.minDimensions.Y = openLayoutElement.dimensions.Y
+	case SIZING_TYPE_CALC:
+		openLayoutElement.dimensions.Y = c.viewportCalcSize(h, AxisY)
+		openLayoutElement.minDimensions.Y = openLayoutElement.dimensions.Y
+	default:
 		mm := h.GetMinMax()
 		openLayoutElement.dimensions.Y = min(max(openLayoutElement.dimensions.Y, mm.Min), mm.Max)
 		openLayoutElement.minDimensions.Y = min(max(openLayoutElement.minDimensions.Y, mm.Min), mm.Max)
-	default:
-		openLayoutElement.dimensions.Y = 0
 	}
 
-	updateAspectRatioBox(openLayoutElement)
+	// Columns are broken by the height of the element, which FIXED and FIT heights already have here. Break them now
+	// so that a FIT width, and with it the FIT widths of the ancestors, covers every column.
+	if wraps && layoutConfig.LayoutDirection == TOP_TO_BOTTOM && len(openLayoutElement.children) > 0 {
+		if fit := layoutConfig.Sizing.Width; fit.Type == SIZING_TYPE_FIT {
+			switch layoutConfig.Sizing.Height.Type {
+			case SIZING_TYPE_FIXED, SIZING_TYPE_FIT:
+				columnsWidth := c.wrapColumnsWidth(openLayoutElement, openLayoutElement.dimensions.Y-topBottomPadding) + leftRightPadding
+				mm := fit.GetMinMax()
+				openLayoutElement.dimensions.X = min(max(columnsWidth, mm.Min), mm.Max)
+			}
+		}
+	}
+
+	// An element with an aspect ratio can't be sized past what the min and max of its height allow, and its height
+	// follows its width rather than its content
+	if config, ok := c.aspectRatioConfig(openLayoutElement); ok && config.AspectRatio > 0 {
+		mm := c.sizingMinMax(openLayoutElement, AxisX)
+		openLayoutElement.dimensions.X = min(max(openLayoutElement.dimensions.X, mm.Min), mm.Max)
+		openLayoutElement.minDimensions.X = min(max(openLayoutElement.minDimensions.X, mm.Min), mm.Max)
+		if openLayoutElement.dimensions.X != 0 {
+			mm = c.sizingMinMax(openLayoutElement, AxisY)
+			openLayoutElement.dimensions.Y = min(max(openLayoutElement.dimensions.X/config.AspectRatio, mm.Min), mm.Max)
+			openLayoutElement.minDimensions.Y = openLayoutElement.dimensions.Y
+		}
+	}
+
+	c.updateAspectRatioBox(openLayoutElement)
 
-	elementIsFloating := elementHasConfig[*FloatingElementConfig](openLayoutElement)
+	elementIsFloating := openLayoutElement.configMask.has(elementConfigFloating)
 
 	// Close the currently open element
 	var closingElementIndex int
@@ -466,15 +903,13 @@ func (c *Context) closeElement() {
 			openLayoutElement.floatingChildrenCount++
 			return
 		}
-		c.layoutElementChildren = append(c.layoutElementChildren, closingElementIndex)
-		openLayoutElement.children = append(openLayoutElement.children, closingElementIndex)
+		openLayoutElement.childrenCount++
 		c.layoutElementChildrenBuffer = append(c.layoutElementChildrenBuffer, closingElementIndex)
 	}
 }
 
 func (c *Context) openElement() bool {
-	if len(c.layoutElements) == cap(c.layoutElements)-1 || c.booleanWarnings.maxElementsExceeded {
-		c.booleanWarnings.maxElementsExceeded = true
+	if !c.hasElementCapacity() {
 		return false
 	}
 
@@ -497,8 +932,7 @@ func (c *Context) openElement() bool {
 }
 
 func (c *Context) openElementWithId(id ElementId) bool {
-	if len(c.layoutElements) == cap(c.layoutElements)-1 || c.booleanWarnings.maxElementsExceeded {
-		c.booleanWarnings.maxElementsExceeded = true
+	if !c.hasElementCapacity() {
 		return false
 	}
 
@@ -507,8 +941,8 @@ func (c *Context) openElementWithId(id ElementId) bool {
 	})
 	c.openLayoutElementStack = append(c.openLayoutElementStack, len(c.layoutElements)-1)
 	openLayoutElement := &c.layoutElements[len(c.layoutElements)-1]
-	c.addHashMapItem(id, openLayoutElement)
 	c.layoutElementIdStrings = append(c.layoutElementIdStrings, id.stringId)
+	c.addHashMapItem(id, openLayoutElement)
 	if len(c.openClipElementStack) > 0 {
 		c.layoutElementClipElementIds = slicesex_Set(
 			c.layoutElementClipElementIds,
@@ -525,10 +959,38 @@ func (c *Context) openElementWithId(id ElementId) bool {
 }
 
 func (c *Context) openTextElement(text string, textConfig *TextElementConfig) {
-	if len(c.layoutElements) == cap(c.layoutElements)-1 || c.booleanWarnings.maxElementsExceeded {
-		c.booleanWarnings.maxElementsExceeded = true
+	if !c.hasElementCapacity() {
 		return
 	}
+
+	textMeasured := c.measureTextCached(text, textConfig)
+	textDimensions := textMeasured.unwrappedDimensions
+	if textConfig.LineHeight > 0 {
+		textDimensions.Y = float32(textConfig.LineHeight)
+	}
+	naturalHeight := textMeasured.unwrappedDimensions.Y
+	c.attachTextElement(TextElementData{
+		text:                text,
+		preferredDimensions: textMeasured.unwrappedDimensions,
+		baseline:            lineBaseline(naturalHeight, textDimensions.Y, naturalHeight-textMeasured.baselineOffset()),
+	}, textConfig, textDimensions, textMeasured.minWidth)
+}
+
+func (c *Context) openRichTextElement(spans []TextSpan, textConfig *TextElementConfig) {
+	if !c.hasElementCapacity() {
+		return
+	}
+
+	pieces := c.measureRichTextPieces(spans, textConfig)
+	textDimensions, minWidth, baseline := measureRichText(pieces, textConfig)
+	c.attachTextElement(TextElementData{
+		spans:               spans,
+		preferredDimensions: textDimensions,
+		baseline:            baseline,
+	}, textConfig, textDimensions, minWidth)
+}
+
+func (c *Context) attachTextElement(data TextElementData, textConfig *TextElementConfig, textDimensions Dimensions, minWidth float32) {
 	parentElement := c.getOpenLayoutElement()
 
 	c.layoutElements = append(c.layoutElements, LayoutElement{})
@@ -541,77 +1003,71 @@ func (c *Context) openTextElement(text string, textConfig *TextElementConfig) {
 	}
 
 	c.layoutElementChildrenBuffer = append(c.layoutElementChildrenBuffer, len(c.layoutElements)-1)
-	textMeasured := c.measureTextCached(text, textConfig)
-	elementId := hashNumber(uint32(len(parentElement.children)), parentElement.id)
+	elementId := hashNumber(uint32(parentElement.childrenCount), parentElement.id)
 	textElement.id = elementId.id
-	c.addHashMapItem(elementId, textElement)
 	c.layoutElementIdStrings = append(c.layoutElementIdStrings, elementId.stringId)
-	textDimensions := textMeasured.unwrappedDimensions
-	if textConfig.LineHeight > 0 {
-		textDimensions.Y = float32(textConfig.LineHeight)
-	}
+	c.addHashMapItem(elementId, textElement)
 	textElement.dimensions = textDimensions
-	textElement.minDimensions = MakeDimensions(textMeasured.minWidth, textDimensions.Y)
-	c.textElementData = append(c.textElementData, TextElementData{
-		text:                text,
-		preferredDimensions: textMeasured.unwrappedDimensions,
-		elementIndex:        len(c.layoutElements) - 1,
-	})
+	textElement.minDimensions = MakeDimensions(minWidth, textDimensions.Y)
+	data.elementIndex = len(c.layoutElements) - 1
+	c.textElementData = append(c.textElementData, data)
 	textElement.textElementData = &c.textElementData[len(c.textElementData)-1]
-	c.elementConfigs = append(c.elementConfigs, textConfig)
-	textElement.elementConfigs = c.elementConfigs[len(c.elementConfigs)-1 : len(c.elementConfigs)]
+	// The config is refused once the element limit is exceeded, the last stored config then belongs to another element
+	if c.storeTextElementConfig(*textConfig) != &default_TextElementConfig {
+		textElement.configMask = 1 << elementConfigText
+		textElement.configIndexes[elementConfigText] = int32(len(c.textElementConfigs) - 1)
+	}
 	textElement.layoutConfig = &default_LayoutConfig
 
-	parentElement.children = append(parentElement.children, 0)
+	parentElement.childrenCount++
 }
 
 func (c *Context) configureOpenElement(declaration *ElementDeclaration) {
-	if declaration.Layout.Sizing.Width == nil {
-		declaration.Layout.Sizing.Width = FIT(0)
+	if c.rightToLeft {
+		declaration.Layout.LayoutDirection = declaration.Layout.LayoutDirection.Mirror()
+		declaration.Layout.ChildAlignment.X = declaration.Layout.ChildAlignment.X.Mirror()
+		declaration.Layout.Padding.Left, declaration.Layout.Padding.Right = declaration.Layout.Padding.Right, declaration.Layout.Padding.Left
+		declaration.Layout.Margin.Left, declaration.Layout.Margin.Right = declaration.Layout.Margin.Right, declaration.Layout.Margin.Left
+		declaration.Floating.AttachPoints.Element = declaration.Floating.AttachPoints.Element.Mirror()
+		declaration.Floating.AttachPoints.Parent = declaration.Floating.AttachPoints.Parent.Mirror()
+		declaration.Floating.Offset.X = -declaration.Floating.Offset.X
+	}
+
+	if w, ok := c.percentOfMissingAncestor(declaration.Layout.Sizing.Width); ok {
+		declaration.Layout.Sizing.Width = w
 	}
-	if declaration.Layout.Sizing.Height == nil {
-		declaration.Layout.Sizing.Height = FIT(0)
+	if h, ok := c.percentOfMissingAncestor(declaration.Layout.Sizing.Height); ok {
+		declaration.Layout.Sizing.Height = h
 	}
 
 	openLayoutElement := c.getOpenLayoutElement()
 	openLayoutElement.layoutConfig = c.storeLayoutConfig(declaration.Layout)
 
 	checkSizing := func(sizing Sizing) bool {
-		switch w := sizing.Width.(type) {
-		case SizingAxisPercent:
-			if w.Percent > 1 {
-				return true
-			}
+		if sizing.Width.Type == SIZING_TYPE_PERCENT && sizing.Width.Percent > 1 {
+			return true
 		}
-		switch h := sizing.Height.(type) {
-		case SizingAxisPercent:
-			if h.Percent > 1 {
-				return true
-			}
+		if sizing.Height.Type == SIZING_TYPE_PERCENT && sizing.Height.Percent > 1 {
+			return true
 		}
 		return false
 	}
 
 	if checkSizing(declaration.Layout.Sizing) {
-		c.errorHandler.ErrorHandlerFunction(ErrorData{
-			ErrorType: ERROR_TYPE_PERCENTAGE_OVER_1,
-			ErrorText: "An element was configured with SIZING_PERCENT, but the provided percentage value was over 1.0. Clay expects a value between 0 and 1, i.e. 20% is 0.2.",
-			UserData:  c.errorHandler.UserData,
-		})
+		c.reportOpenElementError(ERROR_TYPE_PERCENTAGE_OVER_1, "An element was configured with SIZING_PERCENT, but the provided percentage value was over 1.0. Clay expects a value between 0 and 1, i.e. 20% is 0.2.")
 	}
 
-	openLayoutElement.elementConfigs = c.elementConfigs[len(c.elementConfigs):len(c.elementConfigs)]
 	sharedConfig := (*SharedElementConfig)(nil)
 	if c.renderTranslucent || declaration.BackgroundColor.A > 0 {
 		sharedConfig = c.storeSharedElementConfig(SharedElementConfig{backgroundColor: declaration.BackgroundColor})
-		c.attachElementConfig(sharedConfig)
+		c.attachElementConfig(elementConfigShared, len(c.sharedElementConfigs)-1)
 	}
 	if !declaration.CornerRadius.IsEmpty() {
 		if sharedConfig != nil {
 			sharedConfig.cornerRadius = declaration.CornerRadius
 		} else {
 			sharedConfig = c.storeSharedElementConfig(SharedElementConfig{cornerRadius: declaration.CornerRadius})
-			c.attachElementConfig(sharedConfig)
+			c.attachElementConfig(elementConfigShared, len(c.sharedElementConfigs)-1)
 		}
 	}
 	if declaration.UserData != nil {
@@ -619,15 +1075,16 @@ func (c *Context) configureOpenElement(declaration *ElementDeclaration) {
 			sharedConfig.userData = declaration.UserData
 		} else {
 			sharedConfig = c.storeSharedElementConfig(SharedElementConfig{userData: declaration.UserData})
-			c.attachElementConfig(sharedConfig)
+			c.attachElementConfig(elementConfigShared, len(c.sharedElementConfigs)-1)
 		}
 	}
 	if declaration.Image.ImageData != nil {
-		c.attachElementConfig(c.storeImageElementConfig(declaration.Image))
+		c.storeImageElementConfig(declaration.Image)
+		c.attachElementConfig(elementConfigImage, len(c.imageElementConfigs)-1)
 	}
 	if declaration.AspectRatio.AspectRatio > 0 {
-		c.attachElementConfig(c.storeAspectRatioElementConfig(declaration.AspectRatio))
-		c.aspectRatioElementIndexes = append(c.aspectRatioElementIndexes, len(c.layoutElements)-1)
+		c.storeAspectRatioElementConfig(declaration.AspectRatio)
+		c.attachElementConfig(elementConfigAspectRatio, len(c.aspectRatioElementConfigs)-1)
 	}
 
 	if declaration.Floating.AttachTo != ATTACH_TO_NONE {
@@ -635,7 +1092,7 @@ func (c *Context) configureOpenElement(declaration *ElementDeclaration) {
 		// This looks dodgy but because of the auto generated root element the depth of the tree will always be at least 2 here
 		hierarchicalParent := c.layoutElements[c.openLayoutElementStack[len(c.openLayoutElementStack)-2]]
 		if true /*hierarchicalParent.id != 0*/ {
-			clipElementId := 0
+			var clipElementId uint64
 			switch declaration.Floating.AttachTo {
 			case ATTACH_TO_PARENT:
 				// Attach to the element's direct hierarchical parent
@@ -646,18 +1103,13 @@ func (c *Context) configureOpenElement(declaration *ElementDeclaration) {
 			case ATTACH_TO_ELEMENT_WITH_ID:
 				parentItem, ok := c.layoutElementsHashMap[floatingConfig.ParentId]
 				if !ok {
-					c.errorHandler.ErrorHandlerFunction(ErrorData{
-						ErrorType: ERROR_TYPE_FLOATING_CONTAINER_PARENT_NOT_FOUND,
-						ErrorText: "A floating element was declared with a parentId, but no element with that ID was found.",
-						UserData:  c.errorHandler.UserData,
-					})
-				} else {
-					_ = parentItem
-					// TODO: fix
-					//clipElementId = c.layoutElementClipElementIds[(int32)(parentItem.layoutElement-c.layoutElements.internalArray))
+					c.reportOpenElementError(ERROR_TYPE_FLOATING_CONTAINER_PARENT_NOT_FOUND, "A floating element was declared with a parentId, but no element with that ID was found.")
+				} else if parentItem.generation == c.generation+1 {
+					// Parents declared in the previous layouts have no clip element yet, they can only be declared later in this one
+					clipElementId = c.layoutElementClipElementIds[parentItem.layoutElementIndex]
 				}
 			case ATTACH_TO_ROOT:
-				floatingConfig.ParentId = hashString("Clay__RootContainer").id
+				floatingConfig.ParentId = hashString("Clay__RootContainer", 0).id
 			}
 			if declaration.Floating.ClipTo == CLIP_TO_NONE {
 				clipElementId = 0
@@ -668,19 +1120,22 @@ func (c *Context) configureOpenElement(declaration *ElementDeclaration) {
 			c.layoutElementTreeRoots = append(c.layoutElementTreeRoots, LayoutElementTreeRoot{
 				layoutElementIndex: c.openLayoutElementStack[len(c.openLayoutElementStack)-1],
 				parentId:           floatingConfig.ParentId,
-				clipElementId:      uint32(clipElementId),
+				clipElementId:      clipElementId,
 				zIndex:             floatingConfig.ZIndex,
 			})
-			c.attachElementConfig(c.storeFloatingElementConfig(floatingConfig))
+			c.storeFloatingElementConfig(floatingConfig)
+			c.attachElementConfig(elementConfigFloating, len(c.floatingElementConfigs)-1)
 		}
 	}
 	if declaration.Custom.CustomData != nil {
-		c.attachElementConfig(c.storeCustomElementConfig(declaration.Custom))
+		c.storeCustomElementConfig(declaration.Custom)
+		c.attachElementConfig(elementConfigCustom, len(c.customElementConfigs)-1)
 	}
 
 	if declaration.Clip.Horizontal || declaration.Clip.Vertical {
-		c.attachElementConfig(c.storeClipElementConfig(declaration.Clip))
-		c.openClipElementStack = append(c.openClipElementStack, (int)(openLayoutElement.id))
+		c.storeClipElementConfig(declaration.Clip)
+		c.attachElementConfig(elementConfigClip, len(c.clipElementConfigs)-1)
+		c.openClipElementStack = append(c.openClipElementStack, openLayoutElement.id)
 		// Retrieve or create cached data to track scroll position across frames
 		scrollOffset := (*ScrollContainerDataInternal)(nil)
 		for i := range c.scrollContainerDatas {
@@ -700,14 +1155,14 @@ func (c *Context) configureOpenElement(declaration *ElementDeclaration) {
 			})
 			scrollOffset = &c.scrollContainerDatas[len(c.scrollContainerDatas)-1]
 		}
-		if c.externalScrollHandlingEnabled {
-			// TODO: fix
-			//scrollOffset.scrollPosition = queryScrollOffset(scrollOffset.elementId, c.queryScrollOffsetUserData)
+		if c.externalScrollHandlingEnabled && c.queryScrollOffset != nil {
+			scrollOffset.scrollPosition = c.queryScrollOffset(scrollOffset.elementId, c.queryScrollOffsetUserData)
 		}
 	}
 
 	if !declaration.Border.IsEmpty() {
-		c.attachElementConfig(c.storeBorderElementConfig(declaration.Border))
+		c.storeBorderElementConfig(declaration.Border)
+		c.attachElementConfig(elementConfigBorder, len(c.borderElementConfigs)-1)
 	}
 }
 
@@ -720,20 +1175,24 @@ func (c *Context) sizeContainersAlongAxis(axis Axis) {
 		bfsBuffer = append(bfsBuffer, root.layoutElementIndex)
 
 		// Size floating containers to their parents
-		if floatingElementConfig, ok := findElementConfigWithType[*FloatingElementConfig](rootElement); ok {
+		if floatingElementConfig, ok := c.floatingConfig(rootElement); ok {
 			if parentItem, ok := c.layoutElementsHashMap[floatingElementConfig.ParentId]; ok {
 				parentLayoutElement := parentItem.layoutElement
-				switch rootElement.layoutConfig.Sizing.Width.(type) {
-				case SizingAxisGrow:
+				switch w := rootElement.layoutConfig.Sizing.Width; w.Type {
+				case SIZING_TYPE_GROW:
 					rootElement.dimensions.X = parentLayoutElement.dimensions.X
-				case SizingAxisPercent:
-					rootElement.dimensions.X = parentLayoutElement.dimensions.X * rootElement.layoutConfig.Sizing.Width.(SizingAxisPercent).Percent
+				case SIZING_TYPE_PERCENT:
+					rootElement.dimensions.X = c.percentSize(w, AxisX, parentLayoutElement.dimensions.X)
+				case SIZING_TYPE_CALC:
+					rootElement.dimensions.X = c.calcSize(w, AxisX, parentLayoutElement.dimensions.X)
 				}
-				switch rootElement.layoutConfig.Sizing.Height.(type) {
-				case SizingAxisGrow:
+				switch h := rootElement.layoutConfig.Sizing.Height; h.Type {
+				case SIZING_TYPE_GROW:
 					rootElement.dimensions.Y = parentLayoutElement.dimensions.Y
-				case SizingAxisPercent:
-					rootElement.dimensions.Y = parentLayoutElement.dimensions.Y * rootElement.layoutConfig.Sizing.Height.(SizingAxisPercent).Percent
+				case SIZING_TYPE_PERCENT:
+					rootElement.dimensions.Y = c.percentSize(h, AxisY, parentLayoutElement.dimensions.Y)
+				case SIZING_TYPE_CALC:
+					rootElement.dimensions.Y = c.calcSize(h, AxisY, parentLayoutElement.dimensions.Y)
 				}
 			}
 		}
@@ -746,12 +1205,13 @@ func (c *Context) sizeContainersAlongAxis(axis Axis) {
 			    rootElement->dimensions.height = CLAY__MIN(CLAY__MAX(rootElement->dimensions.height, rootElement->layoutConfig->sizing.height.size.minMax.min), rootElement->layoutConfig->sizing.height.size.minMax.max);
 			}
 		*/
-		if mm, ok := rootElement.layoutConfig.Sizing.Width.(SizingAxisMinMax); ok {
-			rootElement.dimensions.X = min(max(rootElement.dimensions.X, mm.GetMinMax().Min), mm.GetMinMax().Max)
+		if w := rootElement.layoutConfig.Sizing.Width; w.fitsContent() {
+			rootElement.dimensions.X = min(max(rootElement.dimensions.X, w.GetMinMax().Min), w.GetMinMax().Max)
 		}
-		if mm, ok := rootElement.layoutConfig.Sizing.Height.(SizingAxisMinMax); ok {
-			rootElement.dimensions.Y = min(max(rootElement.dimensions.Y, mm.GetMinMax().Min), mm.GetMinMax().Max)
+		if h := rootElement.layoutConfig.Sizing.Height; h.fitsContent() {
+			rootElement.dimensions.Y = min(max(rootElement.dimensions.Y, h.GetMinMax().Min), h.GetMinMax().Max)
 		}
+		c.applyAspectRatio(rootElement, axis)
 
 		for bi := 0; bi < len(bfsBuffer); bi++ {
 			parentIndex := bfsBuffer[bi]
@@ -768,34 +1228,49 @@ func (c *Context) sizeContainersAlongAxis(axis Axis) {
 				parentPadding = float32(parent.layoutConfig.Padding.Top + parent.layoutConfig.Padding.Bottom)
 			}
 
+			if parent.grid != nil {
+				for _, childElementIndex := range parent.children {
+					child := &c.layoutElements[childElementIndex]
+					if !child.configMask.has(elementConfigText) && len(child.children) > 0 {
+						bfsBuffer = append(bfsBuffer, childElementIndex)
+					}
+				}
+				c.sizeGridAlongAxis(parent, axis)
+				continue
+			}
+
 			totalPaddingAndChildGaps := parentPadding
 			sizingAlongAxis := parentStyleConfig.LayoutDirection.IsAlongAxis(axis)
+			parentWraps := parentStyleConfig.Wrap == LAYOUT_WRAP && parentStyleConfig.LayoutDirection != STACK
 			c.openLayoutElementStack = c.openLayoutElementStack[:0]
 			resizableContainerBuffer = resizableContainerBuffer[:0]
 			parentChildGap := parentStyleConfig.ChildGap
 
 			for childOffset, childElementIndex := range parent.children {
 				child := &c.layoutElements[childElementIndex]
+				if axis == AxisX {
+					c.aspectRatioWidthFromParentHeight(child, parent)
+				}
 				childSizing := child.layoutConfig.Sizing.GetAxis(axis)
 				childSize := child.dimensions.Axis(axis)
 
-				if !elementHasConfig[*TextElementConfig](child) && len(child.children) > 0 {
+				if !child.configMask.has(elementConfigText) && len(child.children) > 0 {
 					bfsBuffer = append(bfsBuffer, childElementIndex)
 				}
 
 				if func() bool {
-					switch childSizing.(type) {
-					case SizingAxisFit:
+					switch childSizing.Type {
+					case SIZING_TYPE_FIT:
 						return true
-					case SizingAxisGrow:
+					case SIZING_TYPE_GROW:
 						return true
 					}
 
 					return false
 				}() {
 					if func() bool {
-						if tc, ok := findElementConfigWithType[*TextElementConfig](child); !ok || tc.WrapMode == TEXT_WRAP_WORDS {
-							if axis == AxisX || !elementHasConfig[*AspectRatioElementConfig](child) {
+						if tc, ok := c.textConfig(child); !ok || tc.WrapMode == TEXT_WRAP_WORDS {
+							if axis == AxisX || !child.configMask.has(elementConfigAspectRatio) {
 								return true
 							}
 						}
@@ -807,150 +1282,64 @@ func (c *Context) sizeContainersAlongAxis(axis Axis) {
 				}
 
 				if sizingAlongAxis {
-					switch childSizing.(type) {
-					case SizingAxisPercent:
-					case SizingAxisGrow:
+					switch childSizing.Type {
+					case SIZING_TYPE_PERCENT, SIZING_TYPE_CALC:
+					case SIZING_TYPE_GROW:
 						growContainerCount++
 						innerContentSize += childSize
 					default:
 						innerContentSize += childSize
 					}
+					innerContentSize += child.marginAxis(axis)
 					if childOffset > 0 {
 						innerContentSize += float32(parentChildGap) // For children after index 0, the childAxisOffset is the gap from the previous child
 						totalPaddingAndChildGaps += float32(parentChildGap)
 					}
 				} else {
-					innerContentSize = max(childSize, innerContentSize)
+					innerContentSize = max(childSize+child.marginAxis(axis), innerContentSize)
 				}
 			}
 
-			// Expand percentage containers to size
+			if sizingAlongAxis && parentWraps {
+				// Percentages of a wrapping element are relative to the length of a line, the child gaps depend on how the children wrap
+				totalPaddingAndChildGaps = parentPadding
+			}
+
+			// Expand percentage and calc containers to size
 			for _, childElementIndex := range parent.children {
 				child := &c.layoutElements[childElementIndex]
 				childSizing := child.layoutConfig.Sizing.GetAxis(axis)
 				childSize := child.dimensions.Axis(axis)
 
-				switch p := childSizing.(type) {
-				case SizingAxisPercent:
-					childSize = (parentSize - totalPaddingAndChildGaps) * p.Percent
-					child.dimensions = child.dimensions.SetAxis(axis, childSize)
-					if sizingAlongAxis {
-						innerContentSize += childSize
-					}
-					updateAspectRatioBox(child)
+				switch childSizing.Type {
+				case SIZING_TYPE_PERCENT:
+					childSize = c.percentSize(childSizing, axis, parentSize-totalPaddingAndChildGaps)
+				case SIZING_TYPE_CALC:
+					childSize = c.calcSize(childSizing, axis, parentSize-totalPaddingAndChildGaps)
+				default:
+					continue
+				}
+				child.dimensions = child.dimensions.SetAxis(axis, childSize)
+				if sizingAlongAxis {
+					innerContentSize += childSize
 				}
 			}
 
-			if sizingAlongAxis {
+			if sizingAlongAxis && parentWraps {
+				c.sizeWrapLinesAlongAxis(parent, axis, parentSize-parentPadding, resizableContainerBuffer)
+			} else if sizingAlongAxis {
 				sizeToDistribute := parentSize - parentPadding - innerContentSize
-				// The content is too large, compress the children as much as possible
-				if sizeToDistribute < 0 {
-					// If the parent clips content in this axis direction, don't compress children, just leave them alone
-					if clipElementConfig, ok := findElementConfigWithType[*ClipElementConfig](parent); ok {
-						if (axis == 0 && clipElementConfig.Horizontal) || (axis == 1 && clipElementConfig.Vertical) {
-							continue
-						}
-					}
-					// Scrolling containers preferentially compress before others
-					for sizeToDistribute < -CLAY__EPSILON && len(resizableContainerBuffer) > 0 {
-						var largest float32
-						var secondLargest float32
-						widthToAdd := sizeToDistribute
-						for _, childIndex := range resizableContainerBuffer {
-							child := c.layoutElements[childIndex]
-							childSize := child.dimensions.Axis(axis)
-							if floatEqual(childSize, largest) {
-								continue
-							}
-							if childSize > largest {
-								secondLargest = largest
-								largest = childSize
-							}
-							if childSize < largest {
-								secondLargest = max(secondLargest, childSize)
-								widthToAdd = secondLargest - largest
-							}
-						}
-
-						widthToAdd = max(widthToAdd, sizeToDistribute/float32(len(resizableContainerBuffer)))
-
-						for childIndex := range resizableContainerBuffer {
-							child := &c.layoutElements[resizableContainerBuffer[childIndex]]
-							childSize := child.dimensions.Axis(axis)
-							minSize := child.minDimensions.Axis(axis)
-							previousWidth := childSize
-							if floatEqual(childSize, largest) {
-								childSize += widthToAdd
-								if childSize <= minSize {
-									childSize = minSize
-									resizableContainerBuffer, _ = slicesex_RemoveSwapback(resizableContainerBuffer, childIndex)
-									childIndex--
-								}
-								child.dimensions = child.dimensions.SetAxis(axis, childSize)
-								sizeToDistribute -= (childSize - previousWidth)
-							}
-						}
-					}
-					// The content is too small, allow SIZING_GROW containers to expand
-				} else if sizeToDistribute > 0 && growContainerCount > 0 {
-					for ci := 0; ci < len(resizableContainerBuffer); ci++ {
-						child := &c.layoutElements[resizableContainerBuffer[ci]]
-						childSizing := child.layoutConfig.Sizing.GetAxis(axis)
-						switch childSizing.(type) {
-						case SizingAxisGrow:
-						default:
-							resizableContainerBuffer, _ = slicesex_RemoveSwapback(resizableContainerBuffer, ci)
-							ci--
-						}
-					}
-
-					for sizeToDistribute > CLAY__EPSILON && len(resizableContainerBuffer) > 0 {
-						smallest := float32(math.MaxFloat32)
-						secondSmallest := float32(math.MaxFloat32)
-						widthToAdd := sizeToDistribute
-						for _, rcb := range resizableContainerBuffer {
-							child := c.layoutElements[rcb]
-							childSize := child.dimensions.Axis(axis)
-							if floatEqual(childSize, smallest) {
-								continue
-							}
-							if childSize < smallest {
-								secondSmallest = smallest
-								smallest = childSize
-							}
-							if childSize > smallest {
-								secondSmallest = min(secondSmallest, childSize)
-								widthToAdd = secondSmallest - smallest
-							}
-						}
-
-						widthToAdd = min(widthToAdd, sizeToDistribute/float32(len(resizableContainerBuffer)))
-
-						for childIndex := range resizableContainerBuffer {
-							child := &c.layoutElements[resizableContainerBuffer[childIndex]]
-							childSize := child.dimensions.Axis(axis)
-							childSizing := child.layoutConfig.Sizing.GetAxis(axis)
-							var maxSize float32
-							switch mm := childSizing.(type) {
-							case SizingAxisMinMax:
-								maxSize = mm.GetMinMax().Max
-							}
-							previousWidth := childSize
-							if floatEqual(childSize, smallest) {
-								childSize += widthToAdd
-								if childSize >= maxSize {
-									childSize = maxSize
-									resizableContainerBuffer, _ = slicesex_RemoveSwapback(resizableContainerBuffer, childIndex)
-									childIndex--
-								}
-								child.dimensions = child.dimensions.SetAxis(axis, childSize)
-								sizeToDistribute -= (childSize - previousWidth)
-							}
-						}
-					}
-
+				// If the parent clips content in this axis direction, don't compress children, just leave them alone
+				if sizeToDistribute >= 0 || !c.elementClipsAxis(parent, axis) {
+					c.distributeSizeAlongAxis(axis, sizeToDistribute, resizableContainerBuffer, growContainerCount)
 				}
 				// Sizing along the non layout axis ("off axis")
+			} else if parentWraps {
+				// Children of a wrapping element grow to the size of their line instead, which is only known once they are broken into lines.
+				// Rows are broken before heights are sized, columns are sized across right after they are broken.
+				if parent.wrapLines != nil {
+					c.sizeWrapLinesAcrossAxis(parent, axis)
+				}
 			} else {
 				for _, rcb := range resizableContainerBuffer {
 					child := &c.layoutElements[rcb]
@@ -960,33 +1349,650 @@ func (c *Context) sizeContainersAlongAxis(axis Axis) {
 
 					// If we're laying out the children of a scroll panel, grow containers expand to the size of the inner content, not the outer container
 					maxSize := parentSize - parentPadding
-					if clipElementConfig, ok := findElementConfigWithType[*ClipElementConfig](parent); ok {
+					if clipElementConfig, ok := c.clipConfig(parent); ok {
 						if (axis == 0 && clipElementConfig.Horizontal) || (axis == 1 && clipElementConfig.Vertical) {
 							maxSize = max(maxSize, innerContentSize)
 						}
 					}
-					switch s := childSizing.(type) {
-					case SizingAxisGrow:
-						childSize = min(maxSize, s.MinMax.Max)
+					// The margin of the child takes up part of the space it can fill
+					maxSize -= child.marginAxis(axis)
+					switch childSizing.Type {
+					case SIZING_TYPE_GROW:
+						childSize = min(maxSize, c.sizingMinMax(child, axis).Max)
 					}
 					child.dimensions = child.dimensions.SetAxis(axis, max(minSize, min(childSize, maxSize)))
 				}
 			}
+
+			for _, childElementIndex := range parent.children {
+				c.applyAspectRatio(&c.layoutElements[childElementIndex], axis)
+			}
 		}
 	}
 }
 
-func (c *Context) elementIsOffscreen(boundingBox BoundingBox) bool {
-	if c.disableCulling {
-		return false
+// Sizes a FIT width with an aspect ratio to the PERCENT or CALC height of the element while widths are sized, when the
+// parent has a FIXED height that height is taken of. The siblings and the children of the element are sized around it.
+func (c *Context) aspectRatioWidthFromParentHeight(element *LayoutElement, parent *LayoutElement) {
+	config, ok := c.aspectRatioConfig(element)
+	if !ok || config.AspectRatio == 0 {
+		return
+	}
+	if element.layoutConfig.Sizing.Width.Type != SIZING_TYPE_FIT {
+		return
+	}
+	if parent.layoutConfig.Sizing.Height.Type != SIZING_TYPE_FIXED || parent.grid != nil {
+		return
+	}
+	parentConfig := parent.layoutConfig
+	parentHeight := parent.dimensions.Y - float32(parentConfig.Padding.Top+parentConfig.Padding.Bottom)
+	if parentConfig.LayoutDirection == TOP_TO_BOTTOM && parentConfig.Wrap != LAYOUT_WRAP {
+		parentHeight -= float32(max(len(parent.children)-1, 0) * int(parentConfig.ChildGap))
+	}
+	var height float32
+	switch h := element.layoutConfig.Sizing.Height; h.Type {
+	case SIZING_TYPE_PERCENT:
+		height = c.percentSize(h, AxisY, parentHeight)
+	case SIZING_TYPE_CALC:
+		height = c.calcSize(h, AxisY, parentHeight)
+	default:
+		return
+	}
+	mm := c.sizingMinMax(element, AxisX)
+	element.dimensions.X = min(max(height*config.AspectRatio, mm.Min), mm.Max)
+	element.minDimensions.X = element.dimensions.X
+}
+
+// Sizes an element with an aspect ratio along the other axis once its size along the axis is final, within the min and max
+// of the other axis. Heights follow widths while widths are sized, so that FIT parents and the children are sized around them.
+// Widths only follow heights while heights are sized when the height was sized to something else than the width implies,
+// such as a percentage of the parent height or the min and max of the height.
+func (c *Context) applyAspectRatio(element *LayoutElement, axis Axis) {
+	config, ok := c.aspectRatioConfig(element)
+	if !ok || config.AspectRatio == 0 {
+		return
+	}
+	if axis == AxisX {
+		mm := c.sizingMinMax(element, AxisY)
+		element.dimensions.Y = min(max(element.dimensions.X/config.AspectRatio, mm.Min), mm.Max)
+		return
+	}
+	if width := element.dimensions.Y * config.AspectRatio; !floatEqual(width, element.dimensions.X) {
+		mm := c.sizingMinMax(element, AxisX)
+		element.dimensions.X = min(max(width, mm.Min), mm.Max)
+	}
+}
+
+// Returns the size of a PERCENT element along the axis, parentSize is the size a percentage of the parent is taken of.
+func (c *Context) percentSize(sizing SizingAxis, axis Axis, parentSize float32) float32 {
+	size := parentSize
+	switch sizing.Of {
+	case PERCENT_OF_VIEWPORT_WIDTH, PERCENT_OF_VIEWPORT_HEIGHT:
+		return c.viewportPercentSize(sizing, axis)
+	case PERCENT_OF_ELEMENT:
+		if item, ok := c.layoutElementsHashMap[sizing.Element.id]; ok && item.generation == c.generation+1 {
+			element := item.layoutElement
+			padding := element.layoutConfig.Padding
+			if axis == AxisX {
+				size = element.dimensions.X - float32(padding.Left+padding.Right)
+			} else {
+				size = element.dimensions.Y - float32(padding.Top+padding.Bottom)
+			}
+		}
+	}
+	return sizing.clamp(size * sizing.Percent)
+}
+
+// Returns the size of a PERCENT element relative to the viewport along the axis, or 0 for other references.
+func (c *Context) viewportPercentSize(sizing SizingAxis, axis Axis) float32 {
+	viewport := c.layoutBoundingBox.Size
+	if c.debugModeEnabled {
+		viewport.X -= float32(debugViewWidth)
+	}
+	switch sizing.Of {
+	case PERCENT_OF_VIEWPORT_WIDTH:
+		return sizing.clamp(viewport.X * sizing.Percent)
+	case PERCENT_OF_VIEWPORT_HEIGHT:
+		return sizing.clamp(viewport.Y * sizing.Percent)
+	}
+	return 0
+}
+
+// Returns the size of a CALC element along the axis, parentSize is the size percentages of the parent are taken of.
+func (c *Context) calcSize(sizing SizingAxis, axis Axis, parentSize float32) float32 {
+	var size float32
+	for i, term := range sizing.Terms {
+		var termSize float32
+		switch term.Type {
+		case SIZING_TYPE_FIXED:
+			termSize = term.MinMax.Min
+		case SIZING_TYPE_PERCENT:
+			termSize = c.percentSize(term, axis, parentSize)
+		case SIZING_TYPE_CALC:
+			termSize = c.calcSize(term, axis, parentSize)
+		}
+		switch {
+		case i == 0:
+			size = termSize
+		case sizing.Operation == CALC_OP_MIN:
+			size = min(size, termSize)
+		case sizing.Operation == CALC_OP_MAX:
+			size = max(size, termSize)
+		default:
+			size += termSize
+		}
+	}
+	return max(size, 0)
+}
+
+// Returns the size of a CALC element that only depends on the viewport along the axis, or 0 if it depends on the layout.
+func (c *Context) viewportCalcSize(sizing SizingAxis, axis Axis) float32 {
+	if !sizing.independentOfLayout() {
+		return 0
+	}
+	return c.calcSize(sizing, axis, 0)
+}
+
+// Returns true if none of the terms of the expression are percentages of the parent or an ancestor.
+func (s SizingAxis) independentOfLayout() bool {
+	for _, term := range s.Terms {
+		switch term.Type {
+		case SIZING_TYPE_PERCENT:
+			if term.Of == PERCENT_OF_PARENT || term.Of == PERCENT_OF_ELEMENT {
+				return false
+			}
+		case SIZING_TYPE_CALC:
+			if !term.independentOfLayout() {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Reports sizing that is a percentage of an element which isn't an ancestor of the open element,
+// and returns the sizing as a percentage of the parent to use instead.
+func (c *Context) percentOfMissingAncestor(sizing SizingAxis) (SizingAxis, bool) {
+	if sizing.Type != SIZING_TYPE_PERCENT || sizing.Of != PERCENT_OF_ELEMENT {
+		return sizing, false
+	}
+	for _, ancestor := range c.openLayoutElementStack[:len(c.openLayoutElementStack)-1] {
+		if c.layoutElements[ancestor].id == sizing.Element.id {
+			return sizing, false
+		}
+	}
+	c.reportOpenElementError(ERROR_TYPE_PERCENT_REFERENCE_NOT_FOUND, "An element was configured with PERCENT_OF_ELEMENT sizing, but the element with the provided id isn't one of its ancestors. The percentage is taken of the parent instead.")
+	sizing.Of = PERCENT_OF_PARENT
+	return sizing, true
+}
+
+// Returns true if the element clips its children along the axis, such elements don't compress their children.
+func (c *Context) elementClipsAxis(element *LayoutElement, axis Axis) bool {
+	if clipElementConfig, ok := c.clipConfig(element); ok {
+		return (axis == AxisX && clipElementConfig.Horizontal) || (axis == AxisY && clipElementConfig.Vertical)
+	}
+	return false
+}
+
+// Breaks the children of a wrapping element into lines no longer than lineLength along the layout axis,
+// then compresses and grows the children of every line like the children of an element that doesn't wrap.
+// Children are broken into lines by the size they have before growing, a child longer than a line is alone on its line.
+func (c *Context) sizeWrapLinesAlongAxis(parent *LayoutElement, axis Axis, lineLength float32, resizableContainerBuffer []int) {
+	if len(parent.children) == 0 {
+		return
+	}
+
+	childGap := float32(parent.layoutConfig.ChildGap)
+	linesStart := len(c.wrapLines)
+	line := wrapLine{}
+	var lineSize float32
+	for i, childElementIndex := range parent.children {
+		childSize := c.layoutElements[childElementIndex].outerSize(axis)
+		if i > line.start {
+			if lineSize+childGap+childSize > lineLength+CLAY__EPSILON {
+				line.end = i
+				c.wrapLines = append(c.wrapLines, line)
+				line = wrapLine{start: i}
+				lineSize = 0
+			} else {
+				lineSize += childGap
+			}
+		}
+		lineSize += childSize
+	}
+	line.end = len(parent.children)
+	c.wrapLines = append(c.wrapLines, line)
+	parent.wrapLines = c.wrapLines[linesStart:len(c.wrapLines):len(c.wrapLines)]
+
+	// The resizable children are in child order, so the ones of a line directly follow the ones of the previous line
+	resizableIndex := 0
+	for _, line := range parent.wrapLines {
+		resizableStart := resizableIndex
+		var growContainerCount int32
+		for _, childElementIndex := range parent.children[line.start:line.end] {
+			if resizableIndex < len(resizableContainerBuffer) && resizableContainerBuffer[resizableIndex] == childElementIndex {
+				resizableIndex++
+			}
+			if c.layoutElements[childElementIndex].layoutConfig.Sizing.GetAxis(axis).Type == SIZING_TYPE_GROW {
+				growContainerCount++
+			}
+		}
+		sizeToDistribute := lineLength - c.wrapLineSize(parent, line, axis)
+		if sizeToDistribute < 0 && c.elementClipsAxis(parent, axis) {
+			continue
+		}
+		c.distributeSizeAlongAxis(axis, sizeToDistribute, resizableContainerBuffer[resizableStart:resizableIndex], growContainerCount)
+	}
+
+	if axis == AxisY {
+		// Widths were sized before the children were broken into columns, size the children and the element to the columns now
+		crossSize := c.sizeWrapLinesAcrossAxis(parent, AxisX)
+		if fit := parent.layoutConfig.Sizing.Width; fit.Type == SIZING_TYPE_FIT {
+			contentWidth := crossSize + float32(parent.layoutConfig.Padding.Left+parent.layoutConfig.Padding.Right)
+			width := min(max(contentWidth, fit.GetMinMax().Min), fit.GetMinMax().Max)
+			if width > parent.dimensions.X+CLAY__EPSILON {
+				c.wrapColumnsWidened = true
+			}
+			parent.dimensions.X = width
+		}
+	}
+}
+
+// Returns the width of the columns the children of a wrapping TOP_TO_BOTTOM element are broken into when a column
+// is columnHeight high, including line gaps but not padding. Breaks the columns the same way as sizeWrapLinesAlongAxis.
+func (c *Context) wrapColumnsWidth(element *LayoutElement, columnHeight float32) float32 {
+	layoutConfig := element.layoutConfig
+	childGap := float32(layoutConfig.ChildGap)
+	var width, columnWidth, columnSize float32
+	for i, childElementIndex := range element.children {
+		child := &c.layoutElements[childElementIndex]
+		childHeight := child.outerSize(AxisY)
+		if i > 0 {
+			if columnSize+childGap+childHeight > columnHeight+CLAY__EPSILON {
+				width += columnWidth + float32(layoutConfig.LineGap)
+				columnWidth = 0
+				columnSize = 0
+			} else {
+				columnSize += childGap
+			}
+		}
+		columnSize += childHeight
+		columnWidth = max(columnWidth, child.outerSize(AxisX))
+	}
+	return width + columnWidth
+}
+
+// Sets the size of every line of a wrapping element along the non layout axis to the size of its largest child, and
+// grows the GROW children of the line to that size. Returns the size of all lines, including line gaps but not padding.
+func (c *Context) sizeWrapLinesAcrossAxis(parent *LayoutElement, axis Axis) float32 {
+	contentSize := c.measureWrapLinesAcrossAxis(parent, axis)
+	for _, line := range parent.wrapLines {
+		for _, childElementIndex := range parent.children[line.start:line.end] {
+			child := &c.layoutElements[childElementIndex]
+			if axis == AxisY && child.configMask.has(elementConfigAspectRatio) {
+				continue
+			}
+			if child.layoutConfig.Sizing.GetAxis(axis).Type == SIZING_TYPE_GROW {
+				child.dimensions = child.dimensions.SetAxis(axis, max(child.minDimensions.Axis(axis), min(line.crossSize-child.marginAxis(axis), c.sizingMinMax(child, axis).Max)))
+			}
+		}
+	}
+	return contentSize
+}
+
+// Sets the size of every line of a wrapping element along the non layout axis to the size of its largest child.
+// Returns the size of all lines, including line gaps but not padding.
+func (c *Context) measureWrapLinesAcrossAxis(parent *LayoutElement, axis Axis) float32 {
+	layoutConfig := parent.layoutConfig
+	var contentSize float32
+	for li := range parent.wrapLines {
+		line := &parent.wrapLines[li]
+		children := parent.children[line.start:line.end]
+		line.crossSize = 0
+		if axis == AxisY && layoutConfig.ChildAlignment.Y == ALIGN_Y_BASELINE {
+			above, below := c.childrenBaselineExtents(children)
+			line.crossSize = above + below
+		}
+		for _, childElementIndex := range children {
+			line.crossSize = max(line.crossSize, c.layoutElements[childElementIndex].outerSize(axis))
+		}
+		if li > 0 {
+			contentSize += float32(layoutConfig.LineGap)
+		}
+		contentSize += line.crossSize
+	}
+	return contentSize
+}
+
+// Returns the size of a line of a wrapping element along the layout axis, including child gaps.
+func (c *Context) wrapLineSize(element *LayoutElement, line wrapLine, axis Axis) float32 {
+	var lineSize float32
+	for _, childElementIndex := range element.children[line.start:line.end] {
+		lineSize += c.layoutElements[childElementIndex].outerSize(axis)
+	}
+	return lineSize + float32((line.end-line.start-1)*int(element.layoutConfig.ChildGap))
+}
+
+// Returns the size of the lines of a wrapping element, including child and line gaps but not padding.
+func (c *Context) wrapLinesContentSize(element *LayoutElement) Dimensions {
+	axis := AxisY
+	if element.layoutConfig.LayoutDirection.IsAlongAxis(AxisX) {
+		axis = AxisX
+	}
+	var alongSize, crossSize float32
+	for i, line := range element.wrapLines {
+		alongSize = max(alongSize, c.wrapLineSize(element, line, axis))
+		if i > 0 {
+			crossSize += float32(element.layoutConfig.LineGap)
+		}
+		crossSize += line.crossSize
+	}
+	if axis == AxisX {
+		return MakeDimensions(alongSize, crossSize)
+	}
+	return MakeDimensions(crossSize, alongSize)
+}
+
+// Returns the offset of the first line of a wrapping element along the non layout axis, aligned by LineAlignment.
+func (c *Context) wrapLinesStartOffset(element *LayoutElement) float32 {
+	layoutConfig := element.layoutConfig
+	contentSize := c.wrapLinesContentSize(element)
+	var offset, extraSpace float32
+	if layoutConfig.LayoutDirection.IsAlongAxis(AxisX) {
+		offset = float32(layoutConfig.Padding.Top)
+		extraSpace = element.dimensions.Y - float32(layoutConfig.Padding.Top+layoutConfig.Padding.Bottom) - contentSize.Y
+	} else {
+		offset = float32(layoutConfig.Padding.Left)
+		extraSpace = element.dimensions.X - float32(layoutConfig.Padding.Left+layoutConfig.Padding.Right) - contentSize.X
+	}
+	switch layoutConfig.LineAlignment {
+	case ALIGN_LINES_START:
+		extraSpace = 0
+	case ALIGN_LINES_CENTER:
+		extraSpace /= 2
+	}
+	return offset + max(0, extraSpace)
+}
+
+// Returns the offset of the first child of a line of a wrapping element along the layout axis and the gap between its children,
+// spread by ChildDistribution or aligned by ChildAlignment. For RIGHT_TO_LEFT it is the offset of the right edge of the first child.
+func (c *Context) wrapLineStartOffset(element *LayoutElement, line wrapLine) (float32, float32) {
+	layoutConfig := element.layoutConfig
+	if layoutConfig.LayoutDirection.IsAlongAxis(AxisX) {
+		lineSize := c.wrapLineSize(element, line, AxisX)
+		extraSpace := element.dimensions.X - float32(layoutConfig.Padding.Left+layoutConfig.Padding.Right) - lineSize
+		offset, gap := childOffsetsAlongAxis(layoutConfig, AxisX, extraSpace, line.end-line.start)
+		offset += float32(layoutConfig.Padding.Left)
+		if layoutConfig.LayoutDirection == RIGHT_TO_LEFT {
+			// Children are placed starting from the right edge of the line
+			offset += lineSize + (gap-float32(layoutConfig.ChildGap))*float32(line.end-line.start-1)
+		}
+		return offset, gap
+	}
+
+	lineSize := c.wrapLineSize(element, line, AxisY)
+	extraSpace := element.dimensions.Y - float32(layoutConfig.Padding.Top+layoutConfig.Padding.Bottom) - lineSize
+	offset, gap := childOffsetsAlongAxis(layoutConfig, AxisY, extraSpace, line.end-line.start)
+	return float32(layoutConfig.Padding.Top) + offset, gap
+}
+
+// Returns the offset of the first child from the start of the content box along the layout axis and the gap between children
+// of an element that doesn't wrap.
+func (c *Context) childDistributionAlongAxis(element *LayoutElement) (float32, float32) {
+	layoutConfig := element.layoutConfig
+	axis := AxisY
+	if layoutConfig.LayoutDirection.IsAlongAxis(AxisX) {
+		axis = AxisX
+	}
+	var contentSize float32
+	for _, child := range element.children {
+		contentSize += c.layoutElements[child].outerSize(axis)
+	}
+	contentSize += float32(max(len(element.children)-1, 0) * int(layoutConfig.ChildGap))
+	padding := layoutConfig.Padding.Top + layoutConfig.Padding.Bottom
+	if axis == AxisX {
+		padding = layoutConfig.Padding.Left + layoutConfig.Padding.Right
+	}
+	return childOffsetsAlongAxis(layoutConfig, axis, element.dimensions.Axis(axis)-float32(padding)-contentSize, len(element.children))
+}
+
+// Returns the offset of the first child from the start of the content box along the layout axis and the gap between children,
+// when extraSpace is left over along the axis. The space is spread by ChildDistribution, or taken before the children by ChildAlignment.
+func childOffsetsAlongAxis(layoutConfig *LayoutConfig, axis Axis, extraSpace float32, childCount int) (float32, float32) {
+	gap := float32(layoutConfig.ChildGap)
+	if extraSpace <= 0 || childCount == 0 {
+		return 0, gap
+	}
+
+	switch layoutConfig.ChildDistribution {
+	case DISTRIBUTE_SPACE_BETWEEN:
+		if childCount > 1 {
+			return 0, gap + extraSpace/float32(childCount-1)
+		}
+		return 0, gap
+	case DISTRIBUTE_SPACE_AROUND:
+		space := extraSpace / float32(childCount)
+		return space / 2, gap + space
+	case DISTRIBUTE_SPACE_EVENLY:
+		space := extraSpace / float32(childCount+1)
+		return space, gap + space
+	}
+
+	if axis == AxisX {
+		switch layoutConfig.ChildAlignment.X {
+		case ALIGN_X_LEFT:
+			return 0, gap
+		case ALIGN_X_CENTER:
+			return extraSpace / 2, gap
+		}
+		return extraSpace, gap
+	}
+	switch layoutConfig.ChildAlignment.Y {
+	case ALIGN_Y_TOP, ALIGN_Y_BASELINE:
+		return 0, gap
+	case ALIGN_Y_CENTER:
+		return extraSpace / 2, gap
+	}
+	return extraSpace, gap
+}
+
+// Compresses the resizable children as much as possible when sizeToDistribute is negative, largest first, or in proportion
+// to their shrink weights times their sizes when any of them has a shrink weight.
+// Otherwise expands the GROW children among them into the remaining space, smallest first.
+func (c *Context) distributeSizeAlongAxis(axis Axis, sizeToDistribute float32, resizableContainerBuffer []int, growContainerCount int32) {
+	// The content is too large, compress the children as much as possible
+	if sizeToDistribute < 0 && c.hasShrinkWeight(axis, resizableContainerBuffer) {
+		// Like flex-shrink, every child gives up a share of the overflow proportional to its shrink weight times its size.
+		// Children that would be compressed below their minimum size keep it, and the rest of the overflow is shared again.
+		for sizeToDistribute < -CLAY__EPSILON && len(resizableContainerBuffer) > 0 {
+			var scaledShrinkTotal float32
+			for _, childIndex := range resizableContainerBuffer {
+				child := &c.layoutElements[childIndex]
+				scaledShrinkTotal += sizingShrinkWeight(child.layoutConfig.Sizing.GetAxis(axis)) * child.dimensions.Axis(axis)
+			}
+			if scaledShrinkTotal <= 0 {
+				break
+			}
+			clamped := false
+			for ci := 0; ci < len(resizableContainerBuffer); ci++ {
+				child := &c.layoutElements[resizableContainerBuffer[ci]]
+				childSize := child.dimensions.Axis(axis)
+				shrunk := childSize + sizeToDistribute*sizingShrinkWeight(child.layoutConfig.Sizing.GetAxis(axis))*childSize/scaledShrinkTotal
+				if minSize := child.minDimensions.Axis(axis); shrunk <= minSize {
+					child.dimensions = child.dimensions.SetAxis(axis, minSize)
+					sizeToDistribute -= minSize - childSize
+					resizableContainerBuffer, _ = slicesex_RemoveSwapback(resizableContainerBuffer, ci)
+					ci--
+					clamped = true
+				}
+			}
+			if clamped {
+				continue
+			}
+			for _, childIndex := range resizableContainerBuffer {
+				child := &c.layoutElements[childIndex]
+				childSize := child.dimensions.Axis(axis)
+				childSize += sizeToDistribute * sizingShrinkWeight(child.layoutConfig.Sizing.GetAxis(axis)) * childSize / scaledShrinkTotal
+				child.dimensions = child.dimensions.SetAxis(axis, childSize)
+			}
+			break
+		}
+	} else if sizeToDistribute < 0 {
+		// Children are compressed from the largest size down, until all of them have the same size
+		for sizeToDistribute < -CLAY__EPSILON && len(resizableContainerBuffer) > 0 {
+			var largest float32
+			var secondLargest float32
+			var largestCount float32
+			for _, childIndex := range resizableContainerBuffer {
+				child := c.layoutElements[childIndex]
+				childSize := child.dimensions.Axis(axis)
+				if floatEqual(childSize, largest) {
+					continue
+				}
+				if childSize > largest {
+					secondLargest = largest
+					largest = childSize
+				}
+				if childSize < largest {
+					secondLargest = max(secondLargest, childSize)
+				}
+			}
+			// The largest children are compressed down to the second largest size, wherever they are in the buffer
+			widthToAdd := secondLargest - largest
+
+			for _, childIndex := range resizableContainerBuffer {
+				if floatEqual(c.layoutElements[childIndex].dimensions.Axis(axis), largest) {
+					largestCount++
+				}
+			}
+
+			widthToAdd = max(widthToAdd, sizeToDistribute/largestCount)
+
+			for ci := 0; ci < len(resizableContainerBuffer); ci++ {
+				child := &c.layoutElements[resizableContainerBuffer[ci]]
+				childSize := child.dimensions.Axis(axis)
+				minSize := child.minDimensions.Axis(axis)
+				previousWidth := childSize
+				if floatEqual(childSize, largest) {
+					childSize += widthToAdd
+					if childSize <= minSize {
+						childSize = minSize
+						resizableContainerBuffer, _ = slicesex_RemoveSwapback(resizableContainerBuffer, ci)
+						ci--
+					}
+					child.dimensions = child.dimensions.SetAxis(axis, childSize)
+					sizeToDistribute -= (childSize - previousWidth)
+				}
+			}
+		}
+		// The content is too small, allow SIZING_GROW containers to expand
+	} else if sizeToDistribute > 0 && growContainerCount > 0 {
+		for ci := 0; ci < len(resizableContainerBuffer); ci++ {
+			child := &c.layoutElements[resizableContainerBuffer[ci]]
+			childSizing := child.layoutConfig.Sizing.GetAxis(axis)
+			switch childSizing.Type {
+			case SIZING_TYPE_GROW:
+			default:
+				resizableContainerBuffer, _ = slicesex_RemoveSwapback(resizableContainerBuffer, ci)
+				ci--
+			}
+		}
+
+		// Children grow from the smallest size per weight up, until all of them have the same size per weight
+		for sizeToDistribute > CLAY__EPSILON && len(resizableContainerBuffer) > 0 {
+			smallest := float32(math.MaxFloat32)
+			secondSmallest := float32(math.MaxFloat32)
+			var smallestWeight float32
+			for _, rcb := range resizableContainerBuffer {
+				child := c.layoutElements[rcb]
+				childRatio := child.dimensions.Axis(axis) / sizingGrowWeight(child.layoutConfig.Sizing.GetAxis(axis))
+				if floatEqual(childRatio, smallest) {
+					continue
+				}
+				if childRatio < smallest {
+					secondSmallest = smallest
+					smallest = childRatio
+				}
+				if childRatio > smallest {
+					secondSmallest = min(secondSmallest, childRatio)
+				}
+			}
+
+			for _, rcb := range resizableContainerBuffer {
+				child := c.layoutElements[rcb]
+				weight := sizingGrowWeight(child.layoutConfig.Sizing.GetAxis(axis))
+				if floatEqual(child.dimensions.Axis(axis)/weight, smallest) {
+					smallestWeight += weight
+				}
+			}
+
+			// The smallest children grow up to the second smallest size per weight, wherever they are in the buffer
+			ratioToAdd := min(secondSmallest-smallest, sizeToDistribute/smallestWeight)
+
+			for ci := 0; ci < len(resizableContainerBuffer); ci++ {
+				child := &c.layoutElements[resizableContainerBuffer[ci]]
+				childSizing := child.layoutConfig.Sizing.GetAxis(axis)
+				weight := sizingGrowWeight(childSizing)
+				childSize := child.dimensions.Axis(axis)
+				maxSize := c.sizingMinMax(child, axis).Max
+				previousWidth := childSize
+				if floatEqual(childSize/weight, smallest) {
+					childSize += ratioToAdd * weight
+					if childSize >= maxSize {
+						childSize = maxSize
+						resizableContainerBuffer, _ = slicesex_RemoveSwapback(resizableContainerBuffer, ci)
+						ci--
+					}
+					child.dimensions = child.dimensions.SetAxis(axis, childSize)
+					sizeToDistribute -= (childSize - previousWidth)
+				}
+			}
+		}
+	}
+}
+
+// Returns the weight an element grows with relative to its GROW siblings.
+func sizingGrowWeight(sizing SizingAxis) float32 {
+	if sizing.Type == SIZING_TYPE_GROW && sizing.Weight > 0 {
+		return sizing.Weight
+	}
+	return 1
+}
+
+// Returns the weight an element is compressed with relative to its siblings.
+func sizingShrinkWeight(sizing SizingAxis) float32 {
+	switch sizing.Type {
+	case SIZING_TYPE_FIT, SIZING_TYPE_GROW:
+		if sizing.Shrink > 0 {
+			return sizing.Shrink
+		}
+	}
+	return 1
+}
+
+// Returns true if any of the elements has a shrink weight along the axis.
+func (c *Context) hasShrinkWeight(axis Axis, elementIndexes []int) bool {
+	for _, elementIndex := range elementIndexes {
+		switch s := c.layoutElements[elementIndex].layoutConfig.Sizing.GetAxis(axis); s.Type {
+		case SIZING_TYPE_FIT, SIZING_TYPE_GROW:
+			if s.Shrink > 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (c *Context) elementIsOffscreen(boundingBox BoundingBox) bool {
+	if c.disableCulling {
+		return false
 	}
 
 	return !c.layoutBoundingBox.OverlappedBy(boundingBox)
 }
 
 func (c *Context) calculateFinalLayout() {
-	c.treeNodeVisited = make([]bool, len(c.layoutElements))
-	defer func() { c.treeNodeVisited = nil }()
+	c.resetTreeNodeVisited()
 
 	// Calculate sizing along the X axis
 	c.sizeContainersAlongAxis(AxisX)
@@ -994,17 +2000,17 @@ func (c *Context) calculateFinalLayout() {
 	// Wrap text
 	c.wrapText()
 
-	// Scale vertical image heights according to aspect ratio
-	c.scaleImagesVerticalAspectRatio()
-
 	// Propagate effect of text wrapping, aspect scaling etc. on height of parents
 	c.propagateTextWrapping()
 
 	// Calculate sizing along the Y axis
+	c.wrapColumnsWidened = false
 	c.sizeContainersAlongAxis(AxisY)
 
-	// Scale horizontal widths according to aspect ratio
-	c.scaleImagesHorizontalAspectRatio()
+	// Widen the parents of wrapping elements whose columns could only be broken once their height was known
+	if c.wrapColumnsWidened {
+		c.propagateWrapColumnWidths()
+	}
 
 	// Sort tree roots by z-index
 	sortMax := len(c.layoutElementTreeRoots) - 1
@@ -1029,7 +2035,7 @@ func (c *Context) calculateFinalLayout() {
 		rootPosition := c.layoutBoundingBox.Position
 		if parentHashMapItem, ok := c.layoutElementsHashMap[root.parentId]; ok {
 			// Position root floating containers
-			if config, ok := findElementConfigWithType[*FloatingElementConfig](rootElement); ok {
+			if config, ok := c.floatingConfig(rootElement); ok {
 				rootDimensions := rootElement.dimensions
 				parentBoundingBox := parentHashMapItem.boundingBox
 				// Set X position
@@ -1076,7 +2082,7 @@ func (c *Context) calculateFinalLayout() {
 			if clipHashMapItem, ok := c.layoutElementsHashMap[root.clipElementId]; ok {
 				// Floating elements that are attached to scrolling contents won't be correctly positioned if external scroll handling is enabled, fix here
 				if c.externalScrollHandlingEnabled {
-					if clipConfig, ok := findElementConfigWithType[*ClipElementConfig](clipHashMapItem.layoutElement); ok {
+					if clipConfig, ok := c.clipConfig(clipHashMapItem.layoutElement); ok {
 						if clipConfig.Horizontal {
 							rootPosition.X += clipConfig.ChildOffset.X
 						}
@@ -1088,9 +2094,9 @@ func (c *Context) calculateFinalLayout() {
 				}
 				c.addRenderCommand(RenderCommand{
 					BoundingBox: clipHashMapItem.boundingBox,
-					Id:          hashNumber(rootElement.id, uint32(len(rootElement.children))+10).id, // TODO(clay): need a better strategy for managing derived ids
+					Id:          hashNumber(uint32(len(rootElement.children))+10, rootElement.id).id, // TODO(clay): need a better strategy for managing derived ids
 					ZIndex:      root.zIndex,
-					RenderData:  ScissorsStartData{},
+					RenderData:  storeRenderData(&c.scissorsStartData, ScissorsStartData{}),
 				})
 			}
 		}
@@ -1106,20 +2112,22 @@ func (c *Context) calculateFinalLayout() {
 			currentElement := currentElementTreeNode.layoutElement
 			layoutConfig := currentElement.layoutConfig
 			var scrollOffset Vector2
+			// The gap between children along the layout axis, widened by ChildDistribution
+			childGap := float32(layoutConfig.ChildGap)
 
 			// This will only be run a single time for each element in downwards DFS order
 			if !c.treeNodeVisited[len(c.layoutElementTreeNodeArray1)-1] {
 				c.treeNodeVisited[len(c.layoutElementTreeNodeArray1)-1] = true
 
 				currentElementBoundingBox := MakeBoundingBox(currentElementTreeNode.position, currentElement.dimensions)
-				if floatingElementConfig, ok := findElementConfigWithType[*FloatingElementConfig](currentElement); ok {
+				if floatingElementConfig, ok := c.floatingConfig(currentElement); ok {
 					expand := floatingElementConfig.Expand
 					currentElementBoundingBox = currentElementBoundingBox.AddXYWH(-expand.X, -expand.Y, expand.X*2, expand.Y*2)
 				}
 
 				var scrollContainerData *ScrollContainerDataInternal
 				// Apply scroll offsets to container
-				if clipConfig, ok := findElementConfigWithType[*ClipElementConfig](currentElement); ok {
+				if clipConfig, ok := c.clipConfig(currentElement); ok {
 					// This linear scan could theoretically be slow under very strange conditions, but I can't imagine a real UI with more than a few 10's of scroll containers
 					for i := range c.scrollContainerDatas {
 						mapping := &c.scrollContainerDatas[i]
@@ -1135,45 +2143,17 @@ func (c *Context) calculateFinalLayout() {
 					}
 				}
 
-				if hashMapItem, ok := c.layoutElementsHashMap[currentElement.id]; ok {
+				if hashMapItem, ok := c.layoutElementsHashMap[currentElement.id]; !ok {
+					c.reportError(ERROR_TYPE_INTERNAL_ERROR, "A layout element was missing from the internal hash map while generating render commands.")
+				} else if hashMapItem.layoutElement == currentElement {
+					// Elements declared with a duplicate id don't own the hash map item
 					hashMapItem.boundingBox = currentElementBoundingBox
 					c.layoutElementsHashMap[currentElement.id] = hashMapItem
 				}
 
-				var sortedConfigIndexes [20]int
-				for elementConfigIndex := range currentElement.elementConfigs {
-					sortedConfigIndexes[elementConfigIndex] = elementConfigIndex
-				}
-				sortMax = len(currentElement.elementConfigs) - 1
-				for sortMax > 0 { // TODO(clay): dumb bubble sort
-
-					sortOrder := func(ec AnyElementConfig) int {
-						switch ec.(type) {
-						case *ClipElementConfig:
-							return 1
-						case *BorderElementConfig:
-							return -1
-						default:
-							return 0
-						}
-					}
-
-					for i := range sortMax {
-						current := sortedConfigIndexes[i]
-						next := sortedConfigIndexes[i+1]
-						currentConfig := currentElement.elementConfigs[current]
-						nextConfig := currentElement.elementConfigs[next]
-						if sortOrder(nextConfig) > 0 || sortOrder(currentConfig) < 0 {
-							sortedConfigIndexes[i] = next
-							sortedConfigIndexes[i+1] = current
-						}
-					}
-					sortMax--
-				}
-
 				emitRectangle := false
 				// Create the render commands for this element
-				sharedConfig, ok := findElementConfigWithType[*SharedElementConfig](currentElement)
+				sharedConfig, ok := c.sharedConfig(currentElement)
 				if ok && (c.renderTranslucent || sharedConfig.backgroundColor.A > 0) {
 					emitRectangle = true
 				} else if !ok {
@@ -1181,8 +2161,11 @@ func (c *Context) calculateFinalLayout() {
 					sharedConfig = &default_SharedElementConfig
 				}
 
-				for elementConfigIndex := range currentElement.elementConfigs {
-					elementConfig := currentElement.elementConfigs[sortedConfigIndexes[elementConfigIndex]]
+				for _, kind := range elementConfigRenderOrder {
+					if !currentElement.configMask.has(kind) {
+						continue
+					}
+					elementConfig := c.elementConfig(kind, currentElement.configIndexes[kind])
 					renderCommand := RenderCommand{
 						BoundingBox: currentElementBoundingBox,
 						UserData:    sharedConfig.userData,
@@ -1202,18 +2185,18 @@ func (c *Context) calculateFinalLayout() {
 					case *BorderElementConfig:
 						shouldRender = false
 					case *ClipElementConfig:
-						renderCommand.RenderData = ScissorsStartData{
+						renderCommand.RenderData = storeRenderData(&c.scissorsStartData, ScissorsStartData{
 							ClipRenderData: ClipRenderData{
 								Horizontal: cfg.Horizontal,
 								Vertical:   cfg.Vertical,
 							},
-						}
+						})
 					case *ImageElementConfig:
-						renderCommand.RenderData = ImageRenderData{
+						renderCommand.RenderData = storeRenderData(&c.imageRenderData, ImageRenderData{
 							BackgroundColor: sharedConfig.backgroundColor,
 							CornerRadius:    sharedConfig.cornerRadius,
 							ImageData:       cfg.ImageData,
-						}
+						})
 						emitRectangle = false
 
 					case *TextElementConfig:
@@ -1221,6 +2204,10 @@ func (c *Context) calculateFinalLayout() {
 							break
 						}
 						shouldRender = false
+						if currentElement.textElementData.spans != nil {
+							c.addRichTextRenderCommands(currentElement, currentElementBoundingBox, cfg, root.zIndex)
+							break
+						}
 						naturalLineHeight := currentElement.textElementData.preferredDimensions.Y
 						finalLineHeight := naturalLineHeight
 						if cfg.LineHeight > 0 {
@@ -1233,30 +2220,24 @@ func (c *Context) calculateFinalLayout() {
 								yPosition += finalLineHeight
 								continue
 							}
-							offset := (currentElementBoundingBox.Width() - wrappedLine.dimensions.X)
-							if cfg.TextAlignment == TEXT_ALIGN_LEFT {
-								offset = 0
-							}
-							if cfg.TextAlignment == TEXT_ALIGN_CENTER {
-								offset /= 2
-							}
-							c.addRenderCommand(RenderCommand{
+							offset := c.textLineOffset(currentElementBoundingBox.Width(), wrappedLine.dimensions.X, cfg.TextAlignment)
+							c.addTextRenderCommand(RenderCommand{
 								BoundingBox: MakeBoundingBox(
 									currentElementBoundingBox.Position.AddXY(offset, yPosition),
 									wrappedLine.dimensions,
 								),
-								RenderData: TextRenderData{
-									StringContents: wrappedLine.line,
-									TextColor:      cfg.TextColor,
-									FontId:         cfg.FontId,
-									FontSize:       cfg.FontSize,
-									LetterSpacing:  cfg.LetterSpacing,
-									LineHeight:     cfg.LineHeight,
-								},
 								UserData: cfg.UserData,
 								Id:       hashNumber(uint32(lineIndex), currentElement.id).id,
 								ZIndex:   root.zIndex,
-							})
+							}, TextRenderData{
+								StringContents: wrappedLine.line,
+								TextColor:      cfg.TextColor,
+								FontId:         cfg.FontId,
+								FontSize:       cfg.FontSize,
+								LetterSpacing:  cfg.LetterSpacing,
+								LineHeight:     cfg.LineHeight,
+								BaselineOffset: currentElement.textElementData.baseline - lineHeightOffset,
+							}, cfg)
 							yPosition += finalLineHeight
 
 							if !c.disableCulling && (currentElementBoundingBox.Y()+yPosition > c.layoutBoundingBox.Size.Y) {
@@ -1265,11 +2246,11 @@ func (c *Context) calculateFinalLayout() {
 						}
 					case *CustomElementConfig:
 						{
-							renderCommand.RenderData = CustomRenderData{
+							renderCommand.RenderData = storeRenderData(&c.customRenderData, CustomRenderData{
 								BackgroundColor: sharedConfig.backgroundColor,
 								CornerRadius:    sharedConfig.cornerRadius,
 								CustomData:      cfg.CustomData,
-							}
+							})
 							emitRectangle = false
 							break
 						}
@@ -1289,10 +2270,10 @@ func (c *Context) calculateFinalLayout() {
 				if emitRectangle {
 					c.addRenderCommand(RenderCommand{
 						BoundingBox: currentElementBoundingBox,
-						RenderData: RectangleRenderData{
+						RenderData: storeRenderData(&c.rectangleRenderData, RectangleRenderData{
 							BackgroundColor: sharedConfig.backgroundColor,
 							CornerRadius:    sharedConfig.cornerRadius,
-						},
+						}),
 						UserData: sharedConfig.userData,
 						Id:       currentElement.id,
 						ZIndex:   root.zIndex,
@@ -1300,44 +2281,45 @@ func (c *Context) calculateFinalLayout() {
 				}
 
 				// Setup initial on-axis alignment
-				if !elementHasConfig[*TextElementConfig](currentElementTreeNode.layoutElement) {
+				if !currentElementTreeNode.layoutElement.configMask.has(elementConfigText) {
 					var contentSize Dimensions
-					if layoutConfig.LayoutDirection == LEFT_TO_RIGHT {
+					if currentElement.grid != nil {
+						contentSize = c.gridContentSize(currentElement)
+					} else if layoutConfig.LayoutDirection == STACK {
 						for _, child := range currentElement.children {
 							childElement := c.layoutElements[child]
-							contentSize.X += childElement.dimensions.X
-							contentSize.Y = max(contentSize.Y, childElement.dimensions.Y)
+							contentSize.X = max(contentSize.X, childElement.outerSize(AxisX))
+							contentSize.Y = max(contentSize.Y, childElement.outerSize(AxisY))
+						}
+					} else if currentElement.wrapLines != nil {
+						// The children of a wrapping container are aligned line by line as they are added to the DFS buffer
+						contentSize = c.wrapLinesContentSize(currentElement)
+					} else if layoutConfig.LayoutDirection.IsAlongAxis(AxisX) {
+						for _, child := range currentElement.children {
+							childElement := c.layoutElements[child]
+							contentSize.X += childElement.outerSize(AxisX)
+							contentSize.Y = max(contentSize.Y, childElement.outerSize(AxisY))
 						}
 						contentSize.X += float32(max(len(currentElement.children)-1, 0) * int(layoutConfig.ChildGap))
 						extraSpace := currentElement.dimensions.X - float32(layoutConfig.Padding.Left+layoutConfig.Padding.Right) - contentSize.X
-						switch layoutConfig.ChildAlignment.X {
-						case ALIGN_X_LEFT:
-							extraSpace = 0
-						case ALIGN_X_CENTER:
-							extraSpace /= 2
-						default:
-							break
+						var offset float32
+						offset, childGap = childOffsetsAlongAxis(layoutConfig, AxisX, extraSpace, len(currentElement.children))
+						currentElementTreeNode.nextChildOffset.X += offset
+						if layoutConfig.LayoutDirection == RIGHT_TO_LEFT {
+							// Children are placed starting from the right edge of the content, including the space distributed between them
+							currentElementTreeNode.nextChildOffset.X += contentSize.X + (childGap-float32(layoutConfig.ChildGap))*float32(max(len(currentElement.children)-1, 0))
 						}
-						extraSpace = max(0, extraSpace)
-						currentElementTreeNode.nextChildOffset.X += extraSpace
 					} else {
 						for _, child := range currentElement.children {
 							childElement := c.layoutElements[child]
-							contentSize.X = max(contentSize.X, childElement.dimensions.X)
-							contentSize.Y += childElement.dimensions.Y
+							contentSize.X = max(contentSize.X, childElement.outerSize(AxisX))
+							contentSize.Y += childElement.outerSize(AxisY)
 						}
 						contentSize.Y += float32(max(len(currentElement.children)-1, 0) * int(layoutConfig.ChildGap))
 						extraSpace := currentElement.dimensions.Y - float32(layoutConfig.Padding.Top+layoutConfig.Padding.Bottom) - contentSize.Y
-						switch layoutConfig.ChildAlignment.Y {
-						case ALIGN_Y_TOP:
-							extraSpace = 0
-						case ALIGN_Y_CENTER:
-							extraSpace /= 2
-						default:
-							break
-						}
-						extraSpace = max(0, extraSpace)
-						currentElementTreeNode.nextChildOffset.Y += extraSpace
+						var offset float32
+						offset, childGap = childOffsetsAlongAxis(layoutConfig, AxisY, extraSpace, len(currentElement.children))
+						currentElementTreeNode.nextChildOffset.Y += offset
 					}
 
 					if scrollContainerData != nil {
@@ -1349,7 +2331,7 @@ func (c *Context) calculateFinalLayout() {
 			} else {
 				// DFS is returning upwards backwards
 				closeClipElement := false
-				if clipConfig, ok := findElementConfigWithType[*ClipElementConfig](currentElement); ok {
+				if clipConfig, ok := c.clipConfig(currentElement); ok {
 					closeClipElement = true
 					for _, mapping := range c.scrollContainerDatas {
 						if mapping.layoutElement == currentElement {
@@ -1362,32 +2344,48 @@ func (c *Context) calculateFinalLayout() {
 					}
 				}
 
-				if borderConfig, ok := findElementConfigWithType[*BorderElementConfig](currentElement); ok {
+				if borderConfig, ok := c.borderConfig(currentElement); ok {
 					currentElementData, _ := c.layoutElementsHashMap[currentElement.id]
 					currentElementBoundingBox := currentElementData.boundingBox
 
 					// Culling - Don't bother to generate render commands for rectangles entirely outside the screen - this won't stop their children from being rendered if they overflow
 					if !c.elementIsOffscreen(currentElementBoundingBox) {
-						sharedConfig, ok := findElementConfigWithType[*SharedElementConfig](currentElement)
+						sharedConfig, ok := c.sharedConfig(currentElement)
 						if !ok {
 							sharedConfig = &default_SharedElementConfig
 						}
 						renderCommand := RenderCommand{
 							BoundingBox: currentElementBoundingBox,
-							RenderData: BorderRenderData{
+							RenderData: storeRenderData(&c.borderRenderData, BorderRenderData{
 								Color:        borderConfig.Color,
 								CornerRadius: sharedConfig.cornerRadius,
 								Width:        borderConfig.Width,
-							},
+							}),
 							UserData: sharedConfig.userData,
-							Id:       hashNumber(currentElement.id, uint32(len(currentElement.children))).id,
+							Id:       hashNumber(uint32(len(currentElement.children)), currentElement.id).id,
 						}
 						c.addRenderCommand(renderCommand)
-						if borderConfig.Width.BetweenChildren > 0 && borderConfig.Color.A > 0 {
+						if borderConfig.Width.BetweenChildren > 0 && borderConfig.Color.A > 0 && currentElement.wrapLines == nil && currentElement.grid == nil && layoutConfig.LayoutDirection != STACK {
 							halfGap := layoutConfig.ChildGap / 2
 							borderOffset := MakeVector2(layoutConfig.Padding.Left-halfGap, layoutConfig.Padding.Top-halfGap)
-							if layoutConfig.LayoutDirection == LEFT_TO_RIGHT {
-								for i, child := range currentElement.children {
+							childGap := float32(layoutConfig.ChildGap)
+							if layoutConfig.ChildDistribution != DISTRIBUTE_PACKED {
+								// Borders stay in the middle of the gaps widened by the distribution
+								offset, gap := c.childDistributionAlongAxis(currentElement)
+								if layoutConfig.LayoutDirection.IsAlongAxis(AxisX) {
+									borderOffset.X += offset - (gap-childGap)/2
+								} else {
+									borderOffset.Y += offset - (gap-childGap)/2
+								}
+								childGap = gap
+							}
+							if layoutConfig.LayoutDirection.IsAlongAxis(AxisX) {
+								for i := range currentElement.children {
+									child := currentElement.children[i]
+									if layoutConfig.LayoutDirection == RIGHT_TO_LEFT {
+										// Borders are generated from left to right, which is the reverse child order
+										child = currentElement.children[len(currentElement.children)-1-i]
+									}
 									childElement := c.layoutElements[child]
 									if i > 0 {
 										c.addRenderCommand(RenderCommand{
@@ -1395,14 +2393,14 @@ func (c *Context) calculateFinalLayout() {
 												currentElementBoundingBox.Position.Add(borderOffset).Add(scrollOffset),
 												MakeDimensions(borderConfig.Width.BetweenChildren, currentElement.dimensions.Y),
 											),
-											RenderData: RectangleRenderData{
+											RenderData: storeRenderData(&c.rectangleRenderData, RectangleRenderData{
 												BackgroundColor: borderConfig.Color,
-											},
+											}),
 											UserData: sharedConfig.userData,
-											Id:       hashNumber(currentElement.id, uint32(len(currentElement.children)+1+i)).id,
+											Id:       hashNumber(uint32(len(currentElement.children)+1+i), currentElement.id).id,
 										})
 									}
-									borderOffset.X += (childElement.dimensions.X + float32(layoutConfig.ChildGap))
+									borderOffset.X += (childElement.outerSize(AxisX) + childGap)
 								}
 							} else {
 								for i, child := range currentElement.children {
@@ -1413,14 +2411,14 @@ func (c *Context) calculateFinalLayout() {
 												currentElementBoundingBox.Position.Add(scrollOffset).AddY(borderOffset.Y),
 												MakeDimensions(currentElement.dimensions.X, borderConfig.Width.BetweenChildren),
 											),
-											RenderData: RectangleRenderData{
+											RenderData: storeRenderData(&c.rectangleRenderData, RectangleRenderData{
 												BackgroundColor: borderConfig.Color,
-											},
+											}),
 											UserData: sharedConfig.userData,
-											Id:       hashNumber(currentElement.id, uint32(len(currentElement.children)+1+i)).id,
+											Id:       hashNumber(uint32(len(currentElement.children)+1+i), currentElement.id).id,
 										})
 									}
-									borderOffset.Y += (childElement.dimensions.Y + float32(layoutConfig.ChildGap))
+									borderOffset.Y += (childElement.outerSize(AxisY) + childGap)
 								}
 							}
 						}
@@ -1429,8 +2427,8 @@ func (c *Context) calculateFinalLayout() {
 				// This exists because the scissor needs to end _after_ borders between elements
 				if closeClipElement {
 					c.addRenderCommand(RenderCommand{
-						Id:         hashNumber(currentElement.id, uint32(len(rootElement.children)+11)).id,
-						RenderData: ScissorsEndData{},
+						Id:         hashNumber(uint32(len(rootElement.children)+11), currentElement.id).id,
+						RenderData: storeRenderData(&c.scissorsEndData, ScissorsEndData{}),
 					})
 				}
 
@@ -1439,14 +2437,74 @@ func (c *Context) calculateFinalLayout() {
 			}
 
 			// Add children to the DFS buffer
-			if !elementHasConfig[*TextElementConfig](currentElement) {
+			if !currentElement.configMask.has(elementConfigText) {
+				var maxChildBaseline float32
+				if layoutConfig.ChildAlignment.Y == ALIGN_Y_BASELINE {
+					maxChildBaseline, _ = c.childBaselineExtents(currentElement)
+				}
+				if len(c.layoutElementTreeNodeArray1)+len(currentElement.children) > cap(c.layoutElementTreeNodeArray1) {
+					c.reportError(ERROR_TYPE_INTERNAL_ERROR, "Clay ran out of space in its internal tree node buffer while generating render commands.")
+					return
+				}
 				c.layoutElementTreeNodeArray1 = c.layoutElementTreeNodeArray1[0 : len(c.layoutElementTreeNodeArray1)+len(currentElement.children)]
+				// Children are aligned along the non layout axis inside the content box, or inside their line in wrapping containers
+				var lineOffset, lineCrossSize float32
+				if layoutConfig.LayoutDirection.IsAlongAxis(AxisX) {
+					lineOffset = float32(layoutConfig.Padding.Top)
+					lineCrossSize = currentElement.dimensions.Y - float32(layoutConfig.Padding.Top+layoutConfig.Padding.Bottom)
+				} else {
+					lineOffset = float32(layoutConfig.Padding.Left)
+					lineCrossSize = currentElement.dimensions.X - float32(layoutConfig.Padding.Left+layoutConfig.Padding.Right)
+				}
+				if currentElement.wrapLines != nil {
+					lineOffset = c.wrapLinesStartOffset(currentElement)
+				}
+				var line wrapLine
+				var lineIndex int
 				for i, child := range currentElement.children {
 					childElement := &c.layoutElements[child]
+					if currentElement.wrapLines != nil && i == line.end {
+						// Start the next line of a wrapping container
+						if i > 0 {
+							lineOffset += line.crossSize + float32(layoutConfig.LineGap)
+						}
+						line = currentElement.wrapLines[lineIndex]
+						lineIndex++
+						lineCrossSize = line.crossSize
+						if layoutConfig.LayoutDirection.IsAlongAxis(AxisX) {
+							currentElementTreeNode.nextChildOffset.X, childGap = c.wrapLineStartOffset(currentElement, line)
+							if layoutConfig.ChildAlignment.Y == ALIGN_Y_BASELINE {
+								maxChildBaseline, _ = c.childrenBaselineExtents(currentElement.children[line.start:line.end])
+							}
+						} else {
+							currentElementTreeNode.nextChildOffset.Y, childGap = c.wrapLineStartOffset(currentElement, line)
+						}
+					}
 					// Alignment along non layout axis
-					if layoutConfig.LayoutDirection == LEFT_TO_RIGHT {
-						currentElementTreeNode.nextChildOffset.Y = float32(currentElement.layoutConfig.Padding.Top)
-						whiteSpaceAroundChild := currentElement.dimensions.Y - float32(layoutConfig.Padding.Top+layoutConfig.Padding.Bottom) - childElement.dimensions.Y
+					if currentElement.grid != nil || layoutConfig.LayoutDirection == STACK {
+						// Children of a grid are aligned inside their cell on both axes, children of a stack inside the content box
+						cellOffset := MakeVector2(layoutConfig.Padding.Left, layoutConfig.Padding.Top)
+						cellSize := currentElement.dimensions.Sub(MakeDimensions(layoutConfig.Padding.Left+layoutConfig.Padding.Right, layoutConfig.Padding.Top+layoutConfig.Padding.Bottom))
+						if currentElement.grid != nil {
+							cellOffset, cellSize = c.gridCellBox(currentElement, i)
+						}
+						whiteSpaceAroundChild := cellSize.Sub(MakeDimensions(childElement.outerSize(AxisX), childElement.outerSize(AxisY)))
+						currentElementTreeNode.nextChildOffset = cellOffset
+						switch layoutConfig.ChildAlignment.X {
+						case ALIGN_X_CENTER:
+							currentElementTreeNode.nextChildOffset.X += whiteSpaceAroundChild.X / 2
+						case ALIGN_X_RIGHT:
+							currentElementTreeNode.nextChildOffset.X += whiteSpaceAroundChild.X
+						}
+						switch layoutConfig.ChildAlignment.Y {
+						case ALIGN_Y_CENTER:
+							currentElementTreeNode.nextChildOffset.Y += whiteSpaceAroundChild.Y / 2
+						case ALIGN_Y_BOTTOM:
+							currentElementTreeNode.nextChildOffset.Y += whiteSpaceAroundChild.Y
+						}
+					} else if layoutConfig.LayoutDirection.IsAlongAxis(AxisX) {
+						currentElementTreeNode.nextChildOffset.Y = lineOffset
+						whiteSpaceAroundChild := lineCrossSize - childElement.outerSize(AxisY)
 						switch layoutConfig.ChildAlignment.Y {
 						case ALIGN_Y_TOP:
 							break
@@ -1454,10 +2512,12 @@ func (c *Context) calculateFinalLayout() {
 							currentElementTreeNode.nextChildOffset.Y += whiteSpaceAroundChild / 2
 						case ALIGN_Y_BOTTOM:
 							currentElementTreeNode.nextChildOffset.Y += whiteSpaceAroundChild
+						case ALIGN_Y_BASELINE:
+							currentElementTreeNode.nextChildOffset.Y += maxChildBaseline - elementBaseline(childElement) - float32(childElement.layoutConfig.Margin.Top)
 						}
 					} else {
-						currentElementTreeNode.nextChildOffset.X = float32(currentElement.layoutConfig.Padding.Left)
-						whiteSpaceAroundChild := currentElement.dimensions.X - float32(layoutConfig.Padding.Left+layoutConfig.Padding.Right) - childElement.dimensions.X
+						currentElementTreeNode.nextChildOffset.X = lineOffset
+						whiteSpaceAroundChild := lineCrossSize - childElement.outerSize(AxisX)
 						switch layoutConfig.ChildAlignment.X {
 						case ALIGN_X_LEFT:
 							break
@@ -1469,9 +2529,12 @@ func (c *Context) calculateFinalLayout() {
 					}
 
 					childPosition := MakeVector2(
-						currentElementTreeNode.position.X+currentElementTreeNode.nextChildOffset.X+scrollOffset.X,
-						currentElementTreeNode.position.Y+currentElementTreeNode.nextChildOffset.Y+scrollOffset.Y,
+						currentElementTreeNode.position.X+currentElementTreeNode.nextChildOffset.X+scrollOffset.X+float32(childElement.layoutConfig.Margin.Left),
+						currentElementTreeNode.position.Y+currentElementTreeNode.nextChildOffset.Y+scrollOffset.Y+float32(childElement.layoutConfig.Margin.Top),
 					)
+					if layoutConfig.LayoutDirection == RIGHT_TO_LEFT {
+						childPosition.X -= childElement.outerSize(AxisX)
+					}
 
 					// DFS buffer elements need to be added in reverse because stack traversal happens backwards
 					newNodeIndex := len(c.layoutElementTreeNodeArray1) - 1 - i
@@ -1483,10 +2546,13 @@ func (c *Context) calculateFinalLayout() {
 					c.treeNodeVisited[newNodeIndex] = false
 
 					// Update parent offsets
-					if layoutConfig.LayoutDirection == LEFT_TO_RIGHT {
-						currentElementTreeNode.nextChildOffset.X += childElement.dimensions.X + float32(layoutConfig.ChildGap)
-					} else {
-						currentElementTreeNode.nextChildOffset.Y += childElement.dimensions.Y + float32(layoutConfig.ChildGap)
+					switch layoutConfig.LayoutDirection {
+					case LEFT_TO_RIGHT:
+						currentElementTreeNode.nextChildOffset.X += childElement.outerSize(AxisX) + childGap
+					case RIGHT_TO_LEFT:
+						currentElementTreeNode.nextChildOffset.X -= childElement.outerSize(AxisX) + childGap
+					case TOP_TO_BOTTOM:
+						currentElementTreeNode.nextChildOffset.Y += childElement.outerSize(AxisY) + childGap
 					}
 				}
 
@@ -1496,8 +2562,8 @@ func (c *Context) calculateFinalLayout() {
 
 		if root.clipElementId != 0 {
 			c.addRenderCommand(RenderCommand{
-				Id:         hashNumber(rootElement.id, uint32(len(rootElement.children))+11).id,
-				RenderData: ScissorsEndData{},
+				Id:         hashNumber(uint32(len(rootElement.children))+11, rootElement.id).id,
+				RenderData: storeRenderData(&c.scissorsEndData, ScissorsEndData{}),
 			})
 		}
 	}
@@ -1508,7 +2574,11 @@ func (c *Context) wrapText() {
 		textElementData := &c.textElementData[i]
 		textElementData.wrappedLines = c.wrappedTextLines[len(c.wrappedTextLines):]
 		containerElement := &c.layoutElements[textElementData.elementIndex]
-		textConfig, _ := findElementConfigWithType[*TextElementConfig](containerElement)
+		textConfig, _ := c.textConfig(containerElement)
+		if textElementData.spans != nil {
+			c.wrapRichText(textElementData, containerElement, textConfig)
+			continue
+		}
 		measureTextCacheItem := c.measureTextCached(textElementData.text, textConfig)
 		var lineWidth float32
 		lineHeight := textElementData.preferredDimensions.Y
@@ -1520,10 +2590,10 @@ func (c *Context) wrapText() {
 
 		if !measureTextCacheItem.containsNewlines && textElementData.preferredDimensions.X <= containerElement.dimensions.X {
 			c.wrappedTextLines = append(c.wrappedTextLines, WrappedTextLine{})
-			textElementData.wrappedLines = append(textElementData.wrappedLines, WrappedTextLine{containerElement.dimensions, textElementData.text})
+			textElementData.wrappedLines = append(textElementData.wrappedLines, WrappedTextLine{dimensions: containerElement.dimensions, line: textElementData.text, start: 0})
 			continue
 		}
-		spaceWidth := measureText(SPACECHAR, textConfig, c.measureTextUserData).X
+		spaceWidth := c.measureText(SPACECHAR, textConfig, c.measureTextUserData).X
 		wordIndex := measureTextCacheItem.measuredWordsStartIndex
 		for wordIndex != -1 {
 			if len(c.wrappedTextLines) > cap(c.wrappedTextLines)-1 {
@@ -1534,8 +2604,10 @@ func (c *Context) wrapText() {
 			if lineLengthChars == 0 && lineWidth+measuredWord.width > containerElement.dimensions.X {
 				c.wrappedTextLines = append(c.wrappedTextLines, WrappedTextLine{})
 				textElementData.wrappedLines = append(textElementData.wrappedLines, WrappedTextLine{
-					MakeDimensions(measuredWord.width, lineHeight),
-					textElementData.text[measuredWord.startOffset : measuredWord.startOffset+measuredWord.length]})
+					dimensions: MakeDimensions(measuredWord.width, lineHeight),
+					line:       textElementData.text[measuredWord.startOffset : measuredWord.startOffset+measuredWord.length],
+					start:      measuredWord.startOffset,
+				})
 				wordIndex = measuredWord.next
 				lineStartOffset = measuredWord.startOffset + measuredWord.length
 			} else if measuredWord.length == 0 || lineWidth+measuredWord.width > containerElement.dimensions.X {
@@ -1548,8 +2620,9 @@ func (c *Context) wrapText() {
 				}
 				c.wrappedTextLines = append(c.wrappedTextLines, WrappedTextLine{})
 				textElementData.wrappedLines = append(textElementData.wrappedLines, WrappedTextLine{
-					MakeDimensions(lineWidth+addSpace, lineHeight),
-					textElementData.text[lineStartOffset : lineStartOffset+lineLengthChars],
+					dimensions: MakeDimensions(lineWidth+addSpace, lineHeight),
+					line:       textElementData.text[lineStartOffset : lineStartOffset+lineLengthChars],
+					start:      lineStartOffset,
 				})
 				if lineLengthChars == 0 || measuredWord.length == 0 {
 					wordIndex = measuredWord.next
@@ -1566,28 +2639,319 @@ func (c *Context) wrapText() {
 		if lineLengthChars > 0 {
 			c.wrappedTextLines = append(c.wrappedTextLines, WrappedTextLine{})
 			textElementData.wrappedLines = append(textElementData.wrappedLines, WrappedTextLine{
-				MakeDimensions(lineWidth-float32(textConfig.LetterSpacing), lineHeight), textElementData.text[lineStartOffset : lineStartOffset+lineLengthChars]})
+				dimensions: MakeDimensions(lineWidth-float32(textConfig.LetterSpacing), lineHeight),
+				line:       textElementData.text[lineStartOffset : lineStartOffset+lineLengthChars],
+				start:      lineStartOffset,
+			})
 		}
 		containerElement.dimensions.Y = lineHeight * float32(len(textElementData.wrappedLines))
 	}
 }
 
-func (c *Context) scaleImagesVerticalAspectRatio() {
-	for _, aei := range c.aspectRatioElementIndexes {
-		aspectElement := &c.layoutElements[aei]
-		if config, ok := findElementConfigWithType[*AspectRatioElementConfig](aspectElement); ok {
-			aspectElement.dimensions.Y = (1 / config.AspectRatio) * aspectElement.dimensions.X
-			// aspectElement.layoutConfig.Sizing.Height.size.minMax.max = aspectElement->dimensions.height; FIX: what is going here?
+// Splits all spans of a rich text element into measured pieces, reusing the per-span measure text cache.
+func (c *Context) measureRichTextPieces(spans []TextSpan, textConfig *TextElementConfig) []richTextPiece {
+	pieces := c.richTextPieces[:0]
+	for spanIndex, span := range spans {
+		spanConfig := span.Config
+		if spanConfig == nil {
+			spanConfig = textConfig
+		}
+		measured := c.measureTextCached(span.Text, spanConfig)
+		if c.measureText == nil {
+			break
+		}
+		height := measured.unwrappedDimensions.Y
+		baseline := measured.baselineOffset()
+		spacing := c.measureText(SPACECHAR, spanConfig, c.measureTextUserData).X + float32(spanConfig.LetterSpacing)
+		for wordIndex := measured.measuredWordsStartIndex; wordIndex != -1; wordIndex = c.measuredWords[wordIndex].next {
+			measuredWord := c.measuredWords[wordIndex]
+			if measuredWord.length == 0 {
+				// A newline is always a break opportunity, even inside of a glued word
+				if len(pieces) > 0 {
+					pieces[len(pieces)-1].glued = false
+				}
+				pieces = append(pieces, richTextPiece{
+					span:     int32(spanIndex),
+					start:    measuredWord.startOffset,
+					height:   height,
+					baseline: baseline,
+					newline:  true,
+				})
+				continue
+			}
+			piece := richTextPiece{
+				span:     int32(spanIndex),
+				start:    measuredWord.startOffset,
+				length:   measuredWord.length,
+				width:    measuredWord.width,
+				height:   height,
+				baseline: baseline,
+			}
+			if span.Text[measuredWord.startOffset+measuredWord.length-1] == ' ' {
+				piece.spacing = spacing
+			} else {
+				piece.glued = true
+			}
+			pieces = append(pieces, piece)
+		}
+	}
+	if len(pieces) > 0 {
+		pieces[len(pieces)-1].glued = false
+	}
+
+	c.richTextPieces = pieces
+	return pieces
+}
+
+// Returns the end of the unbreakable run of pieces starting at index and its width.
+func richTextGroup(pieces []richTextPiece, index int) (int, float32) {
+	width := pieces[index].width
+	for pieces[index].glued && index+1 < len(pieces) && !pieces[index+1].newline {
+		index++
+		width += pieces[index].width
+	}
+	return index, width
+}
+
+// Returns the unwrapped dimensions, the minimum width and the first baseline of the rich text made of the provided pieces.
+func measureRichText(pieces []richTextPiece, textConfig *TextElementConfig) (Dimensions, float32, float32) {
+	var dimensions Dimensions
+	var minWidth float32
+	var lineWidth float32
+	var lineHeight float32
+	var lineSpacing float32
+	var baseline float32
+	belowBaseline := float32(math.MaxFloat32)
+	firstLine := true
+
+	endLine := func() {
+		dimensions.X = max(dimensions.X, lineWidth-lineSpacing)
+		naturalHeight := lineHeight
+		if textConfig.LineHeight > 0 {
+			lineHeight = float32(textConfig.LineHeight)
+		}
+		if firstLine {
+			baseline = lineBaseline(naturalHeight, lineHeight, min(belowBaseline, naturalHeight))
+			firstLine = false
+		}
+		dimensions.Y += lineHeight
+		lineWidth = 0
+		lineHeight = 0
+		lineSpacing = 0
+	}
+
+	for i := 0; i < len(pieces); i++ {
+		belowBaseline = min(belowBaseline, pieces[i].height-pieces[i].baseline)
+		if pieces[i].newline {
+			lineHeight = max(lineHeight, pieces[i].height)
+			endLine()
+			continue
+		}
+		end, groupWidth := richTextGroup(pieces, i)
+		for ; i <= end; i++ {
+			lineHeight = max(lineHeight, pieces[i].height)
+			belowBaseline = min(belowBaseline, pieces[i].height-pieces[i].baseline)
+		}
+		i = end
+		minWidth = max(minWidth, groupWidth)
+		lineSpacing = pieces[end].spacing
+		lineWidth += groupWidth + lineSpacing
+	}
+	if lineWidth > 0 || len(pieces) == 0 || pieces[len(pieces)-1].newline {
+		endLine()
+	}
+
+	return dimensions, minWidth, baseline
+}
+
+func (c *Context) wrapRichText(textElementData *TextElementData, containerElement *LayoutElement, textConfig *TextElementConfig) {
+	pieces := c.measureRichTextPieces(textElementData.spans, textConfig)
+	maxWidth := containerElement.dimensions.X
+	if textConfig.WrapMode != TEXT_WRAP_WORDS {
+		maxWidth = math.MaxFloat32
+	}
+
+	lineStart := len(c.wrappedTextFragments)
+	var lineWidth float32
+	var lineHeight float32
+	var lineSpacing float32
+	var totalHeight float32
+
+	endLine := func() {
+		fragments := c.wrappedTextFragments[lineStart:len(c.wrappedTextFragments):len(c.wrappedTextFragments)]
+		if len(fragments) > 0 && lineSpacing > 0 {
+			// Trailing whitespace is not rendered
+			last := &fragments[len(fragments)-1]
+			last.end--
+		}
+		belowBaseline := lineHeight
+		for i := range fragments {
+			fragment := &fragments[i]
+			fragment.text = textElementData.spans[fragment.span].Text[fragment.start:fragment.end]
+			belowBaseline = min(belowBaseline, fragment.dimensions.Y-fragment.baseline)
+		}
+		naturalHeight := lineHeight
+		if textConfig.LineHeight > 0 {
+			lineHeight = float32(textConfig.LineHeight)
+		}
+		if len(textElementData.wrappedLines) == 0 {
+			textElementData.baseline = lineBaseline(naturalHeight, lineHeight, belowBaseline)
+		}
+		c.wrappedTextLines = append(c.wrappedTextLines, WrappedTextLine{})
+		textElementData.wrappedLines = append(textElementData.wrappedLines, WrappedTextLine{
+			dimensions: MakeDimensions(lineWidth-lineSpacing, lineHeight),
+			fragments:  fragments,
+		})
+		totalHeight += lineHeight
+		lineStart = len(c.wrappedTextFragments)
+		lineWidth = 0
+		lineHeight = 0
+		lineSpacing = 0
+	}
+
+	addPiece := func(piece richTextPiece) {
+		if len(c.wrappedTextFragments) > lineStart {
+			last := &c.wrappedTextFragments[len(c.wrappedTextFragments)-1]
+			if last.span == piece.span && last.end == piece.start {
+				last.end = piece.start + piece.length
+				last.dimensions.X = lineWidth + piece.width - last.offset
+				last.dimensions.Y = max(last.dimensions.Y, piece.height)
+				return
+			}
+		}
+		spanConfig := textElementData.spans[piece.span].Config
+		if spanConfig == nil {
+			spanConfig = textConfig
+		}
+		c.wrappedTextFragments = append(c.wrappedTextFragments, WrappedTextFragment{
+			offset:     lineWidth,
+			dimensions: MakeDimensions(piece.width, piece.height),
+			baseline:   piece.baseline,
+			config:     spanConfig,
+			span:       piece.span,
+			start:      piece.start,
+			end:        piece.start + piece.length,
+		})
+	}
+
+	for i := 0; i < len(pieces); i++ {
+		if len(c.wrappedTextLines) > cap(c.wrappedTextLines)-1 {
+			break
+		}
+		if pieces[i].newline {
+			lineHeight = max(lineHeight, pieces[i].height)
+			endLine()
+			continue
+		}
+		end, groupWidth := richTextGroup(pieces, i)
+		if len(c.wrappedTextFragments) > lineStart && lineWidth+groupWidth > maxWidth {
+			endLine()
+		}
+		for ; i <= end; i++ {
+			addPiece(pieces[i])
+			lineHeight = max(lineHeight, pieces[i].height)
+			lineWidth += pieces[i].width + pieces[i].spacing
+		}
+		i = end
+		lineSpacing = pieces[end].spacing
+	}
+	if len(c.wrappedTextFragments) > lineStart {
+		endLine()
+	}
+
+	containerElement.dimensions.Y = totalHeight
+}
+
+// Returns the horizontal offset of a line of text inside its element for the text alignment, mirrored in right to left mode.
+func (c *Context) textLineOffset(elementWidth float32, lineWidth float32, alignment TextAlignment) float32 {
+	if c.rightToLeft {
+		alignment = alignment.Mirror()
+	}
+	switch alignment {
+	case TEXT_ALIGN_LEFT:
+		return 0
+	case TEXT_ALIGN_CENTER:
+		return (elementWidth - lineWidth) / 2
+	}
+	return elementWidth - lineWidth
+}
+
+// Generates one TEXT render command for every fragment of every wrapped line of a rich text element.
+func (c *Context) addRichTextRenderCommands(element *LayoutElement, boundingBox BoundingBox, textConfig *TextElementConfig, zIndex int16) {
+	var yPosition float32
+	var fragmentIndex uint32
+	for _, wrappedLine := range element.textElementData.wrappedLines {
+		offset := c.textLineOffset(boundingBox.Width(), wrappedLine.dimensions.X, textConfig.TextAlignment)
+
+		var naturalLineHeight float32
+		for _, fragment := range wrappedLine.fragments {
+			naturalLineHeight = max(naturalLineHeight, fragment.dimensions.Y)
+		}
+		lineHeightOffset := (wrappedLine.dimensions.Y - naturalLineHeight) / 2
+
+		for _, fragment := range wrappedLine.fragments {
+			fragmentIndex++
+			if len(fragment.text) == 0 {
+				continue
+			}
+			// Fragments with smaller fonts are aligned to the bottom of the line
+			fragmentOffset := lineHeightOffset + naturalLineHeight - fragment.dimensions.Y
+			c.addTextRenderCommand(RenderCommand{
+				BoundingBox: MakeBoundingBox(
+					boundingBox.Position.AddXY(offset+fragment.offset, yPosition+fragmentOffset),
+					fragment.dimensions,
+				),
+				UserData: fragment.config.UserData,
+				Id:       hashNumber(fragmentIndex, element.id).id,
+				ZIndex:   zIndex,
+			}, TextRenderData{
+				StringContents: fragment.text,
+				TextColor:      fragment.config.TextColor,
+				FontId:         fragment.config.FontId,
+				FontSize:       fragment.config.FontSize,
+				LetterSpacing:  fragment.config.LetterSpacing,
+				LineHeight:     fragment.config.LineHeight,
+				BaselineOffset: fragment.baseline,
+			}, fragment.config)
+		}
+		yPosition += wrappedLine.dimensions.Y
+
+		if !c.disableCulling && (boundingBox.Y()+yPosition > c.layoutBoundingBox.Size.Y) {
+			break
 		}
 	}
 }
 
-func (c *Context) scaleImagesHorizontalAspectRatio() {
-	for _, ai := range c.aspectRatioElementIndexes {
-		aspectElement := &c.layoutElements[ai]
-		config, _ := findElementConfigWithType[*AspectRatioElementConfig](aspectElement)
-		aspectElement.dimensions.X = config.AspectRatio * aspectElement.dimensions.Y
+// Returns the distance from the top of the element to its first baseline.
+// Elements without text use their bottom edge as the baseline.
+func elementBaseline(element *LayoutElement) float32 {
+	if element.textElementData != nil {
+		return element.textElementData.baseline
 	}
+	return element.dimensions.Y
+}
+
+// Returns the largest distance above and below the baseline among the children of the element.
+func (c *Context) childBaselineExtents(element *LayoutElement) (float32, float32) {
+	return c.childrenBaselineExtents(element.children)
+}
+
+// Returns the largest distance above and below the baseline among the elements.
+func (c *Context) childrenBaselineExtents(children []int) (float32, float32) {
+	var above, below float32
+	for _, child := range children {
+		childElement := &c.layoutElements[child]
+		baseline := elementBaseline(childElement) + float32(childElement.layoutConfig.Margin.Top)
+		above = max(above, baseline)
+		below = max(below, childElement.outerSize(AxisY)-baseline)
+	}
+	return above, below
+}
+
+// Returns the height needed to fit all children of the element aligned to a common baseline.
+func (c *Context) baselineAlignedHeight(element *LayoutElement) float32 {
+	above, below := c.childBaselineExtents(element)
+	return above + below
 }
 
 func (c *Context) propagateTextWrapping() {
@@ -1604,7 +2968,7 @@ func (c *Context) propagateTextWrapping() {
 		if !c.treeNodeVisited[len(c.layoutElementTreeNodeArray1)-1] {
 			c.treeNodeVisited[len(c.layoutElementTreeNodeArray1)-1] = true
 			// If the element has no children or is the container for a text element, don't bother inspecting it
-			if elementHasConfig[*TextElementConfig](currentElement) || len(currentElement.children) == 0 {
+			if currentElement.configMask.has(elementConfigText) || len(currentElement.children) == 0 {
 				c.layoutElementTreeNodeArray1 = c.layoutElementTreeNodeArray1[:len(c.layoutElementTreeNodeArray1)-1]
 				continue
 			}
@@ -1620,30 +2984,217 @@ func (c *Context) propagateTextWrapping() {
 		c.layoutElementTreeNodeArray1 = c.layoutElementTreeNodeArray1[:len(c.layoutElementTreeNodeArray1)-1]
 
 		// DFS node has been visited, this is on the way back up to the root
+		// The height of an element with an aspect ratio follows its width, not its content
+		if currentElement.configMask.has(elementConfigAspectRatio) {
+			continue
+		}
 		layoutConfig := currentElement.layoutConfig
 		switch layoutConfig.LayoutDirection {
-		case LEFT_TO_RIGHT:
+		case LEFT_TO_RIGHT, RIGHT_TO_LEFT:
+			if currentElement.wrapLines != nil {
+				// The rows of a wrapping container are stacked vertically
+				contentHeight := c.measureWrapLinesAcrossAxis(currentElement, AxisY) + float32(layoutConfig.Padding.Top+layoutConfig.Padding.Bottom)
+				if mm := layoutConfig.Sizing.Height; mm.fitsContent() {
+					currentElement.dimensions.Y = min(max(contentHeight, mm.GetMinMax().Min), mm.GetMinMax().Max)
+				}
+				break
+			}
 			// Resize any parent containers that have grown in height along their non layout axis
 			for _, child := range currentElement.children {
 				childElement := c.layoutElements[child]
-				childHeightWithPadding := max(childElement.dimensions.Y+float32(layoutConfig.Padding.Top+layoutConfig.Padding.Bottom), currentElement.dimensions.Y)
-				switch mm := layoutConfig.Sizing.Height.(type) {
-				case SizingAxisMinMax:
+				childHeightWithPadding := max(childElement.outerSize(AxisY)+float32(layoutConfig.Padding.Top+layoutConfig.Padding.Bottom), currentElement.dimensions.Y)
+				if mm := layoutConfig.Sizing.Height; mm.fitsContent() {
 					currentElement.dimensions.Y = min(max(childHeightWithPadding, mm.GetMinMax().Min), mm.GetMinMax().Max)
 				}
 			}
+			if layoutConfig.ChildAlignment.Y == ALIGN_Y_BASELINE {
+				baselineHeight := max(c.baselineAlignedHeight(currentElement)+float32(layoutConfig.Padding.Top+layoutConfig.Padding.Bottom), currentElement.dimensions.Y)
+				if mm := layoutConfig.Sizing.Height; mm.fitsContent() {
+					currentElement.dimensions.Y = min(max(baselineHeight, mm.GetMinMax().Min), mm.GetMinMax().Max)
+				}
+			}
 		case TOP_TO_BOTTOM:
 			// Resizing along the layout axis
 			contentHeight := float32(layoutConfig.Padding.Top + layoutConfig.Padding.Bottom)
 			for _, child := range currentElement.children {
 				childElement := c.layoutElements[child]
-				contentHeight += childElement.dimensions.Y
+				contentHeight += childElement.outerSize(AxisY)
 			}
 			contentHeight += float32(max(uint16(len(currentElement.children))-1, 0) * layoutConfig.ChildGap)
-			switch mm := layoutConfig.Sizing.Height.(type) {
-			case SizingAxisMinMax:
+			if mm := layoutConfig.Sizing.Height; mm.fitsContent() {
+				currentElement.dimensions.Y = min(max(contentHeight, mm.GetMinMax().Min), mm.GetMinMax().Max)
+			}
+		case STACK:
+			// Resize the stack to the tallest child
+			for _, child := range currentElement.children {
+				childHeightWithPadding := max(c.layoutElements[child].outerSize(AxisY)+float32(layoutConfig.Padding.Top+layoutConfig.Padding.Bottom), currentElement.dimensions.Y)
+				if mm := layoutConfig.Sizing.Height; mm.fitsContent() {
+					currentElement.dimensions.Y = min(max(childHeightWithPadding, mm.GetMinMax().Min), mm.GetMinMax().Max)
+				}
+			}
+		case GRID:
+			// Rows grow with the wrapped text of their children
+			contentHeight := c.fitGridTracks(currentElement, AxisY, false) + float32(layoutConfig.Padding.Top+layoutConfig.Padding.Bottom)
+			if mm := layoutConfig.Sizing.Height; mm.fitsContent() {
 				currentElement.dimensions.Y = min(max(contentHeight, mm.GetMinMax().Min), mm.GetMinMax().Max)
 			}
 		}
 	}
 }
+
+// Grows the FIT widths of elements to their children after height sizing, for the ancestors of wrapping TOP_TO_BOTTOM
+// elements with a GROW height, which only know how many columns they have once their height is sized.
+func (c *Context) propagateWrapColumnWidths() {
+	c.layoutElementTreeNodeArray1 = c.layoutElementTreeNodeArray1[0:0]
+	for _, root := range c.layoutElementTreeRoots {
+		c.treeNodeVisited[len(c.layoutElementTreeNodeArray1)] = false
+		c.layoutElementTreeNodeArray1 = append(c.layoutElementTreeNodeArray1, LayoutElementTreeNode{
+			layoutElement: &c.layoutElements[root.layoutElementIndex],
+		})
+	}
+	for len(c.layoutElementTreeNodeArray1) > 0 {
+		currentElement := c.layoutElementTreeNodeArray1[len(c.layoutElementTreeNodeArray1)-1].layoutElement
+		if !c.treeNodeVisited[len(c.layoutElementTreeNodeArray1)-1] {
+			c.treeNodeVisited[len(c.layoutElementTreeNodeArray1)-1] = true
+			if currentElement.configMask.has(elementConfigText) || len(currentElement.children) == 0 {
+				c.layoutElementTreeNodeArray1 = c.layoutElementTreeNodeArray1[:len(c.layoutElementTreeNodeArray1)-1]
+				continue
+			}
+			for _, child := range currentElement.children {
+				c.treeNodeVisited[len(c.layoutElementTreeNodeArray1)] = false
+				c.layoutElementTreeNodeArray1 = append(c.layoutElementTreeNodeArray1, LayoutElementTreeNode{
+					layoutElement: &c.layoutElements[child],
+				})
+			}
+			continue
+		}
+		c.layoutElementTreeNodeArray1 = c.layoutElementTreeNodeArray1[:len(c.layoutElementTreeNodeArray1)-1]
+
+		// DFS node has been visited, this is on the way back up to the root
+		layoutConfig := currentElement.layoutConfig
+		fit := layoutConfig.Sizing.Width
+		if fit.Type != SIZING_TYPE_FIT || currentElement.wrapLines != nil || currentElement.grid != nil || currentElement.configMask.has(elementConfigAspectRatio) {
+			continue
+		}
+		var contentWidth float32
+		switch layoutConfig.LayoutDirection {
+		case LEFT_TO_RIGHT, RIGHT_TO_LEFT:
+			for _, child := range currentElement.children {
+				contentWidth += c.layoutElements[child].outerSize(AxisX)
+			}
+			contentWidth += float32(max(len(currentElement.children)-1, 0) * int(layoutConfig.ChildGap))
+		case TOP_TO_BOTTOM, STACK:
+			for _, child := range currentElement.children {
+				contentWidth = max(contentWidth, c.layoutElements[child].outerSize(AxisX))
+			}
+		}
+		contentWidth += float32(layoutConfig.Padding.Left + layoutConfig.Padding.Right)
+		currentElement.dimensions.X = min(max(contentWidth, currentElement.dimensions.X, fit.GetMinMax().Min), fit.GetMinMax().Max)
+	}
+}
+
+// Finds the text element with the provided id, or the first text child of the element with the provided id.
+// Rich text elements are not supported.
+func (c *Context) findTextElement(id ElementId) (*LayoutElement, *TextElementConfig, BoundingBox, bool) {
+	item, ok := c.layoutElementsHashMap[id.id]
+	if !ok || item.layoutElement == nil {
+		return nil, nil, BoundingBox{}, false
+	}
+	element := item.layoutElement
+	if element.textElementData == nil {
+		for _, child := range element.children {
+			if c.layoutElements[child].textElementData != nil {
+				element = &c.layoutElements[child]
+				item = c.layoutElementsHashMap[element.id]
+				break
+			}
+		}
+	}
+	if element.textElementData == nil || element.textElementData.spans != nil {
+		return nil, nil, BoundingBox{}, false
+	}
+	config, ok := c.textConfig(element)
+	return element, config, item.boundingBox, ok
+}
+
+// Returns the vertical offset of the first line and the height of every line of a text element.
+func textElementLineMetrics(element *LayoutElement, config *TextElementConfig) (float32, float32) {
+	naturalLineHeight := element.textElementData.preferredDimensions.Y
+	finalLineHeight := naturalLineHeight
+	if config.LineHeight > 0 {
+		finalLineHeight = float32(config.LineHeight)
+	}
+	return (finalLineHeight - naturalLineHeight) / 2, finalLineHeight
+}
+
+// Returns the width of the first n bytes of a line of text, using the shaped glyph advances when a text shaper is set.
+func (c *Context) textPrefixWidth(line string, n int, config *TextElementConfig) float32 {
+	if c.textShaper != nil {
+		run := c.textShaper.Shape(line, config, c.measureGlyphs[:0])
+		c.measureGlyphs = run.Glyphs
+		var width float32
+		for _, glyph := range run.Glyphs {
+			if int(glyph.Cluster) < n {
+				width += glyph.Advance
+			}
+		}
+		return width
+	}
+	if n == 0 || c.measureText == nil {
+		return 0
+	}
+	return c.measureText(line[:n], config, c.measureTextUserData).X
+}
+
+func (c *Context) textCaretPosition(id ElementId, index int) (Vector2, bool) {
+	element, config, boundingBox, ok := c.findTextElement(id)
+	if !ok || len(element.textElementData.wrappedLines) == 0 {
+		return Vector2{}, false
+	}
+	lineHeightOffset, finalLineHeight := textElementLineMetrics(element, config)
+	wrappedLines := element.textElementData.wrappedLines
+	lineIndex := len(wrappedLines) - 1
+	for i, wrappedLine := range wrappedLines {
+		if index <= int(wrappedLine.start)+len(wrappedLine.line) {
+			lineIndex = i
+			break
+		}
+	}
+	wrappedLine := wrappedLines[lineIndex]
+	n := min(max(index-int(wrappedLine.start), 0), len(wrappedLine.line))
+	x := c.textLineOffset(boundingBox.Width(), wrappedLine.dimensions.X, config.TextAlignment) + c.textPrefixWidth(wrappedLine.line, n, config)
+	y := lineHeightOffset + float32(lineIndex)*finalLineHeight
+	return boundingBox.Position.AddXY(x, y), true
+}
+
+func (c *Context) textIndexAtPosition(id ElementId, position Vector2) (int, bool) {
+	element, config, boundingBox, ok := c.findTextElement(id)
+	if !ok || len(element.textElementData.wrappedLines) == 0 {
+		return 0, false
+	}
+	_, finalLineHeight := textElementLineMetrics(element, config)
+	wrappedLines := element.textElementData.wrappedLines
+	lineIndex := 0
+	if finalLineHeight > 0 {
+		lineIndex = min(max(int((position.Y-boundingBox.Y())/finalLineHeight), 0), len(wrappedLines)-1)
+	}
+	wrappedLine := wrappedLines[lineIndex]
+	x := position.X - boundingBox.X() - c.textLineOffset(boundingBox.Width(), wrappedLine.dimensions.X, config.TextAlignment)
+
+	bestIndex := 0
+	bestDistance := float32(math.MaxFloat32)
+	for n := 0; n <= len(wrappedLine.line); n++ {
+		if n < len(wrappedLine.line) && !utf8.RuneStart(wrappedLine.line[n]) {
+			continue
+		}
+		distance := x - c.textPrefixWidth(wrappedLine.line, n, config)
+		if distance < 0 {
+			distance = -distance
+		}
+		if distance < bestDistance {
+			bestDistance = distance
+			bestIndex = n
+		}
+	}
+	return int(wrappedLine.start) + bestIndex, true
+}
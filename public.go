@@ -1,6 +1,8 @@
 package clay
 
 import (
+	"slices"
+
 	"github.com/igadmg/gamemath/vector2"
 )
 
@@ -28,7 +30,7 @@ func (c *Context) SetPointerState(position Vector2, pointerDown bool) {
 	c.pointerOverIds = c.pointerOverIds[:0]
 
 	var dfsBuffer []int
-	treeNodeVisited := make([]bool, len(c.layoutElements))
+	treeNodeVisited := c.resetTreeNodeVisited()
 	for rootIndex := len(c.layoutElementTreeRoots) - 1; rootIndex >= 0; rootIndex-- {
 		dfsBuffer = c.layoutElementChildrenBuffer[:0]
 		root := c.layoutElementTreeRoots[rootIndex]
@@ -44,7 +46,7 @@ func (c *Context) SetPointerState(position Vector2, pointerDown bool) {
 			currentElement := c.layoutElements[dfsBuffer[len(dfsBuffer)-1]]
 			// TODO(clay): think of a way around this, maybe the fact that it's essentially a binary tree limits the cost, but the worst case is not great
 			if mapItem, ok := c.layoutElementsHashMap[currentElement.id]; ok {
-				clipElementId := uint32(0) //TODO(iga) FIX: c.layoutElementClipElementIds[(int32)(currentElement-c.layoutElements.internalArray)]
+				clipElementId := uint64(0) //TODO(iga) FIX: c.layoutElementClipElementIds[(int32)(currentElement-c.layoutElements.internalArray)]
 				clipItem, _ := c.layoutElementsHashMap[clipElementId]
 				elementBox := mapItem.boundingBox.AddPositionXY(root.pointerOffset.X, -root.pointerOffset.Y)
 
@@ -55,7 +57,7 @@ func (c *Context) SetPointerState(position Vector2, pointerDown bool) {
 					c.pointerOverIds = append(c.pointerOverIds, mapItem.elementId)
 					found = true
 				}
-				if elementHasConfig[*TextElementConfig](&currentElement) {
+				if currentElement.configMask.has(elementConfigText) {
 					dfsBuffer = dfsBuffer[:len(dfsBuffer)-1]
 					continue
 				}
@@ -70,7 +72,7 @@ func (c *Context) SetPointerState(position Vector2, pointerDown bool) {
 
 		if found {
 			rootElement := c.layoutElements[root.layoutElementIndex]
-			if config, ok := findElementConfigWithType[*FloatingElementConfig](&rootElement); ok {
+			if config, ok := c.floatingConfig(&rootElement); ok {
 				if config.PointerCaptureMode == POINTER_CAPTURE_MODE_CAPTURE {
 					break
 				}
@@ -98,25 +100,29 @@ func (c *Context) SetPointerState(position Vector2, pointerDown bool) {
 // - layoutDimensions are the initial bounding dimensions of the layout (i.e. the screen width and height for a full screen layout)
 // - errorHandler is used by Clay to inform you if something has gone wrong in configuration or layout.
 func Initialize(layoutDimensions BoundingBox, errorHandler ErrorHandler) *Context {
+	return initialize(layoutDimensions, errorHandler, defaultMaxElementCount, defaultMaxMeasureTextWordCacheCount)
+}
+
+// Initializes a new context like Initialize, with the element and measure text cache limits set on previous.
+// Use it to replace a context, e.g. when recreating a window, without setting the limits again.
+func InitializeFrom(previous *Context, layoutDimensions BoundingBox, errorHandler ErrorHandler) *Context {
+	return initialize(layoutDimensions, errorHandler, previous.maxElementCount, previous.maxMeasureTextCacheWordCount)
+}
+
+func initialize(layoutDimensions BoundingBox, errorHandler ErrorHandler, maxElementCount int32, maxMeasureTextCacheWordCount int32) *Context {
 	// DEFAULTS
 	if errorHandler.ErrorHandlerFunction == nil {
 		errorHandler.ErrorHandlerFunction = errorHandlerFunctionDefault
 	}
 
 	context := &Context{
-		maxElementCount:              defaultMaxElementCount,
-		maxMeasureTextCacheWordCount: defaultMaxMeasureTextWordCacheCount,
+		maxElementCount:              maxElementCount,
+		maxMeasureTextCacheWordCount: maxMeasureTextCacheWordCount,
 		errorHandler:                 errorHandler,
 		layoutBoundingBox:            layoutDimensions,
 		//internalArena:                arena,
 	}
 
-	if oldContext := GetCurrentContext(); oldContext != nil {
-		context.maxElementCount = oldContext.maxElementCount
-		context.maxMeasureTextCacheWordCount = oldContext.maxMeasureTextCacheWordCount
-	}
-
-	SetCurrentContext(context)
 	context.initializePersistentMemory()
 	context.initializeEphemeralMemory()
 
@@ -125,17 +131,6 @@ func Initialize(layoutDimensions BoundingBox, errorHandler ErrorHandler) *Contex
 	return context
 }
 
-// Returns the Context that clay is currently using. Used when using multiple instances of clay simultaneously.
-func GetCurrentContext() *Context {
-	return currentContext
-}
-
-// Sets the context that clay will use to compute the layout.
-// Used to restore a context saved fromGetCurrentContext when using multiple instances of clay simultaneously.
-func SetCurrentContext(context *Context) {
-	currentContext = context
-}
-
 // Returns the internally stored scroll offset for the currently open element.
 // Generally intended for use with clip elements to create scrolling containers.
 func (c *Context) GetScrollOffset() Vector2 {
@@ -285,6 +280,7 @@ func (c *Context) SetLayoutDimensions(dimensions BoundingBox) {
 // Called before starting any layout declarations.
 func (c *Context) BeginLayout() {
 	c.initializeEphemeralMemory()
+	c.resetRenderData()
 	c.generation++
 	c.dynamicElementIndex = 0
 	// Set up the root container that covers the entire window
@@ -293,6 +289,9 @@ func (c *Context) BeginLayout() {
 		rootDimensions.Size.X -= (float32)(debugViewWidth)
 	}
 	c.booleanWarnings = BooleanWarnings{}
+	c.declarationLocation = ""
+	clear(c.layoutErrors)
+	c.layoutErrors = c.layoutErrors[:0]
 	c.openElementWithId(c.ID("Clay__RootContainer"))
 	c.configureOpenElement(&ElementDeclaration{
 		Layout: LayoutConfig{
@@ -309,13 +308,29 @@ func (c *Context) BeginLayout() {
 // Called when all layout declarations are finished.
 // Computes the layout and generates and returns the array of render commands to draw.
 func (c *Context) EndLayout() []RenderCommand {
+	if len(c.openLayoutElementStack) < 2 {
+		// The root container was already closed, or BeginLayout wasn't called
+		c.reportError(ERROR_TYPE_UNBALANCED_OPEN_CLOSE, "EndLayout was called without a matching call to BeginLayout.")
+		return c.renderCommands
+	}
 	clear(c.renderCommands[0:cap(c.renderCommands)])
+	if len(c.openLayoutElementStack) > 2 && !c.booleanWarnings.maxElementsExceeded {
+		c.reportOpenElementError(ERROR_TYPE_UNBALANCED_OPEN_CLOSE, "There were still open layout elements when EndLayout was called. This results from a CLAY() child function that didn't return, e.g. because of a recovered panic.")
+		// Close them, so that the layout can still be calculated
+		for len(c.openLayoutElementStack) > 2 {
+			c.closeElement()
+		}
+	}
 	c.closeElement()
 	elementsExceededBeforeDebugView := c.booleanWarnings.maxElementsExceeded
 	if c.debugModeEnabled && !elementsExceededBeforeDebugView {
+		// The debug view is always laid out left to right
+		rightToLeft := c.rightToLeft
+		c.rightToLeft = false
 		c.warningsEnabled = false
 		c.Clay__RenderDebugView()
 		c.warningsEnabled = true
+		c.rightToLeft = rightToLeft
 	}
 	if c.booleanWarnings.maxElementsExceeded {
 		message := ""
@@ -326,24 +341,16 @@ func (c *Context) EndLayout() []RenderCommand {
 		}
 		c.addRenderCommand(RenderCommand{
 			BoundingBox: MakeBoundingBox(c.layoutBoundingBox.ScaleF(0.5).AddX(-59*4).Size, vector2.Zero[float32]()),
-			RenderData: TextRenderData{
+			RenderData: storeRenderData(&c.textRenderData, TextRenderData{
 				StringContents: message,
 				TextColor:      Color{R: 255, G: 0, B: 0, A: 255},
 				FontSize:       16,
-			},
+			}),
 		})
+	} else {
+		c.calculateFinalLayout()
 	}
 
-	if len(c.openLayoutElementStack) > 1 {
-		// TODO(iga) FIX: error reporting
-		//	c.errorHandler.errorHandlerFunction(CLAY__INIT(Clay_ErrorData) {
-		//			.errorType = CLAY_ERROR_TYPE_UNBALANCED_OPEN_CLOSE,
-		//			.errorText = CLAY_STRING("There were still open layout elements when EndLayout was called. This results from an unequal number of calls to Clay__OpenElement and Clay__CloseElement."),
-		//			.userData = context->errorHandler.userData });
-	}
-
-	c.calculateFinalLayout()
-
 	return c.renderCommands
 }
 
@@ -354,6 +361,27 @@ func (c *Context) FinalizeLayout() []RenderCommand {
 	return c.Finalize()
 }
 
+// Like EndLayout, but also returns the errors reported since BeginLayout as a *LayoutError, or nil if there were none.
+// The errors are still passed to the ErrorHandler as they are found.
+func (c *Context) EndLayoutWithError() ([]RenderCommand, error) {
+	renderCommands := c.EndLayout()
+	return renderCommands, c.layoutError()
+}
+
+// Like FinalizeLayout, but also returns the errors reported since BeginLayout as a *LayoutError, or nil if there were none.
+func (c *Context) FinalizeLayoutWithError() ([]RenderCommand, error) {
+	renderCommands, err := c.EndLayoutWithError()
+	c.Finalize()
+	return renderCommands, err
+}
+
+func (c *Context) layoutError() error {
+	if len(c.layoutErrors) == 0 {
+		return nil
+	}
+	return &LayoutError{Errors: slices.Clone(c.layoutErrors)}
+}
+
 // Returns layout data such as the final calculated bounding box for an element with a given ID.
 // The returned clay.ElementData contains a `found` bool that will be true if an element with the provided ID was found.
 // This ID can be calculated either with CLAY_ID() for string literal IDs, or clay.GetElementId for dynamic strings.
@@ -414,14 +442,66 @@ func (c *Context) GetScrollContainerData(id ElementId) ScrollContainerData {
 // - measureTextFunction is a user provided function that adheres to the interface clay.Dimensions (clay.StringSlice text, clay.TextElementConfig *config, void *userData);
 // - userData is a pointer that will be transparently passed through when the measureTextFunction is called.
 func (c *Context) SetMeasureTextFunction(fn MeasureTextFn, userData any) {
-	measureText = fn
+	c.measureText = fn
+	c.measureTextMetrics = nil
+	c.textShaper = nil
+	c.measureTextUserData = userData
+}
+
+// Binds a callback function that Clay will call to determine the dimensions, ascent and descent of a given string slice.
+// Use it instead of SetMeasureTextFunction when text is aligned with ALIGN_Y_BASELINE, clay then knows where the baseline of each font is.
+// - userData is a pointer that will be transparently passed through when the measureTextMetricsFunction is called.
+func (c *Context) SetMeasureTextMetricsFunction(fn MeasureTextMetricsFn, userData any) {
+	c.measureTextMetrics = fn
+	c.measureText = nil
+	c.textShaper = nil
+	if fn != nil {
+		c.measureText = func(text string, config *TextElementConfig, userData any) Dimensions {
+			return fn(text, config, userData).Dimensions
+		}
+	}
 	c.measureTextUserData = userData
 }
 
+// Binds a text shaper that Clay will use to measure and wrap text, replacing the measure text function.
+// Text render commands then carry the shaped glyph run in TextRenderData.Glyphs, so renderers don't need to shape the text again.
+// Words are shaped one by one while wrapping, each rendered line is shaped as a whole so kerning across word boundaries is kept.
+// Passing nil removes the shaper and the measure text function.
+func (c *Context) SetTextShaper(shaper TextShaper) {
+	c.textShaper = shaper
+	c.measureText = nil
+	c.measureTextMetrics = nil
+	if shaper != nil {
+		c.measureTextMetrics = func(text string, config *TextElementConfig, userData any) TextMetrics {
+			run := shaper.Shape(text, config, c.measureGlyphs[:0])
+			c.measureGlyphs = run.Glyphs
+			return run.Metrics
+		}
+		c.measureText = func(text string, config *TextElementConfig, userData any) Dimensions {
+			return c.measureTextMetrics(text, config, userData).Dimensions
+		}
+	}
+}
+
+// Returns the position of a caret placed before the byte at index in the text of a text element, relative to the root of the layout.
+// The position is the top of the caret, its height is the line height of the text.
+// - id is the id of the text element, or of an element whose first text child is queried.
+// Returns false if no plain text element was found during the last layout.
+func (c *Context) GetTextCaretPosition(id ElementId, index int) (Vector2, bool) {
+	return c.textCaretPosition(id, index)
+}
+
+// Returns the byte index in the text of a text element of the caret closest to position, for example to place a caret on click.
+// - id is the id of the text element, or of an element whose first text child is queried.
+// Returns false if no plain text element was found during the last layout.
+func (c *Context) GetTextIndexAtPosition(id ElementId, position Vector2) (int, bool) {
+	return c.textIndexAtPosition(id, position)
+}
+
 // Experimental - Used in cases where Clay needs to integrate with a system that manages its own scrolling containers externally.
 // Please reach out if you plan to use this function, as it may be subject to change.
 func (c *Context) SetQueryScrollOffsetFunction(fn QueryScrollOffsetFn, userData any) {
-	queryScrollOffset = fn
+	c.queryScrollOffset = fn
 	c.queryScrollOffsetUserData = userData
 }
 
@@ -439,28 +519,89 @@ func (c *Context) SetRenderTranclucentEnabled(v bool) {
 	c.renderTranslucent = v
 }
 
+// Enables and disables right to left mode, used for Arabic and Hebrew user interfaces.
+// Elements declared while the mode is enabled are mirrored: LEFT_TO_RIGHT and RIGHT_TO_LEFT directions, left and right padding and margins,
+// ALIGN_X_LEFT and ALIGN_X_RIGHT, text alignment and floating attach points swap places, grid columns are placed from the right,
+// and floating offsets are negated on the x axis.
+// Lines of text are also reordered with a right to left base direction.
+// This state is retained and does not need to be set each frame.
+func (c *Context) SetRightToLeftEnabled(enabled bool) {
+	c.rightToLeft = enabled
+}
+
+// Enables and disables strict validation, which reports declarations that are valid but most likely mistakes:
+// GROW elements in FIT parents, PERCENT sizes of children adding up to more than 1, negative child gaps and minimum sizes over the maximum.
+// Meant for tests and CI, the checks make EndLayout slower. This state is retained and does not need to be set each frame.
+func (c *Context) SetStrictValidationEnabled(enabled bool) {
+	c.strictValidation = enabled
+}
+
+// Returns true if strict validation is currently enabled.
+func (c *Context) IsStrictValidationEnabled() bool {
+	return c.strictValidation
+}
+
+// Returns true if right to left mode is currently enabled.
+func (c *Context) IsRightToLeftEnabled() bool {
+	return c.rightToLeft
+}
+
 // Returns the maximum number of UI elements supported by Clay's current configuration.
-///CLAY_DLL_EXPORT int32_t GetMaxElementCount(void);
+func (c *Context) GetMaxElementCount() int32 {
+	return c.maxElementCount
+}
 
 // Modifies the maximum number of UI elements supported by Clay's current configuration.
-// This may require reallocating additional memory, and re-calling clay.Initialize();
-///CLAY_DLL_EXPORT void SetMaxElementCount(int32_t maxElementCount);
+// Also limits the number of render commands. All memory is reallocated to the new size, which discards
+// scroll positions and the text measurement cache, so call it before BeginLayout rather than every frame.
+func (c *Context) SetMaxElementCount(maxElementCount int32) {
+	if maxElementCount < 2 {
+		c.reportError(ERROR_TYPE_ARENA_CAPACITY_EXCEEDED, "SetMaxElementCount() was called with a count smaller than 2, which is needed for the root container and one element. Using 2 instead.")
+	}
+	c.maxElementCount = max(maxElementCount, 2) // Room for the root container and one element
+	c.initializePersistentMemory()
+	c.initializeEphemeralMemory()
+}
 
 // Returns the maximum number of measured "words" (whitespace seperated runs of characters) that Clay can store in its internal text measurement cache.
-///CLAY_DLL_EXPORT int32_t GetMaxMeasureTextCacheWordCount(void);
+func (c *Context) GetMaxMeasureTextCacheWordCount() int32 {
+	return c.maxMeasureTextCacheWordCount
+}
 
 // Modifies the maximum number of measured "words" (whitespace seperated runs of characters) that Clay can store in its internal text measurement cache.
-// This may require reallocating additional memory, and re-calling clay.Initialize();
-///CLAY_DLL_EXPORT void SetMaxMeasureTextCacheWordCount(int32_t maxMeasureTextCacheWordCount);
+// All memory is reallocated to the new size, like SetMaxElementCount.
+func (c *Context) SetMaxMeasureTextCacheWordCount(maxMeasureTextCacheWordCount int32) {
+	if maxMeasureTextCacheWordCount < 1 {
+		c.reportError(ERROR_TYPE_ARENA_CAPACITY_EXCEEDED, "SetMaxMeasureTextCacheWordCount() was called with a count smaller than 1. Using 1 instead.")
+	}
+	c.maxMeasureTextCacheWordCount = max(maxMeasureTextCacheWordCount, 1)
+	c.initializePersistentMemory()
+	c.initializeEphemeralMemory()
+}
 
 // Resets Clay's internal text measurement cache. Useful if font mappings have changed or fonts have been reloaded.
 func (c *Context) ResetMeasureTextCache() {
 	c.measuredWords = c.measuredWords[0:0]
 	c.measuredWordsFreeList = c.measuredWordsFreeList[0:0]
+	c.measureTextCacheItems = c.measureTextCacheItems[0:0]
+	c.measureTextCacheFreeList = c.measureTextCacheFreeList[0:0]
+	c.measureTextCacheHead = -1
+	c.measureTextCacheTail = -1
 	clear(c.measureTextHashMap)
 }
 
+// Returns counters collected by this context since it was initialized.
+func (c *Context) Stats() Stats {
+	stats := c.stats
+	stats.MeasureTextCacheEntries = len(c.measureTextHashMap)
+	stats.MeasureTextCacheWords = len(c.measuredWords) - len(c.measuredWordsFreeList)
+	return stats
+}
+
 func (c *Context) CLAY(e ElementDeclaration, fns ...func()) {
+	if captureDeclarationLocations {
+		c.declarationLocation = callerLocation()
+	}
 	if !c.openElement() {
 		return
 	}
@@ -473,6 +614,9 @@ func (c *Context) CLAY(e ElementDeclaration, fns ...func()) {
 }
 
 func (c *Context) CLAY_ID(id ElementId, e ElementDeclaration, fns ...func()) {
+	if captureDeclarationLocations {
+		c.declarationLocation = callerLocation()
+	}
 	if !c.openElementWithId(id) {
 		return
 	}
@@ -485,9 +629,22 @@ func (c *Context) CLAY_ID(id ElementId, e ElementDeclaration, fns ...func()) {
 }
 
 func (c *Context) CLAY_TEXT(text string, config *TextElementConfig) {
+	if captureDeclarationLocations {
+		c.declarationLocation = callerLocation()
+	}
 	c.openTextElement(text, config)
 }
 
+// Declares a single text element made of several spans with their own configs, e.g. for inline bold text or links.
+// The spans wrap together as one paragraph and generate one TEXT render command per span fragment per line.
+// - config controls wrapping, alignment and line height of the paragraph and is used for spans without a Config.
+func (c *Context) CLAY_RICH_TEXT(config *TextElementConfig, spans ...TextSpan) {
+	if captureDeclarationLocations {
+		c.declarationLocation = callerLocation()
+	}
+	c.openRichTextElement(spans, config)
+}
+
 func (c *Context) TEXT_CONFIG(config TextElementConfig) *TextElementConfig {
 	return c.storeTextElementConfig(config)
 }
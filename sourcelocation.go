@@ -0,0 +1,7 @@
+//go:build !claydebug
+
+package clay
+
+// Build with the claydebug tag to report the source locations of elements with duplicate ids.
+// Capturing them costs a runtime.Caller call and an allocation per element.
+const captureDeclarationLocations = false
@@ -0,0 +1,6 @@
+//go:build claydebug
+
+package clay
+
+// Built with the claydebug tag, CLAY calls remember their source location to report it for duplicate ids.
+const captureDeclarationLocations = true
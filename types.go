@@ -1,40 +1,58 @@
 package clay
 
 import (
-	"fmt"
 	"math"
+	"strconv"
+	"strings"
 
 	"github.com/igadmg/gamemath/rect2"
 	"golang.org/x/exp/constraints"
 )
 
 type MeasureTextFn func(text string, config *TextElementConfig, userData any) Dimensions
-type QueryScrollOffsetFn func(elementId uint32, userData any) Vector2
+type MeasureTextMetricsFn func(text string, config *TextElementConfig, userData any) TextMetrics
+type QueryScrollOffsetFn func(elementId uint64, userData any) Vector2
 
 // Primarily created via the ID(), IDI(), ID_LOCAL() and IDI_LOCAL() macros.
 // Represents a hashed string ID used for identifying and finding specific clay UI elements, required
 // by functions such as PointerOver() and GetElementData().
 type ElementId struct {
-	id uint32 // The resulting hash generated from the other fields.
-	//offset   uint32 // A numerical offset applied after computing the hash from stringId.
-	//baseId   uint32 // A base hash value to start from, for example the parent element ID is used when calculating ID_LOCAL().
+	id       uint64 // The resulting hash generated from the other fields.
+	offset   uint32 // The index passed to IDI() and IDI_LOCAL(), or the index in the parent of an anonymous element.
+	baseId   uint64 // The hash of the string id without the offset, or the parent id of an anonymous element.
 	stringId string // The string id to hash.
 }
 
-func ID(label string) ElementId            { return hashString(label) }
-func (*Context) ID(label string) ElementId { return hashString(label) }
+// Returns the string id with the index of indexed ids, e.g. "item[3]" for IDI("item", 3).
+// Anonymous elements are written as # followed by their index in the parent, e.g. "#2".
+func (id ElementId) String() string {
+	switch {
+	case id.baseId == id.id:
+		// ID() and ID_LOCAL()
+		return id.stringId
+	case id.stringId == "":
+		return "#" + strconv.FormatUint(uint64(id.offset), 10)
+	}
+	return id.stringId + "[" + strconv.FormatUint(uint64(id.offset), 10) + "]"
+}
 
+func ID(label string) ElementId            { return hashString(label, 0) }
+func (*Context) ID(label string) ElementId { return hashString(label, 0) }
+
+// The index of IDI("item", 3) is hashed into the id of "item" as a number, the string id of the element stays "item".
 func IDI(label string, index uint32) ElementId {
-	return hashString(fmt.Sprintf("%s[%d]", label, index))
+	return hashStringWithOffset(label, index, 0)
 }
 func (*Context) IDI(label string, index uint32) ElementId {
-	return hashString(fmt.Sprintf("%s[%d]", label, index))
+	return hashStringWithOffset(label, index, 0)
 }
+
+// The label is hashed with the id of the parent element as the seed, so it only has to be unique among the children of the parent.
 func (c *Context) ID_LOCAL(label string) ElementId {
-	return hashString(fmt.Sprintf("%s[:%d]", label, c.getParentElementId()))
+	return hashString(label, c.getParentElementId())
 }
 func (c *Context) IDI_LOCAL(label string, index uint32) ElementId {
-	return hashString(fmt.Sprintf("%s[%d:%d]", label, index, c.getParentElementId()))
+	return hashStringWithOffset(label, index, c.getParentElementId())
 }
 
 // Controls the "radius", or corner rounding of elements, including rectangles, borders and images.
@@ -68,6 +86,14 @@ const (
 	LEFT_TO_RIGHT LayoutDirection = iota
 	// Lays out child elements from top to bottom with increasing y.
 	TOP_TO_BOTTOM
+	// Lays out child elements from right to left with decreasing x.
+	RIGHT_TO_LEFT
+	// Lays out child elements in the cells of the columns and rows configured by LayoutConfig.Grid.
+	// Children fill the cells in row order unless they are placed by LayoutConfig.GridCell, and are aligned in their cell by ChildAlignment.
+	GRID
+	// Lays out all child elements on top of each other in the content box, later ones over earlier ones.
+	// Children are aligned in the content box by ChildAlignment, the FIT size is the size of the largest child on each axis.
+	STACK
 )
 
 func (d LayoutDirection) String() string {
@@ -76,15 +102,33 @@ func (d LayoutDirection) String() string {
 		return "TOP_TO_BOTTOM"
 	case LEFT_TO_RIGHT:
 		return "LEFT_TO_RIGHT"
+	case RIGHT_TO_LEFT:
+		return "RIGHT_TO_LEFT"
+	case GRID:
+		return "GRID"
+	case STACK:
+		return "STACK"
 	}
 
 	return ""
 }
 
+// Returns the direction as seen in a mirror, LEFT_TO_RIGHT and RIGHT_TO_LEFT swap places.
+func (d LayoutDirection) Mirror() LayoutDirection {
+	switch d {
+	case LEFT_TO_RIGHT:
+		return RIGHT_TO_LEFT
+	case RIGHT_TO_LEFT:
+		return LEFT_TO_RIGHT
+	}
+
+	return d
+}
+
 func (d LayoutDirection) IsAlongAxis(axis Axis) bool {
 	switch axis {
 	case AxisX:
-		return d == LEFT_TO_RIGHT
+		return d == LEFT_TO_RIGHT || d == RIGHT_TO_LEFT
 	case AxisY:
 		return d == TOP_TO_BOTTOM
 	}
@@ -104,6 +148,18 @@ const (
 	ALIGN_X_CENTER
 )
 
+// Returns the alignment as seen in a mirror, ALIGN_X_LEFT and ALIGN_X_RIGHT swap places.
+func (a LayoutAlignmentX) Mirror() LayoutAlignmentX {
+	switch a {
+	case ALIGN_X_LEFT:
+		return ALIGN_X_RIGHT
+	case ALIGN_X_RIGHT:
+		return ALIGN_X_LEFT
+	}
+
+	return a
+}
+
 // Controls the alignment along the y axis (vertical) of child elements.
 type LayoutAlignmentY uint8
 
@@ -114,8 +170,77 @@ const (
 	ALIGN_Y_BOTTOM
 	// Aligns child elements vertically to the center of this element
 	ALIGN_Y_CENTER
+	// Aligns the first baseline of text child elements in a left to right container.
+	// Child elements without text are aligned by their bottom edge. Behaves as ALIGN_Y_TOP in top to bottom containers.
+	ALIGN_Y_BASELINE
 )
 
+// Controls whether child elements that don't fit along the layout axis flow onto additional lines.
+type LayoutWrap uint8
+
+const (
+	// (Default) Lays out all child elements on a single line, compressing them when they don't fit.
+	LAYOUT_WRAP_NONE LayoutWrap = iota
+	// Starts a new line whenever the next child element doesn't fit into the rest of the line.
+	// Lines are rows in LEFT_TO_RIGHT and RIGHT_TO_LEFT containers and columns in TOP_TO_BOTTOM containers.
+	// A FIT width covers every column, and FIT parents are sized around them. Columns of an element with a GROW height
+	// are only broken once heights are sized, its FIT ancestors then grow to include them without shrinking their siblings.
+	LAYOUT_WRAP
+)
+
+func (w LayoutWrap) String() string {
+	switch w {
+	case LAYOUT_WRAP_NONE:
+		return "NONE"
+	case LAYOUT_WRAP:
+		return "WRAP"
+	}
+
+	return ""
+}
+
+// Controls the alignment of the lines of a wrapping element along its non layout axis.
+type LayoutAlignmentLines uint8
+
+const (
+	// (Default) Packs the lines against the top of the element for rows, or the left hand side for columns, offset by padding.
+	ALIGN_LINES_START LayoutAlignmentLines = iota
+	// Packs the lines against the bottom of the element for rows, or the right hand side for columns, offset by padding.
+	ALIGN_LINES_END
+	// Packs the lines in the center of the element.
+	ALIGN_LINES_CENTER
+)
+
+// Controls how the space left over along the layout axis is spread between and around child elements.
+type LayoutDistribution uint8
+
+const (
+	// (Default) Packs the child elements together, aligned along the layout axis by ChildAlignment.
+	DISTRIBUTE_PACKED LayoutDistribution = iota
+	// Places the first and last child elements against the edges of the content box with equal space between each pair of children.
+	// A single child element is placed at the start.
+	DISTRIBUTE_SPACE_BETWEEN
+	// Gives every child element equal space on both sides, so the space at the edges is half the space between children.
+	DISTRIBUTE_SPACE_AROUND
+	// Makes the space between each pair of child elements and the space at the edges equal.
+	DISTRIBUTE_SPACE_EVENLY
+)
+
+func (d LayoutDistribution) String() string {
+	switch d {
+	case DISTRIBUTE_PACKED:
+		return "PACKED"
+	case DISTRIBUTE_SPACE_BETWEEN:
+		return "SPACE_BETWEEN"
+	case DISTRIBUTE_SPACE_AROUND:
+		return "SPACE_AROUND"
+	case DISTRIBUTE_SPACE_EVENLY:
+		return "SPACE_EVENLY"
+	}
+
+	return ""
+}
+
 // Controls how the element takes up space inside its parent container.
 type SizingType uint8
 
@@ -128,6 +253,12 @@ const (
 	SIZING_TYPE_PERCENT
 	// Clamps the axis size to an exact size in pixels.
 	SIZING_TYPE_FIXED
+	// Sizes the element to the smallest size of its contents, wrapping text at every word.
+	SIZING_TYPE_MIN_CONTENT
+	// Sizes the element to the preferred size of its contents, without wrapping text.
+	SIZING_TYPE_MAX_CONTENT
+	// Sizes the element to an expression of other sizings.
+	SIZING_TYPE_CALC
 )
 
 // Controls how child elements are aligned on each axis.
@@ -143,104 +274,266 @@ type SizingMinMax struct {
 	Max float32 // The largest final size of the element on this axis will be this value in pixels.
 }
 
-// Controls the sizing of this element along one axis inside its parent container.
-//type SizingAxis struct {
-//	// Controls the minimum and maximum size in pixels that this element is allowed to grow or shrink to, overriding sizing types such as FIT or GROW.
-//	// Expects 0-1 range. Clamps the axis size to a percent of the parent container's axis size minus padding and child gaps.
-//	data SizingMinMax
-//	Type SizingType // Controls how the element takes up space inside its parent container.
-//}
-
-type SizingAxisMinMax interface {
-	GetMinMax() SizingMinMax
-}
-
-// Controls the minimum and maximum size in pixels that this element is allowed to grow or shrink to, overriding sizing types such as FIT or GROW.
-type SizingAxisFixed struct {
+// Controls the sizing of this element along one axis inside its parent container. Build it with FIT, GROW, FIXED, PERCENT,
+// VW, VH, PERCENT_OF, MIN_CONTENT, MAX_CONTENT and CALC. It is a value, so sizing declared inline such as
+// Sizing{Width: FIXED(100)} doesn't allocate. The zero value is FIT without a minimum or maximum.
+type SizingAxis struct {
+	Type SizingType // Controls how the element takes up space inside its parent container.
+	// Controls the minimum and maximum size in pixels of every sizing type but CALC. A Max of 0 means there is no maximum,
+	// except for FIXED, where Min and Max are the size. PERCENT clamps the size after the percentage is taken.
 	MinMax SizingMinMax
+	// GROW: controls how much of the free space of its parent this element takes, relative to its GROW siblings.
+	// Siblings grow until their sizes are proportional to their weights, a weight of 2 next to a weight of 1 takes 2/3 of the space. Values <= 0 mean 1.
+	Weight float32
+	// FIT and GROW: controls how much this element gives up when its parent is too small, relative to its siblings. Like flex-shrink,
+	// each sibling gives up a share of the overflow proportional to its shrink weight times its size, down to its minimum size.
+	// Values <= 0 mean 1. Without a shrink weight on any sibling, the largest siblings are compressed first.
+	Shrink float32
+	// GROW: the size this element starts growing or shrinking from instead of the size of its content, still clamped by MinMax. 0 uses the size of the content.
+	Basis float32
+	// PERCENT: expects 0-1 range. Sizes the element to a percent of the parent container's axis size minus padding and child gaps,
+	// or of the size set by Of.
+	Percent float32
+	Of      PercentReference // PERCENT: controls what the percentage is taken of.
+	Element ElementId        // PERCENT: the ancestor the percentage is taken of for PERCENT_OF_ELEMENT.
+	// CALC: sizes the element to an expression of other sizings, evaluated when the element is sized inside its parent like PERCENT.
+	// Terms can be FIXED, PERCENT and CALC sizings, other sizing types count as 0. The result is never negative.
+	Operation CalcOperation
+	Terms     []SizingAxis // CALC: the terms of the expression. Building them allocates, keep CALC sizings that don't change between frames in variables.
 }
 
-func (s SizingAxisFixed) GetMinMax() SizingMinMax {
+// Deprecated: sizing is a SizingAxis value now, AnySizingAxis used to be the interface the sizing types were boxed in.
+type AnySizingAxis = SizingAxis
+
+// Returns the min and max size of the element, with math.MaxFloat32 for no maximum.
+func (s SizingAxis) GetMinMax() SizingMinMax {
+	if s.MinMax.Max == 0 && s.Type != SIZING_TYPE_FIXED {
+		return SizingMinMax{Min: s.MinMax.Min, Max: math.MaxFloat32}
+	}
 	return s.MinMax
 }
 
-// Controls the minimum and maximum size in pixels that this element is allowed to grow or shrink to, overriding sizing types such as FIT or GROW.
-type SizingAxisFit struct {
-	MinMax SizingMinMax
+// Returns true for the sizing types whose size is the size of the content, or a fixed size, clamped by MinMax.
+func (s SizingAxis) fitsContent() bool {
+	switch s.Type {
+	case SIZING_TYPE_FIT, SIZING_TYPE_GROW, SIZING_TYPE_FIXED, SIZING_TYPE_MIN_CONTENT, SIZING_TYPE_MAX_CONTENT:
+		return true
+	}
+	return false
 }
 
-func (s SizingAxisFit) GetMinMax() SizingMinMax {
-	return s.MinMax
+// Clamps a PERCENT size after the percentage is taken.
+func (s SizingAxis) clamp(size float32) float32 {
+	mm := s.GetMinMax()
+	return min(max(size, mm.Min), mm.Max)
 }
 
-// Controls the minimum and maximum size in pixels that this element is allowed to grow or shrink to, overriding sizing types such as FIT or GROW.
-type SizingAxisGrow struct {
-	MinMax SizingMinMax
+// Returns a copy of the GROW sizing with the grow weight set.
+func (s SizingAxis) WithWeight(weight float32) SizingAxis {
+	s.Weight = weight
+	return s
 }
 
-func (s SizingAxisGrow) GetMinMax() SizingMinMax {
-	return s.MinMax
+// Returns a copy of the FIT or GROW sizing with the shrink weight set.
+func (s SizingAxis) WithShrink(shrink float32) SizingAxis {
+	s.Shrink = shrink
+	return s
 }
 
-// Expects 0-1 range. Clamps the axis size to a percent of the parent container's axis size minus padding and child gaps.
-type SizingAxisPercent struct {
-	Percent float32
+// Returns a copy of the GROW sizing with the flex basis set.
+func (s SizingAxis) WithBasis(basis float32) SizingAxis {
+	s.Basis = basis
+	return s
 }
 
-type AnySizingAxis any
+// Controls what the percentage of PERCENT sizing is taken of.
+type PercentReference uint8
+
+const (
+	// (Default) The axis size of the parent container minus padding and child gaps.
+	PERCENT_OF_PARENT PercentReference = iota
+	// The width of the layout, like the CSS vw unit.
+	PERCENT_OF_VIEWPORT_WIDTH
+	// The height of the layout, like the CSS vh unit.
+	PERCENT_OF_VIEWPORT_HEIGHT
+	// The axis size of the ancestor set in Element minus its padding.
+	PERCENT_OF_ELEMENT
+)
+
+// Controls how the terms of CALC sizing are combined.
+type CalcOperation uint8
+
+const (
+	// (Default) Adds the terms together, a negative FIXED term subtracts.
+	CALC_OP_SUM CalcOperation = iota
+	// Takes the smallest of the terms.
+	CALC_OP_MIN
+	// Takes the largest of the terms.
+	CALC_OP_MAX
+)
+
+// Returns the sizing as it is written in a CALC expression, e.g. "100% + -48" for CALC(PERCENT(1), FIXED(-48)).
+func (s SizingAxis) String() string {
+	var b strings.Builder
+	s.writeTo(&b)
+	return b.String()
+}
+
+func (s SizingAxis) writeTo(b *strings.Builder) {
+	switch s.Type {
+	case SIZING_TYPE_FIXED:
+		b.WriteString(strconv.FormatFloat(float64(s.MinMax.Min), 'g', -1, 32))
+	case SIZING_TYPE_PERCENT:
+		b.WriteString(strconv.FormatFloat(float64(s.Percent*100), 'g', -1, 32))
+		switch s.Of {
+		case PERCENT_OF_VIEWPORT_WIDTH:
+			b.WriteString("vw")
+		case PERCENT_OF_VIEWPORT_HEIGHT:
+			b.WriteString("vh")
+		case PERCENT_OF_ELEMENT:
+			b.WriteString("% of ")
+			b.WriteString(s.Element.String())
+		default:
+			b.WriteString("%")
+		}
+	case SIZING_TYPE_CALC:
+		separator := " + "
+		switch s.Operation {
+		case CALC_OP_MIN:
+			b.WriteString("min(")
+			separator = ", "
+		case CALC_OP_MAX:
+			b.WriteString("max(")
+			separator = ", "
+		}
+		for i, term := range s.Terms {
+			if i > 0 {
+				b.WriteString(separator)
+			}
+			term.writeTo(b)
+		}
+		if s.Operation != CALC_OP_SUM {
+			b.WriteString(")")
+		}
+	default:
+		b.WriteString(SizingAxisTypeString(s))
+	}
+}
 
-func SizingAxisTypeString(a AnySizingAxis) string {
-	switch a.(type) {
-	case SizingAxisFixed:
+func SizingAxisTypeString(a SizingAxis) string {
+	switch a.Type {
+	case SIZING_TYPE_FIXED:
 		return "FIXED"
-	case SizingAxisFit:
+	case SIZING_TYPE_FIT:
 		return "FIT"
-	case SizingAxisGrow:
+	case SIZING_TYPE_GROW:
 		return "GROW"
-	case SizingAxisPercent:
+	case SIZING_TYPE_PERCENT:
 		return "PERCENT"
+	case SIZING_TYPE_MIN_CONTENT:
+		return "MIN_CONTENT"
+	case SIZING_TYPE_MAX_CONTENT:
+		return "MAX_CONTENT"
+	case SIZING_TYPE_CALC:
+		return "CALC"
 	}
 
 	return ""
 }
 
-func FIT[T Coordinate](s ...T) SizingAxisFit {
+// Returns the min and max set by the arguments of FIT, GROW, MIN_CONTENT and MAX_CONTENT.
+func sizingMinMaxArgs[T Coordinate](s []T) SizingMinMax {
 	switch len(s) {
 	case 0:
-		return SizingAxisFit{MinMax: SizingMinMax{Min: 0, Max: math.MaxFloat32}}
+		return SizingMinMax{Min: 0, Max: math.MaxFloat32}
 	case 1:
-		return SizingAxisFit{MinMax: SizingMinMax{Min: float32(s[0]), Max: math.MaxFloat32}}
+		return SizingMinMax{Min: float32(s[0]), Max: math.MaxFloat32}
 	default:
-		return SizingAxisFit{MinMax: SizingMinMax{Min: float32(s[0]), Max: float32(s[1])}}
+		return SizingMinMax{Min: float32(s[0]), Max: float32(s[1])}
 	}
 }
 
-func GROW[T Coordinate](s ...T) SizingAxisGrow {
-	switch len(s) {
-	case 0:
-		return SizingAxisGrow{MinMax: SizingMinMax{Min: 0, Max: math.MaxFloat32}}
-	case 1:
-		return SizingAxisGrow{MinMax: SizingMinMax{Min: float32(s[0]), Max: math.MaxFloat32}}
-	default:
-		return SizingAxisGrow{MinMax: SizingMinMax{Min: float32(s[0]), Max: float32(s[1])}}
-	}
+func FIT[T Coordinate](s ...T) SizingAxis {
+	return SizingAxis{Type: SIZING_TYPE_FIT, MinMax: sizingMinMaxArgs(s)}
+}
+
+func GROW[T Coordinate](s ...T) SizingAxis {
+	return SizingAxis{Type: SIZING_TYPE_GROW, MinMax: sizingMinMaxArgs(s)}
+}
+
+// Sizes the element to the smallest size its contents can take, such as the width of the longest word of text.
+// Unlike FIT, the element is neither grown nor compressed by its parent.
+func MIN_CONTENT[T Coordinate](s ...T) SizingAxis {
+	return SizingAxis{Type: SIZING_TYPE_MIN_CONTENT, MinMax: sizingMinMaxArgs(s)}
+}
+
+// Sizes the element to the preferred size of its contents, with text unwrapped.
+// Unlike FIT, the element is neither grown nor compressed by its parent, so it doesn't collapse when space is short.
+func MAX_CONTENT[T Coordinate](s ...T) SizingAxis {
+	return SizingAxis{Type: SIZING_TYPE_MAX_CONTENT, MinMax: sizingMinMaxArgs(s)}
+}
+
+func FIXED[T Coordinate](fixedSize T) SizingAxis {
+	return SizingAxis{Type: SIZING_TYPE_FIXED, MinMax: SizingMinMax{Min: float32(fixedSize), Max: float32(fixedSize)}}
+}
+
+func PERCENT(percentOfParent float32, minMax ...float32) SizingAxis {
+	return SizingAxis{Type: SIZING_TYPE_PERCENT, Percent: percentOfParent, MinMax: percentMinMax(minMax)}
+}
+
+// Sizes the element to a percent of the layout width, like the CSS vw unit.
+func VW(percentOfWidth float32, minMax ...float32) SizingAxis {
+	return SizingAxis{Type: SIZING_TYPE_PERCENT, Percent: percentOfWidth, Of: PERCENT_OF_VIEWPORT_WIDTH, MinMax: percentMinMax(minMax)}
+}
+
+// Sizes the element to a percent of the layout height, like the CSS vh unit.
+func VH(percentOfHeight float32, minMax ...float32) SizingAxis {
+	return SizingAxis{Type: SIZING_TYPE_PERCENT, Percent: percentOfHeight, Of: PERCENT_OF_VIEWPORT_HEIGHT, MinMax: percentMinMax(minMax)}
+}
+
+// Sizes the element to a percent of the size of an ancestor on the same axis.
+func PERCENT_OF(ancestor ElementId, percentOfAncestor float32, minMax ...float32) SizingAxis {
+	return SizingAxis{Type: SIZING_TYPE_PERCENT, Percent: percentOfAncestor, Of: PERCENT_OF_ELEMENT, Element: ancestor, MinMax: percentMinMax(minMax)}
+}
+
+// Sizes the element to the sum of the terms, e.g. CALC(PERCENT(1), FIXED(-48)) for the full width minus a 48px sidebar.
+func CALC(terms ...SizingAxis) SizingAxis {
+	return SizingAxis{Type: SIZING_TYPE_CALC, Operation: CALC_OP_SUM, Terms: terms}
 }
 
-func FIXED[T Coordinate](fixedSize T) SizingAxisFixed {
-	return SizingAxisFixed{MinMax: SizingMinMax{Min: float32(fixedSize), Max: float32(fixedSize)}}
+// Sizes the element to the smallest of the terms.
+func CALC_MIN(terms ...SizingAxis) SizingAxis {
+	return SizingAxis{Type: SIZING_TYPE_CALC, Operation: CALC_OP_MIN, Terms: terms}
 }
 
-func PERCENT(percentOfParent float32) SizingAxisPercent {
-	return SizingAxisPercent{Percent: percentOfParent}
+// Sizes the element to the largest of the terms.
+func CALC_MAX(terms ...SizingAxis) SizingAxis {
+	return SizingAxis{Type: SIZING_TYPE_CALC, Operation: CALC_OP_MAX, Terms: terms}
+}
+
+// Sizes the element to value, but no smaller than minimum and no larger than maximum, like the CSS clamp() function.
+func CALC_CLAMP(minimum SizingAxis, value SizingAxis, maximum SizingAxis) SizingAxis {
+	return CALC_MAX(minimum, CALC_MIN(value, maximum))
+}
+
+func percentMinMax(minMax []float32) SizingMinMax {
+	switch len(minMax) {
+	case 0:
+		return SizingMinMax{Min: 0, Max: math.MaxFloat32}
+	case 1:
+		return SizingMinMax{Min: minMax[0], Max: math.MaxFloat32}
+	default:
+		return SizingMinMax{Min: minMax[0], Max: minMax[1]}
+	}
 }
 
 // Controls the sizing of this element along one axis inside its parent container.
 type Sizing struct {
-	Width  AnySizingAxis // Controls the width sizing of the element, along the x axis.
-	Height AnySizingAxis // Controls the height sizing of the element, along the y axis.
+	Width  SizingAxis // Controls the width sizing of the element, along the x axis.
+	Height SizingAxis // Controls the height sizing of the element, along the y axis.
 }
 
-func (s Sizing) GetAxis(axis Axis) AnySizingAxis {
+func (s Sizing) GetAxis(axis Axis) SizingAxis {
 	switch axis {
 	case AxisX:
 		return s.Width
@@ -248,7 +541,82 @@ func (s Sizing) GetAxis(axis Axis) AnySizingAxis {
 		return s.Height
 	}
 
-	return SizingAxisFit{}
+	return SizingAxis{}
+}
+
+// Controls how the size of a column or row of a GRID element is computed.
+type GridTrackType uint8
+
+const (
+	// (default) Wraps tightly to the size of the largest child element in the track.
+	GRID_TRACK_FIT GridTrackType = iota
+	// Clamps the track size to an exact size in pixels.
+	GRID_TRACK_FIXED
+	// Takes a fraction of the space left by the other tracks, shared with the other FR tracks in proportion to their fractions.
+	GRID_TRACK_FR
+)
+
+// Controls the size of a column or row of a GRID element.
+type GridTrack struct {
+	Type   GridTrackType
+	Size   float32      // The size in pixels of GRID_TRACK_FIXED tracks, the fraction of GRID_TRACK_FR tracks.
+	MinMax SizingMinMax // Controls the minimum and maximum size in pixels of GRID_TRACK_FIT and GRID_TRACK_FR tracks.
+}
+
+func TRACK_FIT(minMax ...float32) GridTrack {
+	switch len(minMax) {
+	case 0:
+		return GridTrack{Type: GRID_TRACK_FIT, MinMax: SizingMinMax{Min: 0, Max: math.MaxFloat32}}
+	case 1:
+		return GridTrack{Type: GRID_TRACK_FIT, MinMax: SizingMinMax{Min: minMax[0], Max: math.MaxFloat32}}
+	default:
+		return GridTrack{Type: GRID_TRACK_FIT, MinMax: SizingMinMax{Min: minMax[0], Max: minMax[1]}}
+	}
+}
+
+func TRACK_FIXED[T Coordinate](fixedSize T) GridTrack {
+	return GridTrack{Type: GRID_TRACK_FIXED, Size: float32(fixedSize), MinMax: SizingMinMax{Min: float32(fixedSize), Max: float32(fixedSize)}}
+}
+
+func TRACK_FR(fraction float32, minMax ...float32) GridTrack {
+	switch len(minMax) {
+	case 0:
+		return GridTrack{Type: GRID_TRACK_FR, Size: fraction, MinMax: SizingMinMax{Min: 0, Max: math.MaxFloat32}}
+	case 1:
+		return GridTrack{Type: GRID_TRACK_FR, Size: fraction, MinMax: SizingMinMax{Min: minMax[0], Max: math.MaxFloat32}}
+	default:
+		return GridTrack{Type: GRID_TRACK_FR, Size: fraction, MinMax: SizingMinMax{Min: minMax[0], Max: minMax[1]}}
+	}
+}
+
+func (t GridTrack) String() string {
+	switch t.Type {
+	case GRID_TRACK_FIT:
+		return "FIT"
+	case GRID_TRACK_FIXED:
+		return "FIXED"
+	case GRID_TRACK_FR:
+		return "FR"
+	}
+
+	return ""
+}
+
+// Controls the columns and rows of a GRID element.
+type GridConfig struct {
+	Columns   []GridTrack // Without any columns the grid has a single FIT column.
+	Rows      []GridTrack // Rows are added after the declared ones as needed to place every child element, the added rows are FIT.
+	ColumnGap uint16      // Controls the gap in pixels between columns.
+	RowGap    uint16      // Controls the gap in pixels between rows.
+}
+
+// Places an element into the cells of its GRID parent. Columns and rows are numbered from 1, 0 places the element
+// automatically into the next free cells in row order. Elements with both a column and a row are placed first.
+type GridCell struct {
+	Column     uint16
+	Row        uint16
+	ColumnSpan uint16 // Number of columns the element covers, 0 is the same as 1.
+	RowSpan    uint16 // Number of rows the element covers, 0 is the same as 1.
 }
 
 // Controls "padding" in pixels, which is a gap between the bounding box of this element and where its children
@@ -265,14 +633,39 @@ func PADDING(padding ...uint16) Padding {
 	return Padding{p[0], p[1], p[2], p[3]}
 }
 
+// Controls "margin" in pixels, which is a gap between the bounding box of an element and its siblings, added to the
+// child gap of its parent, or the content box of its parent. Margins are ignored by floating and root elements.
+type Margin struct {
+	Left   uint16
+	Right  uint16
+	Top    uint16
+	Bottom uint16
+}
+
+func MARGIN(margin ...uint16) Margin {
+	m := unpackMargins[uint16](margin...)
+	return Margin{m[0], m[1], m[2], m[3]}
+}
+
 // Controls various settings that affect the size and position of an element, as well as the sizes and positions
 // of any child elements.
 type LayoutConfig struct {
-	Sizing          Sizing          // Controls the sizing of this element inside it's parent container, including FIT, GROW, PERCENT and FIXED sizing.
-	Padding         Padding         // Controls "padding" in pixels, which is a gap between the bounding box of this element and where its children will be placed.
-	ChildGap        uint16          // Controls the gap in pixels between child elements along the layout axis (horizontal gap for LEFT_TO_RIGHT, vertical gap for TOP_TO_BOTTOM).
-	ChildAlignment  ChildAlignment  // Controls how child elements are aligned on each axis.
-	LayoutDirection LayoutDirection // Controls the direction in which child elements will be automatically laid out.
+	Sizing         Sizing         // Controls the sizing of this element inside it's parent container, including FIT, GROW, PERCENT and FIXED sizing.
+	Padding        Padding        // Controls "padding" in pixels, which is a gap between the bounding box of this element and where its children will be placed.
+	Margin         Margin         // Controls "margin" in pixels, which is a gap around the bounding box of this element that pushes it away from its siblings.
+	ChildGap       uint16         // Controls the gap in pixels between child elements along the layout axis (horizontal gap for LEFT_TO_RIGHT, vertical gap for TOP_TO_BOTTOM).
+	ChildAlignment ChildAlignment // Controls how child elements are aligned on each axis.
+	// Controls how the space left over along the layout axis is spread between and around child elements, in addition to ChildGap.
+	// Overrides ChildAlignment along the layout axis unless it is DISTRIBUTE_PACKED. Lines of a wrapping element are distributed one by one.
+	ChildDistribution LayoutDistribution
+	LayoutDirection   LayoutDirection // Controls the direction in which child elements will be automatically laid out.
+	Wrap              LayoutWrap      // Controls whether child elements that don't fit along the layout axis flow onto additional lines.
+	LineGap           uint16          // Controls the gap in pixels between the lines of a wrapping element (vertical gap for rows, horizontal gap for columns).
+	// Controls how the lines of a wrapping element are aligned along the non layout axis.
+	// Children are aligned inside their line by ChildAlignment.
+	LineAlignment LayoutAlignmentLines
+	Grid          GridConfig // Controls the columns and rows of an element with the GRID layout direction.
+	GridCell      GridCell   // Places the element into a cell of its parent when the parent has the GRID layout direction.
 }
 
 var default_LayoutConfig LayoutConfig = LayoutConfig{
@@ -303,6 +696,14 @@ func WithPadding(cfg Padding) ElementOptionsFn {
 	}
 }
 
+func WithMargin(cfg Margin) ElementOptionsFn {
+	return func(ed ElementDeclaration) ElementDeclaration {
+		ed.Layout.Margin = cfg
+		return ed
+	}
+}
+
+// Negative gaps wrap around to very large ones, strict validation reports them as ERROR_TYPE_NEGATIVE_CHILD_GAP.
 func WithChildGap[T constraints.Signed](gap T) ElementOptionsFn {
 	return func(ed ElementDeclaration) ElementDeclaration {
 		ed.Layout.ChildGap = uint16(gap)
@@ -325,6 +726,13 @@ func WithChildAligns(x LayoutAlignmentX, y LayoutAlignmentY) ElementOptionsFn {
 	}
 }
 
+func WithChildDistribution(cfg LayoutDistribution) ElementOptionsFn {
+	return func(ed ElementDeclaration) ElementDeclaration {
+		ed.Layout.ChildDistribution = cfg
+		return ed
+	}
+}
+
 func WithLayoutDirection(cfg LayoutDirection) ElementOptionsFn {
 	return func(ed ElementDeclaration) ElementDeclaration {
 		ed.Layout.LayoutDirection = cfg
@@ -332,6 +740,43 @@ func WithLayoutDirection(cfg LayoutDirection) ElementOptionsFn {
 	}
 }
 
+func WithGrid(cfg GridConfig) ElementOptionsFn {
+	return func(ed ElementDeclaration) ElementDeclaration {
+		ed.Layout.LayoutDirection = GRID
+		ed.Layout.Grid = cfg
+		return ed
+	}
+}
+
+func WithGridCell(cfg GridCell) ElementOptionsFn {
+	return func(ed ElementDeclaration) ElementDeclaration {
+		ed.Layout.GridCell = cfg
+		return ed
+	}
+}
+
+func WithWrap(cfg LayoutWrap) ElementOptionsFn {
+	return func(ed ElementDeclaration) ElementDeclaration {
+		ed.Layout.Wrap = cfg
+		return ed
+	}
+}
+
+// Negative gaps wrap around to very large ones, strict validation reports them as ERROR_TYPE_NEGATIVE_CHILD_GAP.
+func WithLineGap[T constraints.Signed](gap T) ElementOptionsFn {
+	return func(ed ElementDeclaration) ElementDeclaration {
+		ed.Layout.LineGap = uint16(gap)
+		return ed
+	}
+}
+
+func WithLineAlignment(cfg LayoutAlignmentLines) ElementOptionsFn {
+	return func(ed ElementDeclaration) ElementDeclaration {
+		ed.Layout.LineAlignment = cfg
+		return ed
+	}
+}
+
 // Controls how text "wraps", that is how it is broken into multiple lines when there is insufficient horizontal space.
 type TextElementConfigWrapMode uint8
 
@@ -369,6 +814,18 @@ const (
 	TEXT_ALIGN_RIGHT
 )
 
+// Returns the alignment as seen in a mirror, TEXT_ALIGN_LEFT and TEXT_ALIGN_RIGHT swap places.
+func (a TextAlignment) Mirror() TextAlignment {
+	switch a {
+	case TEXT_ALIGN_LEFT:
+		return TEXT_ALIGN_RIGHT
+	case TEXT_ALIGN_RIGHT:
+		return TEXT_ALIGN_LEFT
+	}
+
+	return a
+}
+
 func (a TextAlignment) String() string {
 	switch a {
 	case TEXT_ALIGN_LEFT:
@@ -411,9 +868,60 @@ type TextElementConfig struct {
 
 var default_TextElementConfig TextElementConfig
 
+// A run of text inside a rich text element, see CLAY_RICH_TEXT.
+// All spans of one element wrap together as a single paragraph.
+type TextSpan struct {
+	// The text of this span. Spans are concatenated as is, so put whitespace where words should break.
+	Text string
+	// Controls font, color, size and user data of this span. A nil Config uses the paragraph config.
+	Config *TextElementConfig
+}
+
+// Result of a measure text metrics function, see SetMeasureTextMetricsFunction.
+type TextMetrics struct {
+	// The width and height of the measured text, same as returned by a plain measure text function.
+	Dimensions Dimensions
+	// The distance from the top of the text to its baseline.
+	Ascent float32
+	// The distance from the baseline to the bottom of the text. The height left by the ascent and descent, such as the line
+	// gap of the font, is split evenly above and below the text when its baseline is placed. 0 means the text fills the
+	// height below the baseline.
+	Descent float32
+}
+
+// A single positioned glyph produced by a TextShaper.
+type ShapedGlyph struct {
+	// The glyph identifier in the font, interpretation is up to the shaper and the renderer.
+	// Zero when the shaper has no glyph index for the character.
+	GlyphId uint32
+	// The horizontal distance to move the pen after drawing this glyph, including letter spacing.
+	Advance float32
+	// The offset of the glyph from the pen position, used for marks and kerning adjustments.
+	Offset Vector2
+	// Byte offset into the shaped text of the first character of the cluster this glyph belongs to.
+	// Several glyphs can share a cluster (decomposition) and a glyph can cover several characters (ligatures).
+	Cluster int32
+}
+
+// Result of shaping a run of text with a TextShaper.
+type ShapedRun struct {
+	// The dst slice passed to Shape with the glyphs of the run appended in visual order.
+	Glyphs  []ShapedGlyph
+	Metrics TextMetrics
+}
+
+// Converts runs of text into positioned glyphs, see SetTextShaper.
+// A shaper is an alternative to a measure text function for text that needs ligatures, kerning or complex script support.
+type TextShaper interface {
+	// Shapes the text with the provided config. Glyphs are appended to dst and returned in ShapedRun.Glyphs.
+	Shape(text string, config *TextElementConfig, dst []ShapedGlyph) ShapedRun
+}
+
 // Aspect Ratio --------------------------------
 
-// Controls various settings related to aspect ratio scaling element.
+// Controls various settings related to aspect ratio scaling element. Works for any element, including containers:
+// the height follows the width while widths are sized, so FIT parents, GROW siblings and the children are sized around it.
+// A FIT width with a PERCENT or CALC height follows the height instead.
 type AspectRatioElementConfig struct {
 	AspectRatio float32 // A float representing the target "Aspect ratio" for an element, which is its final width divided by its final height.
 }
@@ -447,6 +955,18 @@ const (
 	ATTACH_POINT_RIGHT_BOTTOM
 )
 
+// Returns the attach point as seen in a mirror, left and right attach points swap places.
+func (p FloatingAttachPointType) Mirror() FloatingAttachPointType {
+	switch p {
+	case ATTACH_POINT_LEFT_TOP, ATTACH_POINT_LEFT_CENTER, ATTACH_POINT_LEFT_BOTTOM:
+		return p + ATTACH_POINT_RIGHT_TOP
+	case ATTACH_POINT_RIGHT_TOP, ATTACH_POINT_RIGHT_CENTER, ATTACH_POINT_RIGHT_BOTTOM:
+		return p - ATTACH_POINT_RIGHT_TOP
+	}
+
+	return p
+}
+
 // Controls where a floating element is offset relative to its parent element.
 type FloatingAttachPoints struct {
 	Element FloatingAttachPointType // Controls the origin point on a floating element that attaches to its parent.
@@ -497,7 +1017,7 @@ type FloatingElementConfig struct {
 	Expand Dimensions
 	// When used in conjunction with .attachTo = ATTACH_TO_ELEMENT_WITH_ID, attaches this floating element to the element in the hierarchy with the provided ID.
 	// Hint: attach the ID to the other element with .id = ID("yourId"), and specify the id the same way, with .ParentId = ID("yourId").id
-	ParentId uint32
+	ParentId uint64
 	// Controls the z index of this floating element and all its children. Floating elements are sorted in ascending z order before output.
 	// ZIndex is also passed to the renderer for all elements contained within this floating element.
 	ZIndex int16
@@ -582,6 +1102,7 @@ type BorderWidth struct {
 	// Creates borders between each child element, depending on the .layoutDirection.
 	// e.g. for LEFT_TO_RIGHT, borders will be vertical lines, and for TOP_TO_BOTTOM borders will be horizontal lines.
 	// .BetweenChildren borders will result in individual RECTANGLE render commands being generated.
+	// They are not drawn between the children of elements with LAYOUT_WRAP or the GRID and STACK layout directions.
 	BetweenChildren uint16
 }
 
@@ -631,6 +1152,16 @@ type TextRenderData struct {
 	LetterSpacing uint16
 	// The height of the bounding box for this line of text.
 	LineHeight uint16
+	// The shaped glyphs of StringContents, only set when a text shaper is used. Renderers can draw them without shaping the text again.
+	// Note: the slice is only valid until the next call to BeginLayout.
+	Glyphs []ShapedGlyph
+	// The distance from the top of the bounding box to the baseline of this line of text.
+	// Equals the ascent returned by the measure text metrics function plus half of the height left by the ascent and descent,
+	// or the line height if only dimensions were measured.
+	BaselineOffset float32
+	// True when this run of text is right to left, StringContents is always in logical order.
+	// Set for Hebrew or Arabic runs found by the bidi algorithm, renderers should draw such runs right to left.
+	RightToLeft bool
 }
 
 // Render command data when commandType == CLAY_RENDER_COMMAND_TYPE_RECTANGLE
@@ -692,11 +1223,27 @@ type ScissorsEndData struct {
 	ClipRenderData
 }
 
+// The render data types stored in the pools of a context, the types whose pointers implement AnyRenderData.
+// ClipRenderData is only embedded in ScissorsStartData and ScissorsEndData, it is never render data on its own.
 type RenderDataType interface {
-	RectangleRenderData | TextRenderData | ImageRenderData | CustomRenderData | BorderRenderData | ClipRenderData | ScissorsStartData | ScissorsEndData
+	RectangleRenderData | TextRenderData | ImageRenderData | CustomRenderData | BorderRenderData | ScissorsStartData | ScissorsEndData
+}
+
+// The data of a render command, one of *RectangleRenderData, *TextRenderData, *ImageRenderData, *CustomRenderData,
+// *BorderRenderData, *ScissorsStartData or *ScissorsEndData.
+// Render data is stored by pointer so that building render commands doesn't allocate. Asserting the value types,
+// as in cmd.RenderData.(TextRenderData), doesn't compile; switch on the pointer types or use the RenderCommand accessors.
+type AnyRenderData interface {
+	isRenderData()
 }
 
-type AnyRenderData any
+func (*RectangleRenderData) isRenderData() {}
+func (*TextRenderData) isRenderData()      {}
+func (*ImageRenderData) isRenderData()     {}
+func (*CustomRenderData) isRenderData()    {}
+func (*BorderRenderData) isRenderData()    {}
+func (*ScissorsStartData) isRenderData()   {}
+func (*ScissorsEndData) isRenderData()     {}
 
 // Miscellaneous Structs & Enums ---------------------------------
 
@@ -726,18 +1273,61 @@ type ElementData struct {
 type RenderCommand struct {
 	// A rectangular box that fully encloses this UI element, with the position relative to the root of the layout.
 	BoundingBox rect2.Float32
-	// A struct union containing data specific to this command's commandType.
+	// A pointer to the data specific to this command's type, e.g. *TextRenderData or *RectangleRenderData.
+	// Note: the data is reused by the next layout and is only valid until the next call to BeginLayout.
 	RenderData AnyRenderData
 	// A pointer transparently passed through from the original element declaration.
 	UserData any
 	// The Id of this element, transparently passed through from the original element declaration.
-	Id uint32
+	Id uint64
 	// The z order required for drawing this command correctly.
 	// Note: the render command array is already sorted in ascending order, and will produce correct results if drawn in naive order.
 	// This field is intended for use in batching renderers for improved performance.
 	ZIndex int16
 }
 
+// Returns the rectangle data of the command, or nil if it draws something else.
+func (r *RenderCommand) Rectangle() *RectangleRenderData {
+	data, _ := r.RenderData.(*RectangleRenderData)
+	return data
+}
+
+// Returns the text data of the command, or nil if it draws something else.
+func (r *RenderCommand) Text() *TextRenderData {
+	data, _ := r.RenderData.(*TextRenderData)
+	return data
+}
+
+// Returns the image data of the command, or nil if it draws something else.
+func (r *RenderCommand) Image() *ImageRenderData {
+	data, _ := r.RenderData.(*ImageRenderData)
+	return data
+}
+
+// Returns the custom data of the command, or nil if it draws something else.
+func (r *RenderCommand) Custom() *CustomRenderData {
+	data, _ := r.RenderData.(*CustomRenderData)
+	return data
+}
+
+// Returns the border data of the command, or nil if it draws something else.
+func (r *RenderCommand) Border() *BorderRenderData {
+	data, _ := r.RenderData.(*BorderRenderData)
+	return data
+}
+
+// Returns the clip data of the command if it starts clipping, or nil otherwise.
+func (r *RenderCommand) ScissorsStart() *ScissorsStartData {
+	data, _ := r.RenderData.(*ScissorsStartData)
+	return data
+}
+
+// Returns the clip data of the command if it ends clipping, or nil otherwise.
+func (r *RenderCommand) ScissorsEnd() *ScissorsEndData {
+	data, _ := r.RenderData.(*ScissorsEndData)
+	return data
+}
+
 // Represents the current state of interaction with clay this frame.
 type PointerDataInteractionState uint8
 
@@ -876,7 +1466,7 @@ const (
 	// A text measurement function wasn't provided using Clay_SetMeasureTextFunction(), or the provided function was null.
 	ERROR_TYPE_TEXT_MEASUREMENT_FUNCTION_NOT_PROVIDED ErrorType = iota
 	// Clay attempted to allocate its internal data structures but ran out of space.
-	// The arena passed to Clay_Initialize was created with a capacity smaller than that required by Clay_MinMemorySize().
+	// Reported when SetMaxElementCount() or SetMaxMeasureTextCacheWordCount() is called with a count too small to hold them.
 	ERROR_TYPE_ARENA_CAPACITY_EXCEEDED
 	// Clay ran out of capacity in its internal array for storing elements. This limit can be increased with Clay_SetMaxElementCount().
 	ERROR_TYPE_ELEMENTS_CAPACITY_EXCEEDED
@@ -890,24 +1480,71 @@ const (
 	ERROR_TYPE_PERCENTAGE_OVER_1
 	// Clay encountered an internal error. It would be wonderful if you could report this so we can fix it!
 	ERROR_TYPE_INTERNAL_ERROR
-	// Clay__OpenElement was called more times than Clay__CloseElement, so there were still remaining open elements when the layout ended.
+	// Elements were still open when the layout ended, e.g. because a panic inside a CLAY() child function was recovered,
+	// or more elements were closed than opened, e.g. because EndLayout was called twice.
 	ERROR_TYPE_UNBALANCED_OPEN_CLOSE
+	// Strict validation: a GROW element was declared along the layout axis of a FIT parent without a minimum size,
+	// the parent wraps its children tightly so there is never any space to grow into.
+	ERROR_TYPE_GROW_IN_FIT_PARENT
+	// Strict validation: the PERCENT sizes of the children along their parent's layout axis add up to more than 1.
+	ERROR_TYPE_PERCENTAGE_SUM_OVER_1
+	// Strict validation: the child gap is larger than 32767, which is what a negative value passed to WithChildGap() turns into.
+	ERROR_TYPE_NEGATIVE_CHILD_GAP
+	// Strict validation: an element was declared with a minimum size larger than its maximum size.
+	ERROR_TYPE_SIZING_MIN_OVER_MAX
+	// Two elements declared with different ids within one layout have the same hash.
+	ERROR_TYPE_HASH_COLLISION
+	// An element was declared with PERCENT_OF_ELEMENT sizing, but the element with the provided id isn't one of its ancestors.
+	// The percentage is taken of the parent instead.
+	ERROR_TYPE_PERCENT_REFERENCE_NOT_FOUND
 )
 
+// Counters collected by a Context, see Context.Stats.
+type Stats struct {
+	// Number of texts whose measurement was found in the measure text cache.
+	MeasureTextCacheHits uint64
+	// Number of texts that had to be measured with the measure text function.
+	MeasureTextCacheMisses uint64
+	// Number of least recently used texts removed from the cache to make room for new ones.
+	MeasureTextCacheEvictions uint64
+	// Number of texts currently stored in the measure text cache.
+	MeasureTextCacheEntries int
+	// Number of measured words currently stored in the measure text cache, limited by SetMaxMeasureTextCacheWordCount.
+	MeasureTextCacheWords int
+}
+
 // Data to identify the error that clay has encountered.
 type ErrorData struct {
 	// Represents the type of error clay encountered while computing layout.
 	// ERROR_TYPE_TEXT_MEASUREMENT_FUNCTION_NOT_PROVIDED - A text measurement function wasn't provided using Clay_SetMeasureTextFunction(), or the provided function was null.
-	// ERROR_TYPE_ARENA_CAPACITY_EXCEEDED - Clay attempted to allocate its internal data structures but ran out of space. The arena passed to Clay_Initialize was created with a capacity smaller than that required by Clay_MinMemorySize().
+	// ERROR_TYPE_ARENA_CAPACITY_EXCEEDED - Clay attempted to allocate its internal data structures but ran out of space. The count passed to SetMaxElementCount() or SetMaxMeasureTextCacheWordCount() was too small.
 	// ERROR_TYPE_ELEMENTS_CAPACITY_EXCEEDED - Clay ran out of capacity in its internal array for storing elements. This limit can be increased with Clay_SetMaxElementCount().
 	// ERROR_TYPE_TEXT_MEASUREMENT_CAPACITY_EXCEEDED - Clay ran out of capacity in its internal array for storing elements. This limit can be increased with Clay_SetMaxMeasureTextCacheWordCount().
 	// ERROR_TYPE_DUPLICATE_ID - Two elements were declared with exactly the same ID within one layout.
+	// ERROR_TYPE_HASH_COLLISION - Two elements declared with different IDs within one layout have the same hash.
 	// ERROR_TYPE_FLOATING_CONTAINER_PARENT_NOT_FOUND - A floating element was declared using ATTACH_TO_ELEMENT_ID and either an invalid .parentId was provided or no element with the provided .parentId was found.
 	// ERROR_TYPE_PERCENTAGE_OVER_1 - An element was declared that using SIZING_PERCENT but the percentage value was over 1. Percentage values are expected to be in the 0-1 range.
+	// ERROR_TYPE_PERCENT_REFERENCE_NOT_FOUND - An element was declared using PERCENT_OF_ELEMENT and the element with the provided id isn't one of its ancestors.
 	// ERROR_TYPE_INTERNAL_ERROR - Clay encountered an internal error. It would be wonderful if you could report this so we can fix it!
+	// ERROR_TYPE_UNBALANCED_OPEN_CLOSE - Elements were still open when the layout ended, or more elements were closed than opened.
+	// ERROR_TYPE_GROW_IN_FIT_PARENT, ERROR_TYPE_PERCENTAGE_SUM_OVER_1, ERROR_TYPE_NEGATIVE_CHILD_GAP, ERROR_TYPE_SIZING_MIN_OVER_MAX -
+	// Suspicious declarations, only reported when strict validation is enabled with SetStrictValidationEnabled().
 	ErrorType ErrorType
 	// A string containing human-readable error text that explains the error in more detail.
 	ErrorText string
+	// The string id of the element that caused the error, empty for anonymous elements and errors that aren't caused by an element.
+	// Errors about text elements are reported for the element that contains the text.
+	StringId string
+	// The string ids of the elements from the root down to the element that caused the error, separated by "/".
+	// Anonymous elements are written as # followed by their index in the parent, e.g. "Clay__RootContainer/sidebar/#2".
+	ElementPath string
+	// For ERROR_TYPE_DUPLICATE_ID and ERROR_TYPE_HASH_COLLISION, the string id of the element that was declared first with the same ElementId.
+	FirstStringId string
+	// For ERROR_TYPE_DUPLICATE_ID and ERROR_TYPE_HASH_COLLISION, the Go source file and line of the CLAY call that declared the element,
+	// e.g. "/src/app/sidebar.go:42". Only captured when built with the claydebug build tag, empty otherwise.
+	SourceLocation string
+	// Source location of the element that was declared first with the same ElementId, see SourceLocation.
+	FirstSourceLocation string
 	// A transparent pointer passed through from when the error handler was first provided.
 	UserData any
 }
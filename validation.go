@@ -0,0 +1,60 @@
+package clay
+
+import "math"
+
+// Child gaps above this were most likely negative numbers converted to uint16 by WithChildGap().
+const maxPlausibleChildGap = math.MaxInt16
+
+// Checks the open element and its children for declarations that are valid but most likely mistakes.
+// Called from closeElement when strict validation is enabled, while the children are the last ones in the children buffer.
+func (c *Context) validateOpenElement(openLayoutElement *LayoutElement) {
+	layoutConfig := openLayoutElement.layoutConfig
+
+	if layoutConfig.ChildGap > maxPlausibleChildGap {
+		c.reportOpenElementError(ERROR_TYPE_NEGATIVE_CHILD_GAP, "An element was configured with a child gap over 32767 pixels, which is what a negative gap passed to WithChildGap() becomes.")
+	}
+	if layoutConfig.LineGap > maxPlausibleChildGap {
+		c.reportOpenElementError(ERROR_TYPE_NEGATIVE_CHILD_GAP, "An element was configured with a line gap over 32767 pixels, which is what a negative gap passed to WithLineGap() becomes.")
+	}
+	if sizingMinOverMax(layoutConfig.Sizing.Width) || sizingMinOverMax(layoutConfig.Sizing.Height) {
+		c.reportOpenElementError(ERROR_TYPE_SIZING_MIN_OVER_MAX, "An element was configured with a minimum size larger than its maximum size.")
+	}
+
+	if layoutConfig.LayoutDirection == GRID || layoutConfig.LayoutDirection == STACK {
+		// Children of a grid grow into and take percentages of their cell instead, children of a stack don't share the space
+		return
+	}
+
+	axis := AxisY
+	if layoutConfig.LayoutDirection.IsAlongAxis(AxisX) {
+		axis = AxisX
+	}
+	parentSizing := layoutConfig.Sizing.GetAxis(axis)
+	growChildInFit := false
+	var percentSum float32
+	children := c.layoutElementChildrenBuffer[len(c.layoutElementChildrenBuffer)-openLayoutElement.childrenCount:]
+	for _, childIndex := range children {
+		child := &c.layoutElements[childIndex]
+		if child.layoutConfig == nil {
+			continue
+		}
+		switch sizing := child.layoutConfig.Sizing.GetAxis(axis); sizing.Type {
+		case SIZING_TYPE_GROW:
+			growChildInFit = growChildInFit || parentSizing.Type == SIZING_TYPE_FIT && parentSizing.MinMax.Min == 0
+		case SIZING_TYPE_PERCENT:
+			if sizing.Of == PERCENT_OF_PARENT {
+				percentSum += sizing.Percent
+			}
+		}
+	}
+	if growChildInFit {
+		c.reportOpenElementError(ERROR_TYPE_GROW_IN_FIT_PARENT, "An element with FIT sizing and no minimum size along its layout axis has GROW children, they have no space to grow into. Give the parent a minimum size or use FIXED, GROW or PERCENT sizing.")
+	}
+	if percentSum > 1 {
+		c.reportOpenElementError(ERROR_TYPE_PERCENTAGE_SUM_OVER_1, "The PERCENT sizes of an element's children along its layout axis add up to more than 1, they will overflow the element.")
+	}
+}
+
+func sizingMinOverMax(sizing SizingAxis) bool {
+	return sizing.fitsContent() && sizing.GetMinMax().Min > sizing.GetMinMax().Max
+}
@@ -0,0 +1,167 @@
+package xfont
+
+import (
+	clay "github.com/igadmg/goclay"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goitalic"
+	"golang.org/x/image/font/gofont/gomono"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/font/sfnt"
+)
+
+// Creates the faces of one font, identified by TextElementConfig.FontId.
+type FaceSource interface {
+	// Returns the face for the font size in pixels.
+	NewFace(size uint16) (font.Face, error)
+}
+
+type opentypeSource struct {
+	font *opentype.Font
+}
+
+// Returns a source that creates a face for every requested size of a scalable opentype font.
+func Opentype(f *opentype.Font) FaceSource {
+	return opentypeSource{font: f}
+}
+
+func (s opentypeSource) NewFace(size uint16) (font.Face, error) {
+	return opentype.NewFace(s.font, &opentype.FaceOptions{
+		Size:    float64(size),
+		DPI:     72, // Sizes are in pixels
+		Hinting: font.HintingNone,
+	})
+}
+
+type fixedSource struct {
+	face font.Face
+}
+
+// Returns a source that uses the same face for every size, for bitmap faces like basicfont.Face7x13.
+func Fixed(face font.Face) FaceSource {
+	return fixedSource{face: face}
+}
+
+func (s fixedSource) NewFace(size uint16) (font.Face, error) {
+	return s.face, nil
+}
+
+type faceKey struct {
+	fontId   uint16
+	fontSize uint16
+}
+
+// Maps TextElementConfig.FontId and FontSize to font faces and measures text with them.
+// Faces are created once per font id and size and cached.
+// Faces are not safe for concurrent use, so a Faces value is not either.
+type Faces struct {
+	sources []FaceSource // Indexed by FontId
+	faces   map[faceKey]font.Face
+}
+
+// Creates faces for the sources, the FontId of a text config is the index of its source.
+func NewFaces(sources ...FaceSource) *Faces {
+	return &Faces{
+		sources: sources,
+		faces:   map[faceKey]font.Face{},
+	}
+}
+
+// Creates faces for the embedded Go fonts: FontId 0 is Go Regular, 1 is Go Bold, 2 is Go Italic and 3 is Go Mono.
+func NewGoFaces() *Faces {
+	sources := make([]FaceSource, 0, 4)
+	for _, ttf := range [][]byte{goregular.TTF, gobold.TTF, goitalic.TTF, gomono.TTF} {
+		f, err := opentype.Parse(ttf)
+		if err != nil {
+			panic(err) // The embedded fonts are known to be valid
+		}
+		sources = append(sources, Opentype(f))
+	}
+	return NewFaces(sources...)
+}
+
+// Returns the face for the font id and size of the config.
+// Unknown font ids use the first source, basicfont.Face7x13 is used when there are no sources or a face can't be created.
+func (f *Faces) Face(config *clay.TextElementConfig) font.Face {
+	key := faceKey{fontId: config.FontId, fontSize: config.FontSize}
+	if face, ok := f.faces[key]; ok {
+		return face
+	}
+
+	var face font.Face = basicfont.Face7x13
+	if len(f.sources) > 0 {
+		source := f.sources[0]
+		if int(config.FontId) < len(f.sources) {
+			source = f.sources[config.FontId]
+		}
+		if sourceFace, err := source.NewFace(config.FontSize); err == nil {
+			face = sourceFace
+		}
+	}
+	f.faces[key] = face
+	return face
+}
+
+// Returns the font of the config's font id, or nil if its source is not an opentype font.
+// Unknown font ids use the first source, like Face.
+func (f *Faces) Font(config *clay.TextElementConfig) *sfnt.Font {
+	if len(f.sources) == 0 {
+		return nil
+	}
+	source := f.sources[0]
+	if int(config.FontId) < len(f.sources) {
+		source = f.sources[config.FontId]
+	}
+	if s, ok := source.(opentypeSource); ok {
+		return s.font
+	}
+	return nil
+}
+
+// Returns a text shaper that uses these faces and their fonts for glyph indices, see clay.Context.SetTextShaper.
+func (f *Faces) Shaper() *Shaper {
+	return NewFontShaper(f.Face, f.Font)
+}
+
+// A clay.MeasureTextFn, pass it to clay.Context.SetMeasureTextFunction.
+func (f *Faces) MeasureText(text string, config *clay.TextElementConfig, userData any) clay.Dimensions {
+	return f.MeasureTextMetrics(text, config, userData).Dimensions
+}
+
+// A clay.MeasureTextMetricsFn, pass it to clay.Context.SetMeasureTextMetricsFunction for baseline alignment.
+// The width includes kerning and LetterSpacing between characters. A non zero LineHeight replaces the font height,
+// the extra leading is split evenly above and below the text.
+func (f *Faces) MeasureTextMetrics(text string, config *clay.TextElementConfig, userData any) clay.TextMetrics {
+	face := f.Face(config)
+	letterSpacing := float32(config.LetterSpacing)
+
+	var width float32
+	previous := rune(-1)
+	for _, r := range text {
+		if previous >= 0 {
+			width += fixedToFloat32(face.Kern(previous, r)) + letterSpacing
+		}
+		advance, _ := face.GlyphAdvance(r)
+		width += fixedToFloat32(advance)
+		previous = r
+	}
+
+	metrics := face.Metrics()
+	height := fixedToFloat32(metrics.Height)
+	ascent := fixedToFloat32(metrics.Ascent)
+	descent := fixedToFloat32(metrics.Descent)
+	if config.LineHeight > 0 {
+		leading := (float32(config.LineHeight) - height) / 2
+		height = float32(config.LineHeight)
+		ascent += leading
+		descent += leading
+	}
+
+	return clay.TextMetrics{
+		Dimensions: clay.MakeDimensions(width, height),
+		Ascent:     ascent,
+		Descent:    descent,
+	}
+}
@@ -0,0 +1,64 @@
+package xfont
+
+import (
+	"testing"
+
+	clay "github.com/igadmg/goclay"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+)
+
+func TestFaces_CachePerSize(t *testing.T) {
+	faces := NewGoFaces()
+
+	regular16 := faces.Face(&clay.TextElementConfig{FontId: 0, FontSize: 16})
+	assert.Same(t, regular16, faces.Face(&clay.TextElementConfig{FontId: 0, FontSize: 16, LetterSpacing: 3}))
+	assert.NotSame(t, regular16, faces.Face(&clay.TextElementConfig{FontId: 0, FontSize: 24}))
+	assert.NotSame(t, regular16, faces.Face(&clay.TextElementConfig{FontId: 1, FontSize: 16}))
+	// Unknown font ids fall back to the first font
+	assert.Equal(t,
+		faces.MeasureText("Fallback", &clay.TextElementConfig{FontId: 0, FontSize: 16}, nil),
+		faces.MeasureText("Fallback", &clay.TextElementConfig{FontId: 42, FontSize: 16}, nil))
+}
+
+func TestFaces_MeasureText(t *testing.T) {
+	faces := NewGoFaces()
+	config := &clay.TextElementConfig{FontSize: 20}
+	face := faces.Face(config)
+
+	dimensions := faces.MeasureText("AVATAR", config, nil)
+	assert.Equal(t, fixedToFloat32(font.MeasureString(face, "AVATAR")), dimensions.X)
+	assert.Equal(t, fixedToFloat32(face.Metrics().Height), dimensions.Y)
+
+	spaced := faces.MeasureText("AVATAR", &clay.TextElementConfig{FontSize: 20, LetterSpacing: 2}, nil)
+	assert.Equal(t, dimensions.X+10, spaced.X)
+
+	metrics := faces.MeasureTextMetrics("AVATAR", &clay.TextElementConfig{FontSize: 20, LineHeight: 40}, nil)
+	assert.Equal(t, float32(40), metrics.Dimensions.Y)
+	assert.InDelta(t, 40, metrics.Ascent+metrics.Descent, 0.001)
+	assert.InDelta(t, fixedToFloat32(face.Metrics().Ascent)+(40-dimensions.Y)/2, metrics.Ascent, 0.001)
+}
+
+func TestFaces_FixedFace(t *testing.T) {
+	faces := NewFaces(Fixed(basicfont.Face7x13))
+	dimensions := faces.MeasureText("abc", &clay.TextElementConfig{FontSize: 40}, nil)
+	assert.Equal(t, clay.MakeDimensions(21, 13), dimensions)
+}
+
+func TestFaces_Layout(t *testing.T) {
+	faces := NewGoFaces()
+	ctx := clay.Initialize(clay.MakeBoundingBox(clay.MakeVector2(0, 0), clay.MakeDimensions(800, 600)), clay.ErrorHandler{})
+	ctx.SetMeasureTextFunction(faces.MeasureText, nil)
+
+	config := clay.TextElementConfig{FontSize: 16}
+	ctx.BeginLayout()
+	ctx.CLAY_ID(clay.ID("label"), clay.ElementDeclaration{}, func() {
+		ctx.CLAY_TEXT("Hello Go", ctx.TEXT_CONFIG(config))
+	})
+	ctx.EndLayout()
+
+	assert.Equal(t,
+		faces.MeasureText("Hello Go", &config, nil),
+		ctx.GetElementData(clay.ID("label")).BoundingBox.Size)
+}
@@ -0,0 +1,87 @@
+// Package xfont connects clay text measurement and shaping to golang.org/x/image/font faces.
+package xfont
+
+import (
+	clay "github.com/igadmg/goclay"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+// Returns the face used to render text with the provided config.
+type FaceFunc func(config *clay.TextElementConfig) font.Face
+
+// Returns the font the face for the provided config was created from, or nil if there is none.
+type FontFunc func(config *clay.TextElementConfig) *sfnt.Font
+
+// A clay.TextShaper built on font.Face.
+// Every rune becomes one glyph, neighbouring glyphs are kerned with Face.Kern, across word boundaries too when a whole
+// line is shaped. There is no glyph substitution, so ligatures and complex scripts are not shaped.
+// GlyphId is the index of the glyph in the font returned by Font, and left 0 when there is no font or the font has
+// no glyph for the rune.
+type Shaper struct {
+	Face FaceFunc
+	Font FontFunc
+
+	buffer sfnt.Buffer
+}
+
+func NewShaper(face FaceFunc) *Shaper {
+	return &Shaper{Face: face}
+}
+
+// Returns a shaper that also looks up glyph indices in the fonts returned by font.
+func NewFontShaper(face FaceFunc, font FontFunc) *Shaper {
+	return &Shaper{Face: face, Font: font}
+}
+
+func (s *Shaper) Shape(text string, config *clay.TextElementConfig, dst []clay.ShapedGlyph) clay.ShapedRun {
+	face := s.Face(config)
+	var f *sfnt.Font
+	if s.Font != nil {
+		f = s.Font(config)
+	}
+	letterSpacing := float32(config.LetterSpacing)
+	start := len(dst)
+
+	var width float32
+	previous := rune(-1)
+	for i, r := range text {
+		if previous >= 0 {
+			kern := fixedToFloat32(face.Kern(previous, r))
+			dst[len(dst)-1].Advance += kern
+			width += kern
+		}
+		advance, _ := face.GlyphAdvance(r)
+		glyph := clay.ShapedGlyph{
+			Advance: fixedToFloat32(advance) + letterSpacing,
+			Cluster: int32(i),
+		}
+		if f != nil {
+			if index, err := f.GlyphIndex(&s.buffer, r); err == nil {
+				glyph.GlyphId = uint32(index)
+			}
+		}
+		dst = append(dst, glyph)
+		width += glyph.Advance
+		previous = r
+	}
+	if len(dst) > start {
+		// Letter spacing goes between glyphs, not after the last one
+		width -= letterSpacing
+	}
+
+	metrics := face.Metrics()
+	return clay.ShapedRun{
+		Glyphs: dst,
+		Metrics: clay.TextMetrics{
+			Dimensions: clay.MakeDimensions(width, fixedToFloat32(metrics.Height)),
+			Ascent:     fixedToFloat32(metrics.Ascent),
+			Descent:    fixedToFloat32(metrics.Descent),
+		},
+	}
+}
+
+func fixedToFloat32(v fixed.Int26_6) float32 {
+	return float32(v) / 64
+}
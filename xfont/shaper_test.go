@@ -0,0 +1,95 @@
+package xfont
+
+import (
+	"testing"
+
+	clay "github.com/igadmg/goclay"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/font/sfnt"
+)
+
+func goRegularFont(t *testing.T) *opentype.Font {
+	f, err := opentype.Parse(goregular.TTF)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func goRegularFace(t *testing.T, size float64) font.Face {
+	f := goRegularFont(t)
+	face, err := opentype.NewFace(f, &opentype.FaceOptions{Size: size, DPI: 72})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return face
+}
+
+func TestShaper_Shape(t *testing.T) {
+	face := goRegularFace(t, 16)
+	shaper := NewShaper(func(config *clay.TextElementConfig) font.Face { return face })
+
+	config := &clay.TextElementConfig{FontSize: 16}
+	run := shaper.Shape("Hello", config, nil)
+	assert.Equal(t, 5, len(run.Glyphs))
+	// Without a font there are no glyph indices
+	assert.Equal(t, uint32(0), run.Glyphs[0].GlyphId)
+	assert.Equal(t, int32(4), run.Glyphs[4].Cluster)
+
+	var advances float32
+	for _, glyph := range run.Glyphs {
+		advances += glyph.Advance
+	}
+	assert.Equal(t, advances, run.Metrics.Dimensions.X)
+	assert.Equal(t, float32(face.Metrics().Ascent)/64, run.Metrics.Ascent)
+
+	// Glyphs are appended to dst, letter spacing goes between glyphs only
+	spaced := shaper.Shape("Hello", &clay.TextElementConfig{FontSize: 16, LetterSpacing: 2}, run.Glyphs)
+	assert.Equal(t, 10, len(spaced.Glyphs))
+	assert.Equal(t, run.Metrics.Dimensions.X+8, spaced.Metrics.Dimensions.X)
+}
+
+func TestShaper_GlyphIndices(t *testing.T) {
+	f := goRegularFont(t)
+	face := goRegularFace(t, 16)
+	shaper := NewFontShaper(func(config *clay.TextElementConfig) font.Face { return face },
+		func(config *clay.TextElementConfig) *sfnt.Font { return f })
+
+	run := shaper.Shape("Hi", &clay.TextElementConfig{FontSize: 16}, nil)
+	var buffer sfnt.Buffer
+	for i, r := range "Hi" {
+		index, err := f.GlyphIndex(&buffer, r)
+		assert.NoError(t, err)
+		assert.NotZero(t, index)
+		assert.Equal(t, uint32(index), run.Glyphs[i].GlyphId)
+	}
+
+	// The shaper of the Go faces resolves indices, bitmap faces have no font
+	faces := NewFaces(Opentype(f), Fixed(basicfont.Face7x13))
+	assert.Equal(t, run.Glyphs[0].GlyphId, faces.Shaper().Shape("H", &clay.TextElementConfig{FontSize: 16}, nil).Glyphs[0].GlyphId)
+	assert.Equal(t, uint32(0), faces.Shaper().Shape("H", &clay.TextElementConfig{FontId: 1, FontSize: 16}, nil).Glyphs[0].GlyphId)
+}
+
+func TestShaper_LayoutCarriesGlyphs(t *testing.T) {
+	face := goRegularFace(t, 16)
+	ctx := clay.Initialize(clay.MakeBoundingBox(clay.MakeVector2(0, 0), clay.MakeDimensions(800, 600)), clay.ErrorHandler{})
+	ctx.SetTextShaper(NewShaper(func(config *clay.TextElementConfig) font.Face { return face }))
+
+	ctx.BeginLayout()
+	ctx.CLAY_TEXT("Go fonts", ctx.TEXT_CONFIG(clay.TextElementConfig{FontSize: 16}))
+	commands := ctx.EndLayout()
+
+	found := false
+	for _, cmd := range commands {
+		if text, ok := cmd.RenderData.(*clay.TextRenderData); ok {
+			found = true
+			assert.Equal(t, len(text.StringContents), len(text.Glyphs))
+			assert.InDelta(t, float32(face.Metrics().Ascent)/64, text.BaselineOffset, 0.001)
+		}
+	}
+	assert.True(t, found)
+}